@@ -1,16 +1,51 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kode4food/respect/pkg/acl"
 	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/pubsub"
 	"github.com/kode4food/respect/pkg/server"
 	"github.com/kode4food/respect/pkg/storage"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM,
+	)
+	defer stop()
+
 	s := storage.NewMemory()
-	h := server.WithHandler(command.Storage(s))
+	b := pubsub.NewBroker()
+	a := acl.NewACL()
+	specs := command.Specs()
+	h := server.WithHandler(command.Use(
+		command.ACLWrap(a, command.IntrospectionWrap(
+			command.PubSubWrap(b, command.Storage(s)), specs...,
+		), specs...),
+		command.Recover, command.Logger(slog.Default()),
+		command.Transactions(
+			s, command.WithSpecs(specs...), command.WithACL(a),
+		),
+	))
 	svr := server.NewServer(h)
-	if err := svr.Start(); err != nil {
+	err := svr.Start(ctx)
+	if !errors.Is(err, server.ErrServerClosed) {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := svr.Shutdown(ctx); err != nil {
 		panic(err)
 	}
 }
@@ -0,0 +1,195 @@
+// Package acl implements access control lists: named users with passwords,
+// and the commands and keys that clients authenticated as them are allowed
+// to use. Rules follow the syntax of Redis' ACL SETUSER command
+package acl
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// ACL is a registry of Users, and a log of the requests that its Users
+	// have been denied
+	ACL struct {
+		users   map[string]*User
+		log     []*LogEntry
+		entries int64
+		sync.RWMutex
+	}
+
+	// LogEntry records a request that was denied
+	LogEntry struct {
+		ID       int64
+		Count    int
+		Reason   string
+		Context  string
+		Object   string
+		Username string
+		Created  time.Time
+		Updated  time.Time
+	}
+)
+
+// DefaultUser is the User that clients are authenticated as when they
+// connect. Initially it has no password and can run every command
+const DefaultUser = "default"
+
+// Reasons for a request to be denied
+const (
+	ReasonCommand = "command"
+	ReasonKey     = "key"
+	ReasonAuth    = "auth"
+)
+
+// Error messages
+const (
+	ErrDeleteDefault = "ERR The 'default' user cannot be removed"
+	ErrInvalidName   = "ERR Usernames can't contain spaces or null " +
+		"characters"
+)
+
+const (
+	// maxLogEntries is the number of entries that the log retains
+	maxLogEntries = 128
+
+	// logMergeWindow is how recently an identical entry must have been
+	// updated for a denied request to be counted against it
+	logMergeWindow = time.Minute
+)
+
+// NewACL creates an ACL containing only the DefaultUser
+func NewACL() *ACL {
+	def, _ := newUser(DefaultUser).withRules("on", "nopass", "~*", "+@all")
+	return &ACL{
+		users: map[string]*User{DefaultUser: def},
+	}
+}
+
+// User returns the named User, and whether it exists
+func (a *ACL) User(name string) (*User, bool) {
+	a.RLock()
+	defer a.RUnlock()
+	u, ok := a.users[name]
+	return u, ok
+}
+
+// Users returns all of the Users, ordered by name
+func (a *ACL) Users() []*User {
+	a.RLock()
+	defer a.RUnlock()
+	res := make([]*User, 0, len(a.users))
+	for _, u := range a.users {
+		res = append(res, u)
+	}
+	slices.SortFunc(res, func(l, r *User) int {
+		return strings.Compare(l.name, r.name)
+	})
+	return res
+}
+
+// SetUser applies rules to the named User, creating it first if it doesn't
+// exist. If any of the rules are invalid, the User is left unchanged
+func (a *ACL) SetUser(name string, rules ...string) error {
+	if name == "" || strings.ContainsAny(name, " \x00") {
+		return resp.MakeError(ErrInvalidName)
+	}
+	a.Lock()
+	defer a.Unlock()
+	u, ok := a.users[name]
+	if !ok {
+		u = newUser(name)
+	}
+	res, err := u.withRules(rules...)
+	if err != nil {
+		return err
+	}
+	a.users[name] = res
+	return nil
+}
+
+// DelUser removes the named Users, returning how many existed
+func (a *ACL) DelUser(names ...string) (int, error) {
+	a.Lock()
+	defer a.Unlock()
+	if slices.Contains(names, DefaultUser) {
+		return 0, resp.MakeError(ErrDeleteDefault)
+	}
+	res := 0
+	for _, name := range names {
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			res++
+		}
+	}
+	return res, nil
+}
+
+// Authenticate returns the named User if it exists, is enabled, and accepts
+// the password
+func (a *ACL) Authenticate(name, password string) (*User, bool) {
+	u, ok := a.User(name)
+	if !ok || !u.Authenticate(password) {
+		return nil, false
+	}
+	return u, true
+}
+
+// Deny records a denied request in the log. A request that matches a
+// recently updated entry is counted against that entry instead
+func (a *ACL) Deny(reason, context, object, username string) {
+	a.Lock()
+	defer a.Unlock()
+	now := time.Now()
+	for i, e := range a.log {
+		if e.Reason == reason && e.Context == context &&
+			e.Object == object && e.Username == username &&
+			now.Sub(e.Updated) < logMergeWindow {
+			e.Count++
+			e.Updated = now
+			copy(a.log[1:i+1], a.log[:i])
+			a.log[0] = e
+			return
+		}
+	}
+	a.entries++
+	e := &LogEntry{
+		ID:       a.entries - 1,
+		Count:    1,
+		Reason:   reason,
+		Context:  context,
+		Object:   object,
+		Username: username,
+		Created:  now,
+		Updated:  now,
+	}
+	a.log = append([]*LogEntry{e}, a.log...)
+	if len(a.log) > maxLogEntries {
+		a.log = a.log[:maxLogEntries]
+	}
+}
+
+// Log returns up to the requested number of log entries, most recent first
+func (a *ACL) Log(count int) []LogEntry {
+	a.RLock()
+	defer a.RUnlock()
+	if count > len(a.log) {
+		count = len(a.log)
+	}
+	res := make([]LogEntry, count)
+	for i, e := range a.log[:count] {
+		res[i] = *e
+	}
+	return res
+}
+
+// ResetLog removes all entries from the log
+func (a *ACL) ResetLog() {
+	a.Lock()
+	defer a.Unlock()
+	a.log = nil
+}
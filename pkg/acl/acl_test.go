@@ -0,0 +1,166 @@
+package acl_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func hash(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDefaultUser(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+
+	u, ok := a.User(acl.DefaultUser)
+	as.True(ok)
+	as.True(u.Enabled())
+	as.True(u.NoPass())
+	as.True(u.CanRun("get", acl.CategoryRead))
+	as.True(u.CanAccess("any:key"))
+	as.Equal("user default on nopass ~* +@all", u.String())
+
+	_, ok = a.Authenticate(acl.DefaultUser, "anything")
+	as.True(ok)
+
+	_, err := a.DelUser(acl.DefaultUser)
+	as.Equal(resp.MakeError(acl.ErrDeleteDefault), err)
+}
+
+func TestSetUser(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+
+	as.Nil(a.SetUser("alice"))
+	u, ok := a.User("alice")
+	as.True(ok)
+	as.False(u.Enabled())
+	as.Equal("user alice off -@all", u.String())
+	_, ok = a.Authenticate("alice", "")
+	as.False(ok)
+
+	as.Nil(a.SetUser("alice", "on", ">secret", ">other", "<other"))
+	u, _ = a.User("alice")
+	as.Equal([]string{hash("secret")}, u.Passwords())
+	_, ok = a.Authenticate("alice", "secret")
+	as.True(ok)
+	_, ok = a.Authenticate("alice", "other")
+	as.False(ok)
+
+	as.Nil(a.SetUser("alice", "#"+hash("hashed"), "!"+hash("secret")))
+	_, ok = a.Authenticate("alice", "hashed")
+	as.True(ok)
+	_, ok = a.Authenticate("alice", "secret")
+	as.False(ok)
+
+	as.Nil(a.SetUser("alice", "off"))
+	_, ok = a.Authenticate("alice", "hashed")
+	as.False(ok)
+
+	as.Nil(a.SetUser("alice", "reset"))
+	u, _ = a.User("alice")
+	as.Equal("user alice off -@all", u.String())
+
+	as.Equal(resp.MakeError(acl.ErrInvalidName), a.SetUser("bad name"))
+
+	err := a.SetUser("alice", "on", "bogus")
+	as.Equal(resp.MakeError(acl.ErrInvalidRule, "bogus", acl.ErrSyntax), err)
+	err = a.SetUser("alice", "#abc")
+	as.Equal(
+		resp.MakeError(acl.ErrInvalidRule, "#abc", acl.ErrInvalidHash), err,
+	)
+	err = a.SetUser("alice", "+@bogus")
+	as.Equal(
+		resp.MakeError(acl.ErrInvalidRule, "+@bogus", acl.ErrUnknownCommand),
+		err,
+	)
+	u, _ = a.User("alice")
+	as.False(u.Enabled())
+
+	users := a.Users()
+	as.Equal(2, len(users))
+	as.Equal("alice", users[0].Name())
+	as.Equal(acl.DefaultUser, users[1].Name())
+
+	count, err := a.DelUser("alice", "missing")
+	as.Nil(err)
+	as.Equal(1, count)
+	_, ok = a.User("alice")
+	as.False(ok)
+}
+
+func TestCommandRules(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+
+	as.Nil(a.SetUser("reader", "+@read", "-@dangerous", "+keys", "-get"))
+	u, _ := a.User("reader")
+	as.Equal("+@read -@dangerous +keys -get", u.Commands())
+	as.True(u.CanRun("hget", acl.CategoryRead, acl.CategoryHash))
+	as.False(u.CanRun("set", acl.CategoryWrite, acl.CategoryString))
+	as.True(u.CanRun("KEYS", acl.CategoryRead, acl.CategoryDangerous))
+	as.False(u.CanRun("get", acl.CategoryRead, acl.CategoryString))
+	as.False(u.CanRun("scan", acl.CategoryDangerous))
+
+	as.Nil(a.SetUser("reader", "allcommands", "-@write"))
+	u, _ = a.User("reader")
+	as.Equal("+@all -@write", u.Commands())
+	as.True(u.CanRun("get", acl.CategoryRead))
+	as.False(u.CanRun("set", acl.CategoryWrite))
+
+	as.Nil(a.SetUser("reader", "nocommands"))
+	u, _ = a.User("reader")
+	as.Equal("-@all", u.Commands())
+	as.False(u.CanRun("get", acl.CategoryRead))
+}
+
+func TestKeyRules(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+
+	as.Nil(a.SetUser("alice", "~user:*", "~session:?"))
+	u, _ := a.User("alice")
+	as.Equal("~user:* ~session:?", u.Keys())
+	as.True(u.CanAccess("user:1"))
+	as.True(u.CanAccess("session:a"))
+	as.False(u.CanAccess("session:ab"))
+	as.False(u.CanAccess("other"))
+
+	as.Nil(a.SetUser("alice", "allkeys"))
+	u, _ = a.User("alice")
+	as.True(u.CanAccess("other"))
+
+	as.Nil(a.SetUser("alice", "resetkeys"))
+	u, _ = a.User("alice")
+	as.Equal("", u.Keys())
+	as.False(u.CanAccess("user:1"))
+}
+
+func TestLog(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+
+	a.Deny(acl.ReasonCommand, "toplevel", "get", "alice")
+	a.Deny(acl.ReasonKey, "toplevel", "secret", "alice")
+	a.Deny(acl.ReasonCommand, "toplevel", "get", "alice")
+
+	log := a.Log(10)
+	as.Equal(2, len(log))
+	as.Equal(acl.ReasonCommand, log[0].Reason)
+	as.Equal(2, log[0].Count)
+	as.Equal(int64(0), log[0].ID)
+	as.Equal("secret", log[1].Object)
+	as.Equal(int64(1), log[1].ID)
+
+	as.Equal(1, len(a.Log(1)))
+
+	a.ResetLog()
+	as.Equal(0, len(a.Log(10)))
+}
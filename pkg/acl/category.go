@@ -0,0 +1,46 @@
+package acl
+
+// Command categories, which ACL rules can allow or deny as a whole
+const (
+	CategoryAll         = "all"
+	CategoryKeyspace    = "keyspace"
+	CategoryRead        = "read"
+	CategoryWrite       = "write"
+	CategoryString      = "string"
+	CategoryList        = "list"
+	CategoryHash        = "hash"
+	CategorySet         = "set"
+	CategorySortedSet   = "sortedset"
+	CategoryPubSub      = "pubsub"
+	CategoryAdmin       = "admin"
+	CategoryFast        = "fast"
+	CategorySlow        = "slow"
+	CategoryBlocking    = "blocking"
+	CategoryDangerous   = "dangerous"
+	CategoryConnection  = "connection"
+	CategoryTransaction = "transaction"
+)
+
+var categories = map[string]bool{
+	CategoryAll:         true,
+	CategoryKeyspace:    true,
+	CategoryRead:        true,
+	CategoryWrite:       true,
+	CategoryString:      true,
+	CategoryList:        true,
+	CategoryHash:        true,
+	CategorySet:         true,
+	CategorySortedSet:   true,
+	CategoryPubSub:      true,
+	CategoryAdmin:       true,
+	CategoryFast:        true,
+	CategorySlow:        true,
+	CategoryBlocking:    true,
+	CategoryDangerous:   true,
+	CategoryConnection:  true,
+	CategoryTransaction: true,
+}
+
+func isCategory(name string) bool {
+	return categories[name]
+}
@@ -0,0 +1,343 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/kode4food/respect/pkg/glob"
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// User is a set of credentials and the permissions that are granted to
+	// the clients authenticated with them. Users are immutable, and are
+	// replaced as a whole when their rules change
+	User struct {
+		name      string
+		enabled   bool
+		noPass    bool
+		passwords []string
+		commands  []commandRule
+		keys      []string
+		allKeys   bool
+	}
+
+	// commandRule allows or denies a command, a category of commands, or
+	// all commands. Rules are applied in order, so the last rule that
+	// matches a command decides whether it's allowed
+	commandRule struct {
+		allow    bool
+		category string
+		command  string
+	}
+)
+
+// Error messages
+const (
+	ErrInvalidRule    = "ERR Error in ACL SETUSER modifier '%s': %s"
+	ErrSyntax         = "Syntax error"
+	ErrUnknownCommand = "Unknown command or category name in ACL"
+	ErrInvalidHash    = "The password hash must be exactly 64 characters " +
+		"and contain only lowercase hexadecimal characters"
+)
+
+// newUser creates a User that is disabled, has no passwords, and can run no
+// commands
+func newUser(name string) *User {
+	return &User{name: name}
+}
+
+// Name returns the name of the User
+func (u *User) Name() string {
+	return u.name
+}
+
+// Enabled reports whether clients can authenticate as the User
+func (u *User) Enabled() bool {
+	return u.enabled
+}
+
+// NoPass reports whether the User accepts any password
+func (u *User) NoPass() bool {
+	return u.noPass
+}
+
+// Passwords returns the SHA-256 hashes of the User's passwords, hex encoded
+func (u *User) Passwords() []string {
+	return slices.Clone(u.passwords)
+}
+
+// Flags returns the flags that describe the User, as reported by ACL GETUSER
+func (u *User) Flags() []string {
+	res := []string{"off"}
+	if u.enabled {
+		res[0] = "on"
+	}
+	if u.noPass {
+		res = append(res, "nopass")
+	}
+	return res
+}
+
+// Commands describes the command rules of the User
+func (u *User) Commands() string {
+	if len(u.commands) == 0 {
+		return "-@all"
+	}
+	res := make([]string, len(u.commands))
+	for i, r := range u.commands {
+		res[i] = r.String()
+	}
+	return strings.Join(res, " ")
+}
+
+// Keys describes the key patterns that the User can access
+func (u *User) Keys() string {
+	if u.allKeys {
+		return "~*"
+	}
+	res := make([]string, len(u.keys))
+	for i, k := range u.keys {
+		res[i] = "~" + k
+	}
+	return strings.Join(res, " ")
+}
+
+// String describes the User in the form of the rules that would recreate
+// it, as reported by ACL LIST
+func (u *User) String() string {
+	res := []string{"user", u.name}
+	res = append(res, u.Flags()...)
+	for _, p := range u.passwords {
+		res = append(res, "#"+p)
+	}
+	if keys := u.Keys(); keys != "" {
+		res = append(res, keys)
+	}
+	res = append(res, u.Commands())
+	return strings.Join(res, " ")
+}
+
+// Authenticate reports whether the provided password is one of the User's.
+// A disabled User never authenticates
+func (u *User) Authenticate(password string) bool {
+	if !u.enabled {
+		return false
+	}
+	if u.noPass {
+		return true
+	}
+	hash := hashPassword(password)
+	for _, p := range u.passwords {
+		if subtle.ConstantTimeCompare([]byte(p), []byte(hash)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRun reports whether the User is allowed to run a command that belongs
+// to the provided categories
+func (u *User) CanRun(command string, categories ...string) bool {
+	command = strings.ToLower(command)
+	res := false
+	for _, r := range u.commands {
+		if r.matches(command, categories) {
+			res = r.allow
+		}
+	}
+	return res
+}
+
+// CanAccess reports whether the User is allowed to access a key
+func (u *User) CanAccess(key string) bool {
+	if u.allKeys {
+		return true
+	}
+	for _, p := range u.keys {
+		if glob.Match(p, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRules returns a copy of the User with the provided rules applied, in
+// order. The User is left untouched if any of the rules are invalid
+func (u *User) withRules(rules ...string) (*User, error) {
+	res := u.clone()
+	for _, rule := range rules {
+		if err := res.apply(rule); err != nil {
+			return nil, resp.MakeError(ErrInvalidRule, rule, err)
+		}
+	}
+	return res, nil
+}
+
+func (u *User) apply(rule string) error {
+	switch lower := strings.ToLower(rule); lower {
+	case "on":
+		u.enabled = true
+	case "off":
+		u.enabled = false
+	case "nopass":
+		u.noPass = true
+		u.passwords = nil
+	case "resetpass":
+		u.noPass = false
+		u.passwords = nil
+	case "allkeys":
+		u.allKeys = true
+		u.keys = nil
+	case "resetkeys":
+		u.allKeys = false
+		u.keys = nil
+	case "allcommands":
+		return u.apply("+@all")
+	case "nocommands":
+		return u.apply("-@all")
+	case "reset":
+		*u = User{name: u.name}
+	default:
+		return u.applyPrefixed(rule, lower)
+	}
+	return nil
+}
+
+func (u *User) applyPrefixed(rule, lower string) error {
+	if len(rule) < 2 {
+		return errors.New(ErrSyntax)
+	}
+	arg := rule[1:]
+	switch rule[0] {
+	case '>':
+		u.addPassword(hashPassword(arg))
+	case '<':
+		u.removePassword(hashPassword(arg))
+	case '#':
+		if !validHash(arg) {
+			return errors.New(ErrInvalidHash)
+		}
+		u.addPassword(arg)
+	case '!':
+		if !validHash(arg) {
+			return errors.New(ErrInvalidHash)
+		}
+		u.removePassword(arg)
+	case '~':
+		u.addKeyPattern(arg)
+	case '+', '-':
+		r, err := parseCommandRule(lower)
+		if err != nil {
+			return err
+		}
+		u.addCommandRule(r)
+	default:
+		return errors.New(ErrSyntax)
+	}
+	return nil
+}
+
+func (u *User) addPassword(hash string) {
+	u.noPass = false
+	if !slices.Contains(u.passwords, hash) {
+		u.passwords = append(u.passwords, hash)
+	}
+}
+
+func (u *User) removePassword(hash string) {
+	u.passwords = slices.DeleteFunc(u.passwords, func(p string) bool {
+		return p == hash
+	})
+}
+
+func (u *User) addKeyPattern(pattern string) {
+	if pattern == "*" {
+		u.allKeys = true
+		u.keys = nil
+		return
+	}
+	if !u.allKeys && !slices.Contains(u.keys, pattern) {
+		u.keys = append(u.keys, pattern)
+	}
+}
+
+// addCommandRule appends a command rule. A rule that covers all commands
+// overrides every rule before it, so those are discarded
+func (u *User) addCommandRule(r commandRule) {
+	if r.category == CategoryAll {
+		u.commands = nil
+		if !r.allow {
+			return
+		}
+	}
+	u.commands = append(u.commands, r)
+}
+
+func (u *User) clone() *User {
+	res := *u
+	res.passwords = slices.Clone(u.passwords)
+	res.commands = slices.Clone(u.commands)
+	res.keys = slices.Clone(u.keys)
+	return &res
+}
+
+func parseCommandRule(rule string) (commandRule, error) {
+	res := commandRule{allow: rule[0] == '+'}
+	name := rule[1:]
+	if strings.HasPrefix(name, "@") {
+		res.category = name[1:]
+		if !isCategory(res.category) {
+			return res, errors.New(ErrUnknownCommand)
+		}
+		return res, nil
+	}
+	if name == "" || strings.ContainsAny(name, " @") {
+		return res, errors.New(ErrUnknownCommand)
+	}
+	res.command = name
+	return res, nil
+}
+
+func (r commandRule) matches(command string, categories []string) bool {
+	switch {
+	case r.command != "":
+		return r.command == command
+	case r.category == CategoryAll:
+		return true
+	default:
+		return slices.Contains(categories, r.category)
+	}
+}
+
+func (r commandRule) String() string {
+	sign := "-"
+	if r.allow {
+		sign = "+"
+	}
+	if r.command != "" {
+		return sign + r.command
+	}
+	return sign + "@" + r.category
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, ch := range hash {
+		if !(ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'f') {
+			return false
+		}
+	}
+	return true
+}
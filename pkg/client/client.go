@@ -0,0 +1,269 @@
+// Package client provides a client for RESP servers, including those built
+// using RESPect. A Client maintains a pool of connections, negotiates the
+// protocol version of each using HELLO, and supports pipelining
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// Client is a pool of connections to a RESP server. It's safe for
+	// concurrent use
+	Client struct {
+		Config
+
+		idle   chan *conn
+		slots  chan struct{}
+		closed chan struct{}
+		close  sync.Once
+	}
+
+	Config struct {
+		Network     string
+		Address     string
+		TLSConfig   *tls.Config
+		Username    string
+		Password    string
+		ClientName  string
+		Protocol    int
+		PoolSize    int
+		DialTimeout time.Duration
+
+		// OnInvalidate and OnPush are called with the Push frames that
+		// connections receive outside of a subscription
+		OnInvalidate InvalidationHandler
+		OnPush       PushHandler
+	}
+
+	Option func(*Config)
+)
+
+// RESP protocol versions
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
+// Defaults for the Client's configuration
+const (
+	DefaultAddress     = "localhost:6379"
+	DefaultPoolSize    = 10
+	DefaultDialTimeout = 5 * time.Second
+)
+
+// ErrClientClosed is returned by a Client's methods once it has been closed
+var ErrClientClosed = errors.New("client closed")
+
+var defaultOptions = []Option{
+	WithAddress(DefaultAddress),
+	WithProtocol(RESP3),
+	WithPoolSize(DefaultPoolSize),
+	WithDialTimeout(DefaultDialTimeout),
+}
+
+// Dial creates a Client and establishes its first connection, returning an
+// error if the server can't be reached or rejects the connection
+func Dial(ctx context.Context, opts ...Option) (*Client, error) {
+	res := NewClient(opts...)
+	cn, err := res.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res.put(cn)
+	return res, nil
+}
+
+// NewClient creates a Client without connecting to the server. Connections
+// are established as they're needed
+func NewClient(opts ...Option) *Client {
+	res := &Client{}
+	for _, opt := range append(defaultOptions, opts...) {
+		opt(&res.Config)
+	}
+	res.idle = make(chan *conn, res.PoolSize)
+	res.slots = make(chan struct{}, res.PoolSize)
+	res.closed = make(chan struct{})
+	return res
+}
+
+// WithAddress sets the address of the server. Addresses are TCP host and
+// port pairs unless another network is provided
+func WithAddress(address string) Option {
+	return func(c *Config) {
+		c.Network = "tcp"
+		c.Address = address
+	}
+}
+
+// WithUnixSocket sets the path of the Unix socket that the server listens on
+func WithUnixSocket(path string) Option {
+	return func(c *Config) {
+		c.Network = "unix"
+		c.Address = path
+	}
+}
+
+// WithTLS causes connections to be established using TLS
+func WithTLS(cfg *tls.Config) Option {
+	return func(c *Config) {
+		c.TLSConfig = cfg
+	}
+}
+
+// WithAuth sets the user and password that connections authenticate as. An
+// empty user authenticates as the default user
+func WithAuth(user, password string) Option {
+	return func(c *Config) {
+		c.Username = user
+		c.Password = password
+	}
+}
+
+// WithClientName sets the name that connections identify themselves by
+func WithClientName(name string) Option {
+	return func(c *Config) {
+		c.ClientName = name
+	}
+}
+
+// WithProtocol sets the preferred protocol version. A connection falls back
+// to RESP2 if the server doesn't support the preferred version
+func WithProtocol(protocol int) Option {
+	return func(c *Config) {
+		c.Protocol = protocol
+	}
+}
+
+// WithPoolSize sets the maximum number of connections that are open at once
+func WithPoolSize(size int) Option {
+	return func(c *Config) {
+		c.PoolSize = size
+	}
+}
+
+// WithDialTimeout sets how long establishing a connection may take
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.DialTimeout = d
+	}
+}
+
+// WithInvalidationHandler sets the function that's called with the keys of
+// each invalidation message that the server pushes when client-side caching
+// is enabled
+func WithInvalidationHandler(h InvalidationHandler) Option {
+	return func(c *Config) {
+		c.OnInvalidate = h
+	}
+}
+
+// WithPushHandler sets the function that's called with any Push frame that
+// isn't otherwise routed
+func WithPushHandler(h PushHandler) Option {
+	return func(c *Config) {
+		c.OnPush = h
+	}
+}
+
+// Do sends a command to the server and returns its reply. Arguments can be
+// strings, byte slices, numbers, booleans or resp.BulkStrings. If the server
+// replies with an error, it's returned as a resp.Error
+func (c *Client) Do(ctx context.Context, args ...any) (resp.Value, error) {
+	cmd, err := makeCommand(args)
+	if err != nil {
+		return nil, err
+	}
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.put(cn)
+	res, err := cn.do(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := res.(resp.Error); ok {
+		return nil, e
+	}
+	return res, nil
+}
+
+// Close closes the Client's idle connections. Connections that are in use
+// are closed as soon as they're released
+func (c *Client) Close() error {
+	c.close.Do(func() {
+		close(c.closed)
+	})
+	for {
+		select {
+		case cn := <-c.idle:
+			c.discard(cn)
+		default:
+			return nil
+		}
+	}
+}
+
+// get takes an idle connection from the pool, or establishes a new one if
+// the pool isn't full, waiting for a connection to be released otherwise
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-c.closed:
+		return nil, ErrClientClosed
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+	select {
+	case <-c.closed:
+		return nil, ErrClientClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case cn := <-c.idle:
+		return cn, nil
+	case c.slots <- struct{}{}:
+		cn, err := c.dial(ctx)
+		if err != nil {
+			<-c.slots
+			return nil, err
+		}
+		return cn, nil
+	}
+}
+
+// put returns a connection to the pool, unless it can no longer be used
+func (c *Client) put(cn *conn) {
+	select {
+	case <-c.closed:
+		c.discard(cn)
+		return
+	default:
+	}
+	if cn.broken {
+		c.discard(cn)
+		return
+	}
+	c.idle <- cn
+
+	// The Client may have been closed while the connection was returned
+	select {
+	case <-c.closed:
+		_ = c.Close()
+	default:
+	}
+}
+
+func (c *Client) discard(cn *conn) {
+	_ = cn.Close()
+	<-c.slots
+}
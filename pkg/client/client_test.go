@@ -0,0 +1,263 @@
+package client_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/client"
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/server"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func startServer(t *testing.T, h command.Handler) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := server.NewServer(server.WithHandler(h))
+	go func() { _ = s.Serve(context.Background(), l) }()
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+	return l.Addr().String()
+}
+
+func dial(t *testing.T, addr string, opts ...client.Option) *client.Client {
+	c, err := client.Dial(
+		context.Background(),
+		append([]client.Option{client.WithAddress(addr)}, opts...)...,
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestDo(t *testing.T) {
+	as := assert.New(t)
+	ctx := context.Background()
+	c := dial(t, startServer(t, command.Storage(storage.NewMemory())))
+
+	v, err := c.Do(ctx, "SET", "key", "value")
+	as.Nil(err)
+	as.Equal(resp.OK, v)
+	as.Equal("value", must(client.String(c.Do(ctx, "GET", "key"))))
+	as.Equal(int64(10), must(client.Int(c.Do(ctx, "INCRBY", "n", 10))))
+	as.Equal(2.5, must(client.Float(c.Do(ctx, "INCRBYFLOAT", "f", 2.5))))
+	as.False(must(client.Bool(c.Do(ctx, "SISMEMBER", "s", "m"))))
+
+	_, err = client.String(c.Do(ctx, "GET", "missing"))
+	as.ErrorIs(err, client.ErrNil)
+
+	_, err = c.Do(ctx, "BOGUS")
+	as.Equal(resp.MakeError(command.ErrUnknownCommand, "BOGUS"), err)
+
+	_, err = c.Do(ctx, "SET", "key", struct{}{})
+	as.EqualError(err, "unsupported argument type: struct {}")
+}
+
+func TestProtocol(t *testing.T) {
+	as := assert.New(t)
+	ctx := context.Background()
+	addr := startServer(t, command.Storage(storage.NewMemory()))
+
+	c3 := dial(t, addr)
+	_, err := c3.Do(ctx, "HSET", "hash", "a", "1", "b", "2")
+	as.Nil(err)
+	v, err := c3.Do(ctx, "HGETALL", "hash")
+	as.Nil(err)
+	as.Equal(resp.MapTag, v.Tag())
+	as.Equal(
+		map[string]string{"a": "1", "b": "2"}, must(client.StringMap(v, nil)),
+	)
+
+	c2 := dial(t, addr, client.WithProtocol(client.RESP2))
+	v, err = c2.Do(ctx, "HGETALL", "hash")
+	as.Nil(err)
+	as.Equal(resp.ArrayTag, v.Tag())
+	as.Equal(
+		map[string]string{"a": "1", "b": "2"}, must(client.StringMap(v, nil)),
+	)
+}
+
+func TestAuth(t *testing.T) {
+	as := assert.New(t)
+	ctx := context.Background()
+	a := acl.NewACL()
+	as.Nil(a.SetUser("alice", "on", ">secret", "~*", "+@all"))
+	as.Nil(a.SetUser(acl.DefaultUser, "resetpass", ">hidden"))
+	addr := startServer(t,
+		command.ACLWrap(
+			a, command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+		),
+	)
+
+	c := dial(t, addr, client.WithAuth("alice", "secret"))
+	as.Equal("alice", must(client.String(c.Do(ctx, "ACL", "WHOAMI"))))
+
+	c = dial(t, addr, client.WithAuth("", "hidden"))
+	as.Equal("default", must(client.String(c.Do(ctx, "ACL", "WHOAMI"))))
+
+	_, err := client.Dial(ctx,
+		client.WithAddress(addr), client.WithAuth("alice", "wrong"),
+	)
+	as.Equal(resp.MakeError(command.ErrWrongPass), err)
+
+	c = dial(t, addr)
+	_, err = c.Do(ctx, "GET", "key")
+	as.Equal(resp.MakeError(command.ErrNoAuth), err)
+}
+
+func TestLegacyHandshake(t *testing.T) {
+	as := assert.New(t)
+	addr, received := fakeServer(t,
+		[]resp.Value{resp.MakeError("ERR unknown command 'HELLO'")},
+		[]resp.Value{resp.OK},
+		[]resp.Value{resp.BulkString("value")},
+	)
+
+	c := dial(t, addr, client.WithAuth("", "secret"))
+	v, err := c.Do(context.Background(), "GET", "k")
+	as.Nil(err)
+	as.Equal(resp.BulkString("value"), v)
+	as.Nil(c.Close())
+	as.Equal([]resp.Value{
+		resp.MakeArray(
+			resp.BulkString("HELLO"), resp.BulkString("3"),
+			resp.BulkString("AUTH"), resp.BulkString("default"),
+			resp.BulkString("secret"),
+		),
+		resp.MakeArray(
+			resp.BulkString("AUTH"), resp.BulkString("default"),
+			resp.BulkString("secret"),
+		),
+		resp.MakeArray(resp.BulkString("GET"), resp.BulkString("k")),
+	}, <-received)
+}
+
+// fakeServer accepts a single connection, answering each command that it
+// receives with the next of the provided sets of values. The commands that
+// it received are reported once it has answered all of them
+func fakeServer(
+	t *testing.T, replies ...[]resp.Value,
+) (string, <-chan []resp.Value) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	done := make(chan []resp.Value, 1)
+	go func() {
+		var received []resp.Value
+		defer func() { done <- received }()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		r := resp.NewReader(bufio.NewReader(conn))
+		for _, values := range replies {
+			v, err := r.Next()
+			if err != nil {
+				return
+			}
+			received = append(received, v)
+			for _, reply := range values {
+				_ = reply.Marshal(conn)
+			}
+		}
+	}()
+	return l.Addr().String(), done
+}
+
+func TestPipeline(t *testing.T) {
+	as := assert.New(t)
+	ctx := context.Background()
+	c := dial(t, startServer(t, command.Storage(storage.NewMemory())))
+
+	p := c.Pipeline()
+	for i := 0; i < 50; i++ {
+		p.Queue("INCR", "counter")
+	}
+	p.Queue("BOGUS").Queue("GET", "counter")
+	as.Equal(52, p.Len())
+
+	res, err := p.Exec(ctx)
+	as.Nil(err)
+	as.Equal(52, len(res))
+	as.Equal(resp.Integer(1), res[0])
+	as.Equal(resp.Integer(50), res[49])
+	as.Equal(resp.MakeError(command.ErrUnknownCommand, "BOGUS"), res[50])
+	as.Equal(resp.BulkString("50"), res[51])
+	as.Equal(0, p.Len())
+
+	res, err = p.Exec(ctx)
+	as.Nil(err)
+	as.Equal(0, len(res))
+
+	_, err = p.Queue("GET", struct{}{}).Queue("GET", "counter").Exec(ctx)
+	as.NotNil(err)
+}
+
+func TestPool(t *testing.T) {
+	as := assert.New(t)
+	ctx := context.Background()
+	c := dial(t,
+		startServer(t, command.Storage(storage.NewMemory())),
+		client.WithPoolSize(2),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Do(ctx, "INCR", "counter")
+			as.Nil(err)
+		}()
+	}
+	wg.Wait()
+	as.Equal(int64(20), must(client.Int(c.Do(ctx, "GET", "counter"))))
+
+	as.Nil(c.Close())
+	_, err := c.Do(ctx, "GET", "counter")
+	as.ErrorIs(err, client.ErrClientClosed)
+}
+
+func TestContext(t *testing.T) {
+	as := assert.New(t)
+	release := make(chan struct{})
+	defer close(release)
+	c := dial(t, startServer(t, command.NewHandler(command.Handlers{
+		"BLOCK": func(r command.Responder, _ ...resp.Value) error {
+			<-release
+			return command.Emit(r, resp.OK)
+		},
+		"PING": func(r command.Responder, _ ...resp.Value) error {
+			return command.Emit(r, resp.SimpleString("PONG"))
+		},
+	})), client.WithPoolSize(1))
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 20*time.Millisecond,
+	)
+	defer cancel()
+	_, err := c.Do(ctx, "BLOCK")
+	as.ErrorIs(err, context.DeadlineExceeded)
+
+	_, err = c.Do(ctx, "PING")
+	as.ErrorIs(err, context.DeadlineExceeded)
+
+	v, err := c.Do(context.Background(), "PING")
+	as.Nil(err)
+	as.Equal(resp.SimpleString("PONG"), v)
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
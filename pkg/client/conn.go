@@ -0,0 +1,243 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// conn is a single connection to the server
+type conn struct {
+	net.Conn
+	config   *Config
+	reader   *resp.Reader
+	writer   *bufio.Writer
+	protocol int
+
+	// broken is set once the connection is in a state where it can't be
+	// used again, such as when a reply has been only partially read
+	broken bool
+}
+
+// Error messages
+const (
+	ErrUnsupportedArgument = "unsupported argument type: %T"
+)
+
+// DefaultUser is the user that connections authenticate as if no user has
+// been provided
+const DefaultUser = "default"
+
+// dial establishes a new connection to the server and negotiates its
+// protocol version
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := &net.Dialer{Timeout: c.DialTimeout}
+	var nc net.Conn
+	var err error
+	if c.TLSConfig != nil {
+		td := &tls.Dialer{NetDialer: d, Config: c.TLSConfig}
+		nc, err = td.DialContext(ctx, c.Network, c.Address)
+	} else {
+		nc, err = d.DialContext(ctx, c.Network, c.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := &conn{
+		Conn:     nc,
+		config:   &c.Config,
+		reader:   resp.NewReader(bufio.NewReader(nc), resp.V2Compatible),
+		writer:   bufio.NewWriter(nc),
+		protocol: RESP2,
+	}
+	if err := res.handshake(ctx, &c.Config); err != nil {
+		_ = res.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// handshake negotiates the protocol version of the connection using HELLO,
+// authenticating and naming it at the same time. If the server doesn't
+// support the preferred protocol version, RESP2 is negotiated instead, and
+// if the server doesn't support HELLO at all, AUTH is issued in its place
+func (cn *conn) handshake(ctx context.Context, cfg *Config) error {
+	err := cn.hello(ctx, cfg, cfg.Protocol)
+	if e, ok := err.(resp.Error); ok && cfg.Protocol != RESP2 {
+		switch {
+		case e.Prefix() == "NOPROTO":
+			err = cn.hello(ctx, cfg, RESP2)
+		case strings.Contains(e.Error(), "unknown command"):
+			err = cn.legacyHandshake(ctx, cfg)
+		}
+	}
+	return err
+}
+
+func (cn *conn) hello(ctx context.Context, cfg *Config, protocol int) error {
+	args := []any{"HELLO", protocol}
+	if cfg.Password != "" {
+		args = append(args, "AUTH", authUser(cfg), cfg.Password)
+	}
+	if cfg.ClientName != "" {
+		args = append(args, "SETNAME", cfg.ClientName)
+	}
+	if err := cn.expect(ctx, args...); err != nil {
+		return err
+	}
+	cn.protocol = protocol
+	return nil
+}
+
+func (cn *conn) legacyHandshake(ctx context.Context, cfg *Config) error {
+	if cfg.Password != "" {
+		err := cn.expect(ctx, "AUTH", authUser(cfg), cfg.Password)
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.ClientName != "" {
+		return cn.expect(ctx, "CLIENT", "SETNAME", cfg.ClientName)
+	}
+	return nil
+}
+
+// expect issues a command, returning the error that it replies with, if any
+func (cn *conn) expect(ctx context.Context, args ...any) error {
+	cmd, err := makeCommand(args)
+	if err != nil {
+		return err
+	}
+	res, err := cn.do(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if e, ok := res.(resp.Error); ok {
+		return e
+	}
+	return nil
+}
+
+// do sends a command and reads its reply
+func (cn *conn) do(ctx context.Context, cmd *resp.Array) (resp.Value, error) {
+	res, err := cn.pipeline(ctx, []*resp.Array{cmd})
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// pipeline sends every command with a single write, and then reads their
+// replies. If the Context ends first, the connection is interrupted and
+// can't be used again
+func (cn *conn) pipeline(
+	ctx context.Context, cmds []*resp.Array,
+) ([]resp.Value, error) {
+	if err := cn.SetDeadline(time.Time{}); err != nil {
+		cn.broken = true
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = cn.SetDeadline(time.Now())
+	})
+	res, err := cn.roundTrip(cmds)
+	if !stop() || err != nil {
+		cn.broken = true
+	}
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return res, err
+}
+
+func (cn *conn) roundTrip(cmds []*resp.Array) ([]resp.Value, error) {
+	for _, cmd := range cmds {
+		if err := cmd.Marshal(cn.writer); err != nil {
+			return nil, err
+		}
+	}
+	if err := cn.writer.Flush(); err != nil {
+		return nil, err
+	}
+	res := make([]resp.Value, len(cmds))
+	for i := range res {
+		v, err := cn.nextReply()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
+// nextReply reads the next value that isn't a Push frame, routing any Push
+// frames that arrive ahead of it
+func (cn *conn) nextReply() (resp.Value, error) {
+	for {
+		v, err := cn.reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := v.(*resp.Push); ok {
+			cn.route(p)
+			continue
+		}
+		return v, nil
+	}
+}
+
+func authUser(cfg *Config) string {
+	if cfg.Username == "" {
+		return DefaultUser
+	}
+	return cfg.Username
+}
+
+// makeCommand converts the arguments of a command into the Array of
+// BulkStrings that is sent to the server
+func makeCommand(args []any) (*resp.Array, error) {
+	res := make([]resp.Value, len(args))
+	for i, a := range args {
+		s, err := asBulkString(a)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = s
+	}
+	return resp.MakeArray(res...), nil
+}
+
+func asBulkString(a any) (resp.BulkString, error) {
+	switch a := a.(type) {
+	case resp.BulkString:
+		return a, nil
+	case string:
+		return resp.BulkString(a), nil
+	case []byte:
+		return resp.BulkString(a), nil
+	case int:
+		return resp.BulkString(strconv.Itoa(a)), nil
+	case int64:
+		return resp.BulkString(strconv.FormatInt(a, 10)), nil
+	case uint64:
+		return resp.BulkString(strconv.FormatUint(a, 10)), nil
+	case float64:
+		return resp.BulkString(strconv.FormatFloat(a, 'f', -1, 64)), nil
+	case bool:
+		if a {
+			return "1", nil
+		}
+		return "0", nil
+	case fmt.Stringer:
+		return resp.BulkString(a.String()), nil
+	default:
+		return "", fmt.Errorf(ErrUnsupportedArgument, a)
+	}
+}
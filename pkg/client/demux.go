@@ -0,0 +1,350 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// Conn is a connection that's dedicated to a single user, and that's
+	// read continuously so that the Push frames a server sends at any time,
+	// such as published messages, are routed as soon as they arrive. Every
+	// other reply is matched to its request in the order that requests were
+	// sent. A Conn is safe for concurrent use
+	Conn struct {
+		client   *Client
+		cn       *conn
+		messages chan Message
+		closed   chan struct{}
+		close    sync.Once
+		err      error
+
+		writeLock sync.Mutex
+
+		// pending, channels and patterns are guarded by the embedded Mutex
+		pending  []*request
+		channels map[string]struct{}
+		patterns map[string]struct{}
+		sync.Mutex
+	}
+
+	// request is a command awaiting its reply. Requests that change
+	// subscriptions are instead completed by the confirmations that they're
+	// expecting, unless the server replies with an error
+	request struct {
+		kind     string
+		confirms int
+		reply    resp.Value
+		err      error
+		done     chan struct{}
+	}
+)
+
+// ErrConnClosed is returned by a Conn's methods once it has been closed
+var ErrConnClosed = errors.New("connection closed")
+
+// messageBuffer is the number of published Messages that a Conn holds for
+// its consumer before it stops reading from the server
+const messageBuffer = 100
+
+// Conn takes a connection from the Client's pool and dedicates it to the
+// caller until the Conn is closed
+func (c *Client) Conn(ctx context.Context) (*Conn, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &Conn{
+		client:   c,
+		cn:       cn,
+		messages: make(chan Message, messageBuffer),
+		closed:   make(chan struct{}),
+		channels: map[string]struct{}{},
+		patterns: map[string]struct{}{},
+	}
+	go res.readLoop()
+	return res, nil
+}
+
+// Subscribe creates a Conn that's subscribed to the provided channels
+func (c *Client) Subscribe(
+	ctx context.Context, channels ...string,
+) (*Conn, error) {
+	res, err := c.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := res.Subscribe(ctx, channels...); err != nil {
+		_ = res.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// Do sends a command to the server and returns its reply, as Client.Do does.
+// If the Context ends before the reply arrives, the reply is discarded when
+// it does
+func (c *Conn) Do(ctx context.Context, args ...any) (resp.Value, error) {
+	res, err := c.send(ctx, "", 0, args)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Subscribe subscribes the Conn to the provided channels, returning once the
+// server has confirmed each of them
+func (c *Conn) Subscribe(ctx context.Context, channels ...string) error {
+	return c.subscribe(ctx, "SUBSCRIBE", kindSubscribe, channels)
+}
+
+// PSubscribe subscribes the Conn to the provided channel patterns
+func (c *Conn) PSubscribe(ctx context.Context, patterns ...string) error {
+	return c.subscribe(ctx, "PSUBSCRIBE", kindPSubscribe, patterns)
+}
+
+// Unsubscribe unsubscribes the Conn from the provided channels, or from
+// every channel if none are provided
+func (c *Conn) Unsubscribe(ctx context.Context, channels ...string) error {
+	return c.subscribe(ctx, "UNSUBSCRIBE", kindUnsubscribe, channels)
+}
+
+// PUnsubscribe unsubscribes the Conn from the provided channel patterns, or
+// from every pattern if none are provided
+func (c *Conn) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	return c.subscribe(ctx, "PUNSUBSCRIBE", kindPUnsubscribe, patterns)
+}
+
+// Messages returns the channel that published Messages are delivered to. It
+// is closed once the Conn is
+func (c *Conn) Messages() <-chan Message {
+	return c.messages
+}
+
+// Err returns the error that caused the Conn to close, if any
+func (c *Conn) Err() error {
+	select {
+	case <-c.closed:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close closes the Conn, releasing its place in the Client's pool. The
+// connection itself isn't reused
+func (c *Conn) Close() error {
+	c.fail(ErrConnClosed)
+	return nil
+}
+
+func (c *Conn) subscribe(
+	ctx context.Context, verb, kind string, names []string,
+) error {
+	confirms := len(names)
+	if confirms == 0 {
+		confirms = c.subscriptionCount(kind)
+	}
+	args := make([]any, 0, len(names)+1)
+	args = append(args, verb)
+	for _, n := range names {
+		args = append(args, n)
+	}
+	_, err := c.send(ctx, kind, confirms, args)
+	return err
+}
+
+// subscriptionCount returns the number of confirmations that unsubscribing
+// from everything of a kind produces. The server confirms once even if there
+// was nothing to unsubscribe from
+func (c *Conn) subscriptionCount(kind string) int {
+	c.Lock()
+	defer c.Unlock()
+	res := len(c.channels)
+	if kind == kindPUnsubscribe {
+		res = len(c.patterns)
+	}
+	if res == 0 {
+		return 1
+	}
+	return res
+}
+
+func (c *Conn) send(
+	ctx context.Context, kind string, confirms int, args []any,
+) (resp.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cmd, err := makeCommand(args)
+	if err != nil {
+		return nil, err
+	}
+	r := &request{
+		kind:     kind,
+		confirms: confirms,
+		done:     make(chan struct{}),
+	}
+	if err := c.write(r, cmd); err != nil {
+		return nil, err
+	}
+	select {
+	case <-r.done:
+		if e, ok := r.reply.(resp.Error); ok {
+			return nil, e
+		}
+		return r.reply, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// write queues a request and sends its command. Both happen under the
+// writeLock so that requests are queued in the order they're sent
+func (c *Conn) write(r *request, cmd *resp.Array) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.Lock()
+	if err := c.Err(); err != nil {
+		c.Unlock()
+		return err
+	}
+	c.pending = append(c.pending, r)
+	c.Unlock()
+
+	err := cmd.Marshal(c.cn.writer)
+	if err == nil {
+		err = c.cn.writer.Flush()
+	}
+	if err != nil {
+		c.fail(err)
+	}
+	return err
+}
+
+// readLoop reads everything that the server sends, routing Push frames and
+// completing requests with their replies
+func (c *Conn) readLoop() {
+	defer close(c.messages)
+	for {
+		v, err := c.cn.reader.Next()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if p, ok := c.asPush(v); ok {
+			c.routePush(p)
+			continue
+		}
+		c.complete(v)
+	}
+}
+
+// asPush returns a value as a Push frame if it is one. RESP2 has no Push
+// frames, so a subscribed RESP2 connection receives Arrays in their place
+func (c *Conn) asPush(v resp.Value) (*resp.Push, bool) {
+	switch v := v.(type) {
+	case *resp.Push:
+		return v, true
+	case *resp.Array:
+		if c.cn.protocol == RESP2 && c.subscribed() {
+			return resp.MakePush(v.Values...), true
+		}
+	}
+	return nil, false
+}
+
+// subscribed reports whether the Conn has subscriptions, or is awaiting the
+// confirmation of one
+func (c *Conn) subscribed() bool {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.pending) != 0 && c.pending[0].kind != "" {
+		return true
+	}
+	return len(c.channels) != 0 || len(c.patterns) != 0
+}
+
+func (c *Conn) routePush(p *resp.Push) {
+	switch kind := pushKind(p); kind {
+	case kindMessage, kindPMessage:
+		if m, ok := makeMessage(p); ok {
+			select {
+			case c.messages <- m:
+			case <-c.closed:
+			}
+		}
+	case kindSubscribe, kindPSubscribe, kindUnsubscribe, kindPUnsubscribe:
+		c.confirm(kind, p)
+	case kindPong:
+		c.complete(p)
+	default:
+		c.cn.route(p)
+	}
+}
+
+// confirm records a subscription change, counting it toward the request
+// that's awaiting it
+func (c *Conn) confirm(kind string, p *resp.Push) {
+	c.Lock()
+	defer c.Unlock()
+	if len(p.Values) > 1 {
+		if name, err := String(p.Values[1], nil); err == nil {
+			c.track(kind, name)
+		}
+	}
+	if len(c.pending) == 0 || c.pending[0].kind != kind {
+		return
+	}
+	r := c.pending[0]
+	r.reply = p
+	if r.confirms--; r.confirms <= 0 {
+		c.pending = c.pending[1:]
+		close(r.done)
+	}
+}
+
+func (c *Conn) track(kind, name string) {
+	switch kind {
+	case kindSubscribe:
+		c.channels[name] = struct{}{}
+	case kindPSubscribe:
+		c.patterns[name] = struct{}{}
+	case kindUnsubscribe:
+		delete(c.channels, name)
+	case kindPUnsubscribe:
+		delete(c.patterns, name)
+	}
+}
+
+// complete completes the oldest pending request with a reply
+func (c *Conn) complete(v resp.Value) {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.pending) == 0 {
+		return
+	}
+	r := c.pending[0]
+	c.pending = c.pending[1:]
+	r.reply = v
+	close(r.done)
+}
+
+// fail closes the Conn, completing every pending request with an error
+func (c *Conn) fail(err error) {
+	c.close.Do(func() {
+		c.Lock()
+		defer c.Unlock()
+		c.err = err
+		close(c.closed)
+		for _, r := range c.pending {
+			r.err = err
+			close(r.done)
+		}
+		c.pending = nil
+		c.client.discard(c.cn)
+	})
+}
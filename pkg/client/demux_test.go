@@ -0,0 +1,151 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/client"
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/pubsub"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func pubSubServer(t *testing.T) string {
+	return startServer(t, command.PubSubWrap(
+		pubsub.NewBroker(), command.Storage(storage.NewMemory()),
+	))
+}
+
+func receive(t *testing.T, c *client.Conn) client.Message {
+	select {
+	case m := <-c.Messages():
+		return m
+	case <-time.After(time.Second):
+		assert.Fail(t, "message not received")
+		return client.Message{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	as := assert.New(t)
+	ctx := context.Background()
+	c := dial(t, pubSubServer(t))
+
+	sub, err := c.Subscribe(ctx, "news", "sports")
+	as.Nil(err)
+	as.Nil(sub.PSubscribe(ctx, "n*"))
+
+	_, err = c.Do(ctx, "SET", "key", "value")
+	as.Nil(err)
+	as.Equal(int64(2), must(client.Int(c.Do(ctx, "PUBLISH", "news", "hi"))))
+
+	// Replies are matched to their requests while messages arrive
+	as.Equal("value", must(client.String(sub.Do(ctx, "GET", "key"))))
+	as.Equal(client.Message{Channel: "news", Payload: "hi"}, receive(t, sub))
+	as.Equal(client.Message{
+		Pattern: "n*", Channel: "news", Payload: "hi",
+	}, receive(t, sub))
+
+	as.Nil(sub.Unsubscribe(ctx))
+	as.Nil(sub.PUnsubscribe(ctx))
+	as.Equal(int64(0), must(client.Int(c.Do(ctx, "PUBLISH", "news", "hi"))))
+
+	as.Nil(sub.Close())
+	_, ok := <-sub.Messages()
+	as.False(ok)
+	as.ErrorIs(sub.Err(), client.ErrConnClosed)
+	_, err = sub.Do(ctx, "GET", "key")
+	as.ErrorIs(err, client.ErrConnClosed)
+}
+
+func TestSubscribeRESP2(t *testing.T) {
+	as := assert.New(t)
+	ctx := context.Background()
+	c := dial(t, pubSubServer(t), client.WithProtocol(client.RESP2))
+
+	sub, err := c.Subscribe(ctx, "news")
+	as.Nil(err)
+	defer func() { _ = sub.Close() }()
+
+	v, err := sub.Do(ctx, "PING")
+	as.Nil(err)
+	as.Equal([]string{"pong", ""}, must(client.Strings(v, nil)))
+	_, err = sub.Do(ctx, "GET", "key")
+	as.ErrorContains(err, "only (P|S)SUBSCRIBE")
+
+	as.Equal(int64(1), must(client.Int(c.Do(ctx, "PUBLISH", "news", "hi"))))
+	as.Equal(client.Message{Channel: "news", Payload: "hi"}, receive(t, sub))
+
+	as.Nil(sub.Unsubscribe(ctx, "news"))
+	v, err = sub.Do(ctx, "PING")
+	as.Nil(err)
+	as.Equal(resp.SimpleString("PONG"), v)
+}
+
+func TestConnContext(t *testing.T) {
+	as := assert.New(t)
+	c := dial(t, startServer(t, command.Wrap(command.Handlers{
+		"SLOW": func(r command.Responder, _ ...resp.Value) error {
+			time.Sleep(50 * time.Millisecond)
+			return command.Emit(r, resp.BulkString("slow"))
+		},
+	}, command.Storage(storage.NewMemory()))))
+	conn, err := c.Conn(context.Background())
+	as.Nil(err)
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = conn.Do(ctx, "SET", "key", "value")
+	as.ErrorIs(err, context.Canceled)
+
+	ctx, cancel = context.WithTimeout(
+		context.Background(), 10*time.Millisecond,
+	)
+	defer cancel()
+	_, err = conn.Do(ctx, "SLOW")
+	as.ErrorIs(err, context.DeadlineExceeded)
+
+	// The abandoned request's reply is discarded when it arrives
+	v, err := conn.Do(context.Background(), "GET", "key")
+	as.Nil(err)
+	as.Equal(resp.NullValue, v)
+}
+
+func TestPushRouting(t *testing.T) {
+	as := assert.New(t)
+	hello := resp.MakeMapFromPairs(
+		[2]resp.Value{resp.BulkString("proto"), resp.Integer(3)},
+	)
+	addr, _ := fakeServer(t,
+		[]resp.Value{hello},
+		[]resp.Value{
+			resp.MakePush(
+				resp.BulkString("invalidate"),
+				resp.MakeArray(resp.BulkString("key")),
+			),
+			resp.MakePush(resp.BulkString("other")),
+			resp.BulkString("value"),
+		},
+	)
+
+	invalidated := make(chan []string, 1)
+	pushed := make(chan *resp.Push, 1)
+	c := dial(t, addr,
+		client.WithInvalidationHandler(func(keys []string) {
+			invalidated <- keys
+		}),
+		client.WithPushHandler(func(p *resp.Push) {
+			pushed <- p
+		}),
+	)
+
+	res, err := c.Pipeline().Queue("GET", "key").Exec(context.Background())
+	as.Nil(err)
+	as.Equal([]resp.Value{resp.BulkString("value")}, res)
+	as.Equal([]string{"key"}, <-invalidated)
+	as.Equal(resp.MakePush(resp.BulkString("other")), <-pushed)
+}
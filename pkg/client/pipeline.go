@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// Pipeline queues commands so that they can be sent to the server together,
+// using a single connection and a single write. A Pipeline isn't safe for
+// concurrent use
+type Pipeline struct {
+	client *Client
+	cmds   []*resp.Array
+	err    error
+}
+
+// Pipeline creates a new, empty Pipeline
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{client: c}
+}
+
+// Queue adds a command to the Pipeline. Arguments are accepted in the same
+// forms as they are by Do
+func (p *Pipeline) Queue(args ...any) *Pipeline {
+	if p.err != nil {
+		return p
+	}
+	cmd, err := makeCommand(args)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	p.cmds = append(p.cmds, cmd)
+	return p
+}
+
+// Len returns the number of commands that are queued
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec sends the queued commands to the server and returns their replies in
+// the same order. Errors that the server replies with are included among
+// the replies rather than being returned. The Pipeline is emptied so that it
+// can be reused
+func (p *Pipeline) Exec(ctx context.Context) ([]resp.Value, error) {
+	cmds, err := p.cmds, p.err
+	p.cmds, p.err = nil, nil
+	if err != nil {
+		return nil, err
+	}
+	if len(cmds) == 0 {
+		return []resp.Value{}, nil
+	}
+	cn, err := p.client.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.client.put(cn)
+	return cn.pipeline(ctx, cmds)
+}
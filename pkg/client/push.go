@@ -0,0 +1,81 @@
+package client
+
+import (
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// Message is a message that was published to a channel that a Conn is
+	// subscribed to. Pattern is set if the subscription was made by pattern
+	Message struct {
+		Pattern string
+		Channel string
+		Payload string
+	}
+
+	// InvalidationHandler is called with the keys that the server reports
+	// as invalidated. Nil keys mean that every key has been invalidated
+	InvalidationHandler func(keys []string)
+
+	// PushHandler is called with a Push frame that isn't otherwise routed
+	PushHandler func(*resp.Push)
+)
+
+// Push frame kinds
+const (
+	kindSubscribe    = "subscribe"
+	kindUnsubscribe  = "unsubscribe"
+	kindPSubscribe   = "psubscribe"
+	kindPUnsubscribe = "punsubscribe"
+	kindMessage      = "message"
+	kindPMessage     = "pmessage"
+	kindPong         = "pong"
+	kindInvalidate   = "invalidate"
+)
+
+// route passes a Push frame that arrived outside of a subscription to the
+// configured handlers, dropping it if there are none
+func (cn *conn) route(p *resp.Push) {
+	if pushKind(p) == kindInvalidate && cn.config.OnInvalidate != nil {
+		keys, err := invalidatedKeys(p)
+		if err == nil {
+			cn.config.OnInvalidate(keys)
+			return
+		}
+	}
+	if cn.config.OnPush != nil {
+		cn.config.OnPush(p)
+	}
+}
+
+// pushKind returns the kind of a Push frame, which is its first element
+func pushKind(p *resp.Push) string {
+	if len(p.Values) == 0 {
+		return ""
+	}
+	s, _ := String(p.Values[0], nil)
+	return s
+}
+
+func invalidatedKeys(p *resp.Push) ([]string, error) {
+	if len(p.Values) < 2 || p.Values[1].Tag() == resp.NullTag {
+		return nil, nil
+	}
+	return Strings(p.Values[1], nil)
+}
+
+// makeMessage converts a message or pmessage frame into a Message
+func makeMessage(p *resp.Push) (Message, bool) {
+	v, err := Strings(p, nil)
+	if err != nil {
+		return Message{}, false
+	}
+	switch {
+	case v[0] == kindMessage && len(v) == 3:
+		return Message{Channel: v[1], Payload: v[2]}, true
+	case v[0] == kindPMessage && len(v) == 4:
+		return Message{Pattern: v[1], Channel: v[2], Payload: v[3]}, true
+	default:
+		return Message{}, false
+	}
+}
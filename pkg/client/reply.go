@@ -0,0 +1,181 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// ErrNil is returned by the reply helpers when the server replies with Null
+var ErrNil = errors.New("nil reply")
+
+// Error messages
+const (
+	ErrUnexpectedType = "unexpected reply type: %s"
+	ErrOddPairs       = "reply has an odd number of elements"
+)
+
+// String converts a reply into a string. It's intended to wrap a call to Do,
+// as in String(c.Do(ctx, "GET", "key"))
+func String(v resp.Value, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case resp.BulkString:
+		return string(v), nil
+	case resp.SimpleString:
+		return string(v), nil
+	case *resp.VerbatimString:
+		return v.String(), nil
+	case resp.Integer:
+		return v.String(), nil
+	case resp.Double:
+		return v.String(), nil
+	case resp.Null:
+		return "", ErrNil
+	default:
+		return "", unexpectedType(v)
+	}
+}
+
+// Int converts a reply into an int64
+func Int(v resp.Value, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := v.(type) {
+	case resp.Integer:
+		return int64(v), nil
+	case resp.BulkString, resp.SimpleString:
+		s, _ := String(v, nil)
+		return strconv.ParseInt(s, 10, 64)
+	case resp.Null:
+		return 0, ErrNil
+	default:
+		return 0, unexpectedType(v)
+	}
+}
+
+// Float converts a reply into a float64
+func Float(v resp.Value, err error) (float64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := v.(type) {
+	case resp.Double:
+		return float64(v), nil
+	case resp.Integer:
+		return float64(v), nil
+	case resp.BulkString, resp.SimpleString:
+		s, _ := String(v, nil)
+		return strconv.ParseFloat(s, 64)
+	case resp.Null:
+		return 0, ErrNil
+	default:
+		return 0, unexpectedType(v)
+	}
+}
+
+// Bool converts a reply into a bool. RESP2 servers reply with the Integers 1
+// and 0 where RESP3 servers reply with Booleans
+func Bool(v resp.Value, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case resp.Boolean:
+		return bool(v), nil
+	case resp.Integer:
+		return v != 0, nil
+	case resp.Null:
+		return false, ErrNil
+	default:
+		return false, unexpectedType(v)
+	}
+}
+
+// Values converts a reply into the elements of an Array, Set or Push
+func Values(v resp.Value, err error) ([]resp.Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case resp.Collection:
+		return v.Elements(), nil
+	case resp.Null:
+		return nil, ErrNil
+	default:
+		return nil, unexpectedType(v)
+	}
+}
+
+// Strings converts a reply into a slice of strings. Null elements, such as
+// those that MGET replies with for missing keys, become empty strings
+func Strings(v resp.Value, err error) ([]string, error) {
+	elems, err := Values(v, err)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, len(elems))
+	for i, e := range elems {
+		s, err := String(e, nil)
+		if err != nil && !errors.Is(err, ErrNil) {
+			return nil, err
+		}
+		res[i] = s
+	}
+	return res, nil
+}
+
+// StringMap converts a reply into a map of strings. RESP3 servers reply with
+// a Map where RESP2 servers reply with an Array of alternating keys and
+// values, and either is accepted
+func StringMap(v resp.Value, err error) (map[string]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := v.(resp.Mapped); ok {
+		res := make(map[string]string, m.Count())
+		err := m.ForEach(func(k, v resp.Value) error {
+			return putString(res, k, v)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	elems, err := Values(v, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems)%2 != 0 {
+		return nil, errors.New(ErrOddPairs)
+	}
+	res := make(map[string]string, len(elems)/2)
+	for i := 0; i < len(elems); i += 2 {
+		if err := putString(res, elems[i], elems[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func putString(m map[string]string, k, v resp.Value) error {
+	key, err := String(k, nil)
+	if err != nil {
+		return err
+	}
+	val, err := String(v, nil)
+	if err != nil && !errors.Is(err, ErrNil) {
+		return err
+	}
+	m[key] = val
+	return nil
+}
+
+func unexpectedType(v resp.Value) error {
+	return fmt.Errorf(ErrUnexpectedType, v.Tag())
+}
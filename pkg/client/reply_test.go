@@ -0,0 +1,45 @@
+package client_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/client"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplyHelpers(t *testing.T) {
+	as := assert.New(t)
+	failed := errors.New("failed")
+
+	as.Equal("42", must(client.String(resp.Integer(42), nil)))
+	as.Equal("OK", must(client.String(resp.OK, nil)))
+	_, err := client.String(resp.OK, failed)
+	as.Equal(failed, err)
+	_, err = client.String(resp.MakeArray(), nil)
+	as.EqualError(err, "unexpected reply type: array")
+
+	as.Equal(int64(42), must(client.Int(resp.BulkString("42"), nil)))
+	_, err = client.Int(resp.NullValue, nil)
+	as.ErrorIs(err, client.ErrNil)
+
+	as.Equal(1.5, must(client.Float(resp.Double(1.5), nil)))
+	as.Equal(2.0, must(client.Float(resp.Integer(2), nil)))
+
+	as.True(must(client.Bool(resp.True, nil)))
+	as.True(must(client.Bool(resp.Integer(1), nil)))
+
+	as.Equal([]string{"a", "", "c"}, must(client.Strings(resp.MakeArray(
+		resp.BulkString("a"), resp.NullValue, resp.BulkString("c"),
+	), nil)))
+	as.Equal([]string{"a"}, must(client.Strings(
+		resp.MakeSet(resp.BulkString("a")), nil,
+	)))
+
+	as.Equal(map[string]string{"a": "1"}, must(client.StringMap(
+		resp.MakeArray(resp.BulkString("a"), resp.BulkString("1")), nil,
+	)))
+	_, err = client.StringMap(resp.MakeArray(resp.BulkString("a")), nil)
+	as.EqualError(err, client.ErrOddPairs)
+}
@@ -0,0 +1,324 @@
+package command
+
+import (
+	"time"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// Error messages
+const (
+	ErrNoAuth        = "NOAUTH Authentication required."
+	ErrNoPermCommand = "NOPERM User %s has no permissions to run the '%s' " +
+		"command"
+	ErrNoPermKey = "NOPERM No permissions to access a key"
+	ErrWrongPass = "WRONGPASS invalid username-password pair or user is " +
+		"disabled."
+	ErrAuthNoPassword = "ERR AUTH <password> called without any password " +
+		"configured for the default user. Are you sure your configuration " +
+		"is correct?"
+	ErrNegativeCount = "ERR value is out of range, must be positive"
+)
+
+// ACL log contexts
+const (
+	contextTopLevel = "toplevel"
+	contextMulti    = "multi"
+)
+
+const defaultLogCount = 10
+
+// ACL wraps a Handler so that commands are only dispatched to it if the
+// user of the client's Session is permitted to run them against their keys.
+// It also handles the AUTH and ACL commands
+func ACL(a *acl.ACL) Handler {
+	return ACLWrap(a, NoHandler)
+}
+
+// ACLWrap creates a Handler for the AUTH and ACL commands, falling back to
+// the wrapped Handler for all other commands. The categories and keys of
+// those commands are found using the CommandSpecs provided for them
+func ACLWrap(a *acl.ACL, next Handler, specs ...*CommandSpec) Handler {
+	h := Wrap(Handlers{
+		"AUTH": authOp(a),
+		"ACL":  aclOp(a),
+	}, next, aclSpecs...)
+	t := wrappedSpecs(aclSpecs, specs)
+	return func(r Responder, args ...resp.Value) error {
+		if err := checkACL(a, t, r, args); err != nil {
+			return err
+		}
+		return h(r, args...)
+	}
+}
+
+// checkACL returns an error if the user of the client's Session is not
+// permitted to run a command against its keys. Denied commands and keys are
+// recorded in the ACL's log
+func checkACL(
+	a *acl.ACL, specs specTable, r Responder, args []resp.Value,
+) error {
+	if len(args) == 0 {
+		return nil
+	}
+	v, ok := args[0].(resp.BulkString)
+	if !ok {
+		return nil
+	}
+	spec := specs.lookup(v)
+	if spec.Flags.Has(FlagNoAuth) {
+		return nil
+	}
+	u, err := sessionUser(a, r)
+	if err != nil {
+		return err
+	}
+	if !u.CanRun(spec.Name, spec.ACLCategories()...) {
+		a.Deny(acl.ReasonCommand, aclContext(r), spec.Name, u.Name())
+		return resp.MakeError(ErrNoPermCommand, u.Name(), spec.Name)
+	}
+	keys, err := spec.Keys(args)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if !u.CanAccess(k.String()) {
+			a.Deny(acl.ReasonKey, aclContext(r), k.String(), u.Name())
+			return resp.MakeError(ErrNoPermKey)
+		}
+	}
+	return nil
+}
+
+// sessionUser returns the ACL User of the client's Session. A Session that
+// hasn't authenticated acts as the default user, but only if it requires no
+// password
+func sessionUser(a *acl.ACL, r Responder) (*acl.User, error) {
+	name := DefaultUser
+	authenticated := false
+	if ss := SessionOf(r); ss != nil {
+		name = ss.User()
+		authenticated = ss.Authenticated()
+	}
+	u, ok := a.User(name)
+	if !ok || !u.Enabled() || !authenticated && !u.NoPass() {
+		return nil, resp.MakeError(ErrNoAuth)
+	}
+	return u, nil
+}
+
+func authOp(a *acl.ACL) Handler {
+	return wrapArgsHandler(authSpec, func(r Responder, args *Args) error {
+		names, err := asBulkStrings(args.Values("username"))
+		if err != nil {
+			return err
+		}
+		password, ok := args.Value("password").(resp.BulkString)
+		if !ok {
+			return resp.MakeError(ErrSyntax)
+		}
+		user := DefaultUser
+		if len(names) == 0 {
+			if def, ok := a.User(DefaultUser); ok && def.NoPass() {
+				return resp.MakeError(ErrAuthNoPassword)
+			}
+		} else {
+			user = string(names[0])
+		}
+		ss, err := sessionOf(r)
+		if err != nil {
+			return err
+		}
+		if _, ok := a.Authenticate(user, string(password)); !ok {
+			a.Deny(acl.ReasonAuth, aclContext(r), "AUTH", user)
+			return resp.MakeError(ErrWrongPass)
+		}
+		ss.SetUser(user)
+		return Emit(r, resp.OK)
+	})
+}
+
+func aclOp(a *acl.ACL) Handler {
+	return wrapArgsHandler(aclSpec, func(r Responder, args *Args) error {
+		sub, ok := args.Value("subcommand").(resp.BulkString)
+		if !ok {
+			return resp.MakeError(ErrSyntax)
+		}
+		rest := args.Values("arg")
+		if _, err := asBulkStrings(rest); err != nil {
+			return err
+		}
+		switch normalizeVerb(sub) {
+		case "SETUSER":
+			return aclSetUser(a, r, rest)
+		case "GETUSER":
+			return aclGetUser(a, r, rest)
+		case "DELUSER":
+			return aclDelUser(a, r, rest)
+		case "LIST":
+			return aclList(a, r, rest)
+		case "WHOAMI":
+			return aclWhoAmI(r, rest)
+		case "LOG":
+			return aclLog(a, r, rest)
+		default:
+			return resp.MakeError(ErrUnknownSubcommand, sub)
+		}
+	})
+}
+
+func aclSetUser(a *acl.ACL, r Responder, rest []resp.Value) error {
+	args, err := aclSetUserSpec.parseSubcommand(rest)
+	if err != nil {
+		return err
+	}
+	values := args.Values("rule")
+	rules := make([]string, len(values))
+	for i, rule := range values {
+		rules[i] = rule.(resp.BulkString).String()
+	}
+	err = a.SetUser(string(args.String("username")), rules...)
+	if err != nil {
+		return err
+	}
+	return Emit(r, resp.OK)
+}
+
+func aclGetUser(a *acl.ACL, r Responder, rest []resp.Value) error {
+	args, err := aclGetUserSpec.parseSubcommand(rest)
+	if err != nil {
+		return err
+	}
+	u, ok := a.User(string(args.String("username")))
+	if !ok {
+		return Emit(r, resp.NullValue)
+	}
+	res := resp.MakeMapFromPairs(
+		[2]resp.Value{resp.BulkString("flags"), bulkStrings(u.Flags())},
+		[2]resp.Value{
+			resp.BulkString("passwords"), bulkStrings(u.Passwords()),
+		},
+		[2]resp.Value{
+			resp.BulkString("commands"), resp.BulkString(u.Commands()),
+		},
+		[2]resp.Value{resp.BulkString("keys"), resp.BulkString(u.Keys())},
+	)
+	return Emit(r, protocolMap(res, ProtocolOf(r)))
+}
+
+func aclDelUser(a *acl.ACL, r Responder, rest []resp.Value) error {
+	args, err := aclDelUserSpec.parseSubcommand(rest)
+	if err != nil {
+		return err
+	}
+	values := args.Values("username")
+	names := make([]string, len(values))
+	for i, n := range values {
+		names[i] = n.(resp.BulkString).String()
+	}
+	res, err := a.DelUser(names...)
+	if err != nil {
+		return err
+	}
+	return Emit(r, resp.Integer(res))
+}
+
+func aclList(a *acl.ACL, r Responder, rest []resp.Value) error {
+	if _, err := aclListSpec.parseSubcommand(rest); err != nil {
+		return err
+	}
+	users := a.Users()
+	res := make([]string, len(users))
+	for i, u := range users {
+		res[i] = u.String()
+	}
+	return Emit(r, bulkStrings(res))
+}
+
+func aclWhoAmI(r Responder, rest []resp.Value) error {
+	if _, err := aclWhoAmISpec.parseSubcommand(rest); err != nil {
+		return err
+	}
+	user := DefaultUser
+	if ss := SessionOf(r); ss != nil {
+		user = ss.User()
+	}
+	return Emit(r, resp.BulkString(user))
+}
+
+func aclLog(a *acl.ACL, r Responder, rest []resp.Value) error {
+	args, err := aclLogSpec.parseSubcommand(rest)
+	if err != nil {
+		return err
+	}
+	count := defaultLogCount
+	switch {
+	case args.Has("reset") && args.Has("count"):
+		return resp.MakeError(ErrSyntax)
+	case args.Has("reset"):
+		a.ResetLog()
+		return Emit(r, resp.OK)
+	case args.Has("count"):
+		c := args.Int("count")
+		if c < 0 {
+			return resp.MakeError(ErrNegativeCount)
+		}
+		count = int(c)
+	}
+
+	proto := ProtocolOf(r)
+	entries := a.Log(count)
+	res := make([]resp.Value, len(entries))
+	for i, e := range entries {
+		age := time.Since(e.Created).Seconds()
+		res[i] = protocolMap(resp.MakeMapFromPairs(
+			[2]resp.Value{resp.BulkString("count"), resp.Integer(e.Count)},
+			[2]resp.Value{resp.BulkString("reason"), resp.BulkString(e.Reason)},
+			[2]resp.Value{
+				resp.BulkString("context"), resp.BulkString(e.Context),
+			},
+			[2]resp.Value{resp.BulkString("object"), resp.BulkString(e.Object)},
+			[2]resp.Value{
+				resp.BulkString("username"), resp.BulkString(e.Username),
+			},
+			[2]resp.Value{
+				resp.BulkString("age-seconds"), scoreValue(age, proto),
+			},
+			[2]resp.Value{resp.BulkString("entry-id"), resp.Integer(e.ID)},
+			[2]resp.Value{
+				resp.BulkString("timestamp-created"),
+				resp.Integer(e.Created.UnixMilli()),
+			},
+			[2]resp.Value{
+				resp.BulkString("timestamp-last-updated"),
+				resp.Integer(e.Updated.UnixMilli()),
+			},
+		), proto)
+	}
+	return Emit(r, resp.MakeArray(res...))
+}
+
+func aclContext(r Responder) string {
+	if ss := SessionOf(r); ss != nil && (ss.tx.active || ss.tx.exec != nil) {
+		return contextMulti
+	}
+	return contextTopLevel
+}
+
+// protocolMap returns a Map for RESP3 clients, or the flattened Array that
+// RESP2 clients expect in its place
+func protocolMap(m *resp.Map, proto int) resp.Value {
+	if proto < RESP3 {
+		return flattenMap(m)
+	}
+	return m
+}
+
+func bulkStrings(s []string) *resp.Array {
+	res := make([]resp.Value, len(s))
+	for i, v := range s {
+		res[i] = resp.BulkString(v)
+	}
+	return resp.MakeArray(res...)
+}
@@ -0,0 +1,240 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+	h := command.ACLWrap(
+		a, command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+	)
+	r := newTestResponder()
+	run := func(args ...string) resp.Value {
+		if err := h(r, makeCommand(args...)...); err != nil {
+			return err.(resp.Value)
+		}
+		return <-r.output
+	}
+
+	as.Equal(resp.MakeError(command.ErrAuthNoPassword), run("AUTH", "pw"))
+	as.Equal(resp.OK, run("AUTH", "default", "anything"))
+
+	as.Equal(resp.OK, run("ACL", "SETUSER", "default", "resetpass", ">pw"))
+	as.Equal(resp.OK, run("ACL", "SETUSER", "bob", "on", ">bobpw", "~*"))
+
+	r = newTestResponder()
+	as.Equal(resp.MakeError(command.ErrNoAuth), run("GET", "key"))
+	as.Equal(resp.MakeError(command.ErrNoAuth), run("ACL", "WHOAMI"))
+	as.Equal(resp.MakeError(command.ErrWrongPass), run("AUTH", "wrong"))
+	as.Equal(resp.MakeError(command.ErrWrongPass), run("AUTH", "bob", "pw"))
+	as.Equal(
+		resp.MakeError(command.ErrSyntax),
+		run("AUTH", "a", "b", "c"),
+	)
+	as.Equal(resp.OK, run("AUTH", "pw"))
+	as.Equal(resp.BulkString("default"), run("ACL", "WHOAMI"))
+	as.Equal(resp.NullValue, run("GET", "key"))
+
+	as.Equal(resp.OK, run("AUTH", "bob", "bobpw"))
+	as.Equal(
+		resp.MakeError(command.ErrNoPermCommand, "bob", "get"),
+		run("GET", "key"),
+	)
+	as.Equal(resp.OK, run("AUTH", "default", "pw"))
+	as.Equal(resp.OK, run("ACL", "SETUSER", "bob", "off"))
+
+	r = newTestResponder()
+	as.Equal(resp.MakeError(command.ErrWrongPass), run("AUTH", "bob", "bobpw"))
+}
+
+func TestACLPermissions(t *testing.T) {
+	a := acl.NewACL()
+	h := command.ACLWrap(
+		a, command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+	)
+	testCommands(t, h, [][2]any{
+		{[]string{"SET", "user:1", "value"}, resp.OK},
+		{[]string{"SET", "other", "value"}, resp.OK},
+		{[]string{
+			"ACL", "SETUSER", "reader", "on", ">pw", "~user:*", "+@read",
+			"+acl", "+auth",
+		}, resp.OK},
+		{[]string{"AUTH", "reader", "pw"}, resp.OK},
+		{[]string{"GET", "user:1"}, resp.BulkString("value")},
+		{[]string{"get", "other"}, resp.MakeError(command.ErrNoPermKey)},
+		{[]string{"SET", "user:1", "changed"}, resp.MakeError(
+			command.ErrNoPermCommand, "reader", "set",
+		)},
+		{[]string{"SINTER", "user:1", "other"}, resp.MakeError(
+			command.ErrNoPermKey,
+		)},
+		{[]string{"KEYS", "user:*"}, resp.MakeArray(
+			resp.BulkString("user:1"),
+		)},
+		{[]string{"ACL", "WHOAMI"}, resp.BulkString("reader")},
+	})
+}
+
+func TestACLArrayKey(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+	h := command.ACLWrap(
+		a, command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+	)
+	r := newTestResponder()
+	run := func(args ...resp.Value) resp.Value {
+		if err := h(r, args...); err != nil {
+			return err.(resp.Value)
+		}
+		return <-r.output
+	}
+
+	as.Equal(resp.OK, run(makeCommand("SET", "secret", "value")...))
+	as.Equal(resp.OK, run(makeCommand(
+		"ACL", "SETUSER", "reader", "on", ">pw", "~public:*", "+@read",
+		"+auth",
+	)...))
+	as.Equal(resp.OK, run(makeCommand("AUTH", "reader", "pw")...))
+	as.Equal(
+		resp.MakeError(command.ErrNoPermKey),
+		run(resp.BulkString("GET"), resp.MakeArray(resp.BulkString("secret"))),
+	)
+}
+
+func TestACLTransaction(t *testing.T) {
+	a := acl.NewACL()
+	s := storage.NewMemory()
+	h := command.Use(
+		command.ACLWrap(a, command.Storage(s), command.StorageSpecs()...),
+		command.Transactions(s,
+			command.WithSpecs(command.StorageSpecs()...), command.WithACL(a),
+		),
+	)
+	testCommands(t, h, [][2]any{
+		{[]string{
+			"ACL", "SETUSER", "writer", "on", ">pw", "~*", "+@write",
+			"+auth",
+		}, resp.OK},
+		{[]string{"AUTH", "writer", "pw"}, resp.OK},
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"SET", "key", "value"}, command.Queued},
+		{[]string{"GET", "key"}, resp.MakeError(
+			command.ErrNoPermCommand, "writer", "get",
+		)},
+		{[]string{"EXEC"}, resp.MakeError(command.ErrExecAborted)},
+	})
+}
+
+func TestACLCommands(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+	h := command.ACL(a)
+	r := newTestResponder()
+	run := func(args ...string) resp.Value {
+		if err := h(r, makeCommand(args...)...); err != nil {
+			return err.(resp.Value)
+		}
+		return <-r.output
+	}
+
+	as.Equal(resp.OK, run("ACL", "SETUSER", "alice", "on", "~k*", "+get"))
+	as.Equal(resp.MakeArray(
+		resp.BulkString("user alice on ~k* +get"),
+		resp.BulkString("user default on nopass ~* +@all"),
+	), run("ACL", "LIST"))
+
+	as.Equal(8, run("ACL", "GETUSER", "alice").(*resp.Array).Count())
+	as.Equal(resp.NullValue, run("ACL", "GETUSER", "missing"))
+	as.Equal(
+		resp.MakeError(command.ErrWrongArity, "acl|getuser"),
+		run("ACL", "GETUSER"),
+	)
+
+	as.Nil(h(resp3Responder{r}, makeCommand("ACL", "GETUSER", "alice")...))
+	user := (<-r.output).(*resp.Map)
+	get := func(key string) resp.Value {
+		v, _ := user.Get(resp.BulkString(key))
+		return v
+	}
+	as.Equal(resp.MakeArray(resp.BulkString("on")), get("flags"))
+	as.Equal(resp.EmptyArray, get("passwords"))
+	as.Equal(resp.BulkString("+get"), get("commands"))
+	as.Equal(resp.BulkString("~k*"), get("keys"))
+
+	as.Equal(
+		resp.MakeError(acl.ErrInvalidRule, "bogus", acl.ErrSyntax),
+		run("ACL", "SETUSER", "alice", "bogus"),
+	)
+	as.Equal(
+		resp.MakeError(command.ErrUnknownSubcommand, "BOGUS"),
+		run("ACL", "BOGUS"),
+	)
+	as.Equal(resp.Integer(1), run("ACL", "DELUSER", "alice", "missing"))
+	as.Equal(
+		resp.MakeError(acl.ErrDeleteDefault),
+		run("ACL", "DELUSER", "default"),
+	)
+}
+
+func TestACLLog(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+	s := storage.NewMemory()
+	h := command.Use(
+		command.ACLWrap(a, command.Storage(s), command.StorageSpecs()...),
+		command.Transactions(s,
+			command.WithSpecs(command.StorageSpecs()...), command.WithACL(a),
+		),
+	)
+	r := newTestResponder()
+	run := func(args ...string) resp.Value {
+		if err := h(r, makeCommand(args...)...); err != nil {
+			return err.(resp.Value)
+		}
+		return <-r.output
+	}
+
+	as.Equal(resp.OK, run("ACL", "SETUSER", "alice", "on", ">pw", "+@all"))
+	as.Equal(resp.OK, run("AUTH", "alice", "pw"))
+	as.Equal(resp.MakeError(command.ErrNoPermKey), run("GET", "key"))
+	as.Equal(resp.OK, run("MULTI"))
+	as.Equal(resp.MakeError(command.ErrNoPermKey), run("GET", "key"))
+	as.Equal(resp.OK, run("DISCARD"))
+	as.Equal(resp.MakeError(command.ErrWrongPass), run("AUTH", "bob", "x"))
+
+	log := run("ACL", "LOG").(*resp.Array).Elements()
+	as.Equal(3, len(log))
+	entry := func(i int, key string) resp.Value {
+		elems := log[i].(*resp.Array).Elements()
+		for j := 0; j < len(elems); j += 2 {
+			if elems[j] == resp.BulkString(key) {
+				return elems[j+1]
+			}
+		}
+		return nil
+	}
+	as.Equal(resp.BulkString("auth"), entry(0, "reason"))
+	as.Equal(resp.BulkString("bob"), entry(0, "username"))
+	as.Equal(resp.BulkString("multi"), entry(1, "context"))
+	as.Equal(resp.BulkString("key"), entry(2, "object"))
+	as.Equal(resp.BulkString("toplevel"), entry(2, "context"))
+	as.Equal(resp.Integer(1), entry(2, "count"))
+
+	as.Equal(1, run("ACL", "LOG", "1").(*resp.Array).Count())
+	as.Equal(
+		resp.MakeError(command.ErrNegativeCount), run("ACL", "LOG", "-1"),
+	)
+	as.Equal(
+		resp.MakeError(command.ErrSyntax), run("ACL", "LOG", "1", "RESET"),
+	)
+	as.Equal(resp.OK, run("ACL", "LOG", "RESET"))
+	as.Equal(resp.EmptyArray, run("ACL", "LOG"))
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// Error messages
+const (
+	ErrSyntax     = "ERR syntax error"
+	ErrNotInteger = "ERR value is not an integer or out of range"
+)
+
+// asKeyword returns the upper-cased form of a bulk string argument, or an
+// empty string if the argument isn't a bulk string
+func asKeyword(v resp.Value) string {
+	if s, ok := v.(resp.BulkString); ok {
+		return strings.ToUpper(string(s))
+	}
+	return ""
+}
+
+// asInteger parses an argument as a base 10, 64-bit signed integer
+func asInteger(v resp.Value) (int64, error) {
+	var s string
+	switch v := v.(type) {
+	case resp.Integer:
+		return int64(v), nil
+	case resp.String:
+		s = v.String()
+	default:
+		return 0, resp.MakeError(ErrNotInteger)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, resp.MakeError(ErrNotInteger)
+	}
+	return i, nil
+}
+
+// asRange parses a pair of arguments as the inclusive start and stop indexes
+// of a range
+func asRange(start, stop resp.Value) (int, int, error) {
+	from, err := asInteger(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := asInteger(stop)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(from), int(to), nil
+}
+
+// asBulkStrings requires that each argument is a bulk string
+func asBulkStrings(args []resp.Value) ([]resp.BulkString, error) {
+	res := make([]resp.BulkString, len(args))
+	for i, a := range args {
+		s, ok := a.(resp.BulkString)
+		if !ok {
+			return nil, resp.MakeError(ErrSyntax)
+		}
+		res[i] = s
+	}
+	return res, nil
+}
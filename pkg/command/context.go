@@ -1,6 +1,7 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/kode4food/respect/pkg/resp"
@@ -31,6 +32,18 @@ type (
 		Responder
 		Close() error
 	}
+
+	// ValueWriter is implemented by Responders that can write values to
+	// their client directly, sparing Emit the hop through their channel
+	ValueWriter interface {
+		WriteValue(resp.Value) error
+	}
+
+	// StreamWriter is implemented by Responders that can stream a reply to
+	// their client as it's produced, rather than once it's complete
+	StreamWriter interface {
+		Stream(func(*resp.Writer) error) error
+	}
 )
 
 // HandleNext processes the next command in the Context
@@ -59,6 +72,9 @@ func Accept(r Requester) (resp.Value, error) {
 
 // Emit sends a value to the Responder
 func Emit(r Responder, v resp.Value) error {
+	if w, ok := r.(ValueWriter); ok {
+		return w.WriteValue(v)
+	}
 	select {
 	case <-r.Closed():
 		return fmt.Errorf(ErrContextClosed)
@@ -68,6 +84,35 @@ func Emit(r Responder, v resp.Value) error {
 	}
 }
 
+// Stream writes a single reply to the Responder element by element, so that
+// large collections needn't be built in memory. The provided function must
+// write exactly one value, and mustn't Emit anything while doing so. If the
+// Responder can't stream, the reply is collected and then emitted
+func Stream(r Responder, fn func(*resp.Writer) error) error {
+	if s, ok := r.(StreamWriter); ok {
+		return s.Stream(fn)
+	}
+	v, err := Collect(fn)
+	if err != nil {
+		return err
+	}
+	return Emit(r, v)
+}
+
+// Collect builds the value that a streaming function writes, for Responders
+// that can't stream it directly
+func Collect(fn func(*resp.Writer) error) (resp.Value, error) {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf, resp.SingleValue)
+	if err := fn(w); err != nil {
+		return nil, err
+	}
+	if err := w.Finish(); err != nil {
+		return nil, err
+	}
+	return resp.ReadBytes(buf.Bytes())
+}
+
 func IsClosed(c Closer) bool {
 	select {
 	case <-c.Closed():
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+// Error messages
+const (
+	ErrFloatOverflow = "ERR increment would produce NaN or Infinity"
+)
+
+func incrementOp(by int64) argsOp {
+	return func(s storage.Storage, a *Args) (resp.Value, error) {
+		return incrementBy(s, a.Key("key"), by)
+	}
+}
+
+func incrementByOp(sign int64) argsOp {
+	name := "increment"
+	if sign < 0 {
+		name = "decrement"
+	}
+	return func(s storage.Storage, a *Args) (resp.Value, error) {
+		by := a.Int(name)
+		if sign < 0 && by == math.MinInt64 {
+			return nil, resp.MakeError(ErrOverflow)
+		}
+		return incrementBy(s, a.Key("key"), by*sign)
+	}
+}
+
+func incrementBy(s storage.Storage, key storage.Key, by int64) (
+	resp.Value, error,
+) {
+	var res int64
+	_, err := s.Update(key, func(v resp.Value) (resp.Value, error) {
+		var cur int64
+		if v != nil {
+			i, err := asInteger(v)
+			if err != nil {
+				return nil, err
+			}
+			cur = i
+		}
+		sum, err := checkedAdd(cur, by)
+		if err != nil {
+			return nil, err
+		}
+		res = sum
+		return resp.BulkString(strconv.FormatInt(sum, 10)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(res), nil
+}
+
+func incrementByFloatOp(s storage.Storage, a *Args) (resp.Value, error) {
+	by := a.Float("increment")
+	return s.Update(a.Key("key"), func(v resp.Value) (resp.Value, error) {
+		var cur float64
+		if v != nil {
+			f, err := asFloat(v)
+			if err != nil {
+				return nil, err
+			}
+			cur = f
+		}
+		res := cur + by
+		if math.IsInf(res, 0) || math.IsNaN(res) {
+			return nil, resp.MakeError(ErrFloatOverflow)
+		}
+		return resp.BulkString(strconv.FormatFloat(res, 'f', -1, 64)), nil
+	})
+}
+
+func appendOp(s storage.Storage, a *Args) (resp.Value, error) {
+	suffix, ok := a.Value("value").(resp.String)
+	if !ok {
+		return nil, resp.MakeError(ErrSyntax)
+	}
+	res, err := s.Update(a.Key("key"), func(v resp.Value) (resp.Value, error) {
+		if v == nil {
+			return resp.BulkString(suffix.String()), nil
+		}
+		cur, ok := v.(resp.String)
+		if !ok {
+			return nil, storage.WrongType
+		}
+		return resp.BulkString(cur.String() + suffix.String()), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(len(res.(resp.BulkString))), nil
+}
+
+func getSetOp(s storage.Storage, a *Args) (resp.Value, error) {
+	opts := storage.SetOptions{ValuesOnly: true}
+	old, _, err := s.SetWith(a.Key("key"), a.Value("value"), opts)
+	switch {
+	case err != nil:
+		return nil, err
+	case old == nil:
+		return resp.NullValue, nil
+	default:
+		return old, nil
+	}
+}
+
+func setIfNotExistsOp(s storage.Storage, a *Args) (resp.Value, error) {
+	_, ok, err := s.SetWith(a.Key("key"), a.Value("value"), storage.SetOptions{
+		Condition: storage.IfNotExists,
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case ok:
+		return resp.Integer(1), nil
+	default:
+		return resp.Integer(0), nil
+	}
+}
+
+// checkedAdd adds two integers, failing if the result would overflow
+func checkedAdd(l, r int64) (int64, error) {
+	if r > 0 && l > math.MaxInt64-r || r < 0 && l < math.MinInt64-r {
+		return 0, resp.MakeError(ErrOverflow)
+	}
+	return l + r, nil
+}
@@ -0,0 +1,81 @@
+package command_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounters(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"INCR", "n"}, resp.Integer(1)},
+		{[]string{"INCRBY", "n", "41"}, resp.Integer(42)},
+		{[]string{"DECR", "n"}, resp.Integer(41)},
+		{[]string{"DECRBY", "n", "-9"}, resp.Integer(50)},
+		{[]string{"GET", "n"}, resp.BulkString("50")},
+		{[]string{"EXPIRE", "n", "100"}, resp.Integer(1)},
+		{[]string{"INCR", "n"}, resp.Integer(51)},
+		{[]string{"TTL", "n"}, resp.Integer(100)},
+		{[]string{"INCRBY", "n", "x"}, resp.MakeError(command.ErrNotInteger)},
+		{[]string{"SET", "max", "9223372036854775807"}, resp.OK},
+		{[]string{"INCR", "max"}, resp.MakeError(command.ErrOverflow)},
+		{[]string{"DECRBY", "n", "-9223372036854775808"}, resp.MakeError(command.ErrOverflow)},
+		{[]string{"SET", "str", "abc"}, resp.OK},
+		{[]string{"INCR", "str"}, resp.MakeError(command.ErrNotInteger)},
+		{[]string{"INCRBYFLOAT", "f", "1.5"}, resp.BulkString("1.5")},
+		{[]string{"INCRBYFLOAT", "f", "-0.25"}, resp.BulkString("1.25")},
+		{[]string{"INCRBYFLOAT", "n", "0.5"}, resp.BulkString("51.5")},
+		{[]string{"INCRBYFLOAT", "f", "inf"}, resp.MakeError(command.ErrFloatOverflow)},
+		{[]string{"INCRBYFLOAT", "str", "1"}, resp.MakeError(command.ErrNotFloat)},
+		{[]string{"RPUSH", "list", "a"}, resp.Integer(1)},
+		{[]string{"INCR", "list"}, storage.WrongType},
+	})
+}
+
+func TestStrings(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"APPEND", "s", "Hello"}, resp.Integer(5)},
+		{[]string{"APPEND", "s", ", World"}, resp.Integer(12)},
+		{[]string{"GET", "s"}, resp.BulkString("Hello, World")},
+		{[]string{"SETNX", "s", "other"}, resp.Integer(0)},
+		{[]string{"SETNX", "t", "value"}, resp.Integer(1)},
+		{[]string{"EXPIRE", "t", "100"}, resp.Integer(1)},
+		{[]string{"GETSET", "t", "new"}, resp.BulkString("value")},
+		{[]string{"TTL", "t"}, resp.Integer(-1)},
+		{[]string{"GETSET", "u", "new"}, resp.NullValue},
+		{[]string{"GET", "u"}, resp.BulkString("new")},
+		{[]string{"RPUSH", "list", "a"}, resp.Integer(1)},
+		{[]string{"APPEND", "list", "a"}, storage.WrongType},
+		{[]string{"GETSET", "list", "a"}, storage.WrongType},
+		{[]string{"SETNX", "list"}, resp.MakeError(command.ErrWrongArity, "setnx")},
+	})
+}
+
+func TestConcurrentIncrement(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := newTestResponder()
+			for j := 0; j < 250; j++ {
+				as.Nil(h(r, makeCommand("INCR", "counter")...))
+				<-r.output
+			}
+		}()
+	}
+	wg.Wait()
+
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand("GET", "counter")...))
+	as.Equal(resp.BulkString("2000"), <-r.output)
+}
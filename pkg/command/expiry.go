@@ -0,0 +1,117 @@
+package command
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+// deadlineParser converts a command argument into an absolute deadline
+type deadlineParser func(resp.Value) (time.Time, error)
+
+// Error messages
+const (
+	ErrInvalidExpireTime = "ERR invalid expire time"
+)
+
+// TTL replies for keys that are missing or that have no deadline
+const (
+	ttlMissing    = resp.Integer(-2)
+	ttlNoDeadline = resp.Integer(-1)
+)
+
+// setDeadlines parse the expiration arguments of SET, by name
+var setDeadlines = map[string]deadlineParser{
+	"seconds":                fromNow(time.Second),
+	"milliseconds":           fromNow(time.Millisecond),
+	"unix-time-seconds":      fromEpoch(time.Second),
+	"unix-time-milliseconds": fromEpoch(time.Millisecond),
+}
+
+// expireOp sets a deadline from the argument that's named by its unit, as
+// in setDeadlines
+func expireOp(unit string) argsOp {
+	parse := setDeadlines[unit]
+	return func(s storage.Storage, a *Args) (resp.Value, error) {
+		deadline, err := parse(resp.Integer(a.Int(unit)))
+		if err != nil {
+			return nil, err
+		}
+		err = s.Expire(a.Key("key"), deadline)
+		if errors.Is(err, storage.KeyNotFound) {
+			return resp.ZeroInteger, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return resp.Integer(1), nil
+	}
+}
+
+func ttlOp(unit time.Duration) argsOp {
+	return func(s storage.Storage, a *Args) (resp.Value, error) {
+		deadline, ok, err := s.Deadline(a.Key("key"))
+		switch {
+		case errors.Is(err, storage.KeyNotFound):
+			return ttlMissing, nil
+		case err != nil:
+			return nil, err
+		case !ok:
+			return ttlNoDeadline, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		return resp.Integer((remaining + unit/2) / unit), nil
+	}
+}
+
+func persistOp(s storage.Storage, a *Args) (resp.Value, error) {
+	ok, err := s.Persist(a.Key("key"))
+	switch {
+	case err != nil && !errors.Is(err, storage.KeyNotFound):
+		return nil, err
+	case err != nil || !ok:
+		return resp.ZeroInteger, nil
+	default:
+		return resp.Integer(1), nil
+	}
+}
+
+// fromNow parses an argument as a number of units relative to the present
+func fromNow(unit time.Duration) deadlineParser {
+	return func(v resp.Value) (time.Time, error) {
+		d, err := asDuration(v, unit)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Now().Add(d), nil
+	}
+}
+
+// fromEpoch parses an argument as a number of units since the Unix epoch
+func fromEpoch(unit time.Duration) deadlineParser {
+	return func(v resp.Value) (time.Time, error) {
+		d, err := asDuration(v, unit)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, 0).Add(d), nil
+	}
+}
+
+func asDuration(v resp.Value, unit time.Duration) (time.Duration, error) {
+	i, err := asInteger(v)
+	if err != nil {
+		return 0, err
+	}
+	limit := int64(math.MaxInt64 / unit)
+	if i > limit || i < -limit {
+		return 0, resp.MakeError(ErrInvalidExpireTime)
+	}
+	return time.Duration(i) * unit, nil
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/kode4food/respect/pkg/resp"
@@ -11,12 +12,19 @@ type (
 	// Handler is a function that processes a command
 	Handler func(Responder, ...resp.Value) error
 
+	// argsHandler is a Handler whose arguments have been parsed according
+	// to its CommandSpec
+	argsHandler func(Responder, *Args) error
+
 	// Handlers is a map of command verbs to their respective Handler
 	Handlers map[resp.BulkString]Handler
 
 	// handlers is the internal representation of Handler mapping, as handlers
 	// are case-insensitive, and the Handlers type is essentially a DTO
 	handlers Handlers
+
+	// specTable holds the CommandSpecs of a set of verbs, keyed by verb
+	specTable map[resp.BulkString]*CommandSpec
 )
 
 // Error messages
@@ -26,23 +34,31 @@ const (
 	ErrExpectedBulkString = "WRONGTYPE expected bulk string as command"
 	ErrUnknownCommand     = "ERR unknown command '%s'"
 	ErrCommandProcessing  = "ERR error processing %s. %w"
+	ErrUnknownSubcommand  = "ERR unknown subcommand '%s'"
 )
 
-// NewHandler creates a new Handler from a Handlers map
-func NewHandler(h Handlers) Handler {
-	return Wrap(h, NoHandler)
+// NewHandler creates a new Handler from a Handlers map, validating commands
+// against the CommandSpecs provided for their verbs
+func NewHandler(h Handlers, specs ...*CommandSpec) Handler {
+	return Wrap(h, NoHandler, specs...)
 }
 
 // NoHandler raises the unknown command error, and can be used to terminate a
 // command chain
 func NoHandler(_ Responder, args ...resp.Value) error {
+	if len(args) == 0 {
+		return resp.MakeError(ErrEmptyCommand)
+	}
 	return resp.MakeError(fmt.Sprintf(ErrUnknownCommand, args[0]))
 }
 
 // Wrap creates a new Handler from a Handlers map, falling back to a wrapped
-// Handler if none are found
-func Wrap(h Handlers, wrapped Handler) Handler {
+// Handler if none are found. Commands are validated against the CommandSpecs
+// provided for their verbs, and a verb without one accepts any number of
+// arguments
+func Wrap(h Handlers, wrapped Handler, specs ...*CommandSpec) Handler {
 	i := h.toInternal()
+	t := makeSpecTable(i, specs)
 	return func(c Responder, args ...resp.Value) error {
 		if len(args) == 0 {
 			return resp.MakeError(ErrEmptyCommand)
@@ -52,15 +68,37 @@ func Wrap(h Handlers, wrapped Handler) Handler {
 		}
 		verb := normalizeVerb(args[0].(resp.BulkString))
 		if cmd, ok := i[verb]; ok {
-			if err := cmd(c, args[1:]...); err != nil {
-				return fmt.Errorf(ErrCommandProcessing, verb, err)
+			if err := t[verb].CheckArity(args); err != nil {
+				return err
 			}
-			return nil
+			return wrapError(verb, cmd(c, args[1:]...))
 		}
 		return wrapped(c, args...)
 	}
 }
 
+func wrapArgsHandler(spec *CommandSpec, h argsHandler) Handler {
+	return func(r Responder, args ...resp.Value) error {
+		a, err := spec.Parse(args...)
+		if err != nil {
+			return err
+		}
+		return h(r, a)
+	}
+}
+
+// wrapError adds the verb to errors that aren't already meant to be reported
+// to the client as they are
+func wrapError(verb resp.BulkString, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(resp.Error); ok {
+		return err
+	}
+	return fmt.Errorf(ErrCommandProcessing, verb, err)
+}
+
 func normalizeVerb(k resp.BulkString) resp.BulkString {
 	return resp.BulkString(strings.ToUpper(string(k)))
 }
@@ -73,3 +111,65 @@ func (h Handlers) toInternal() handlers {
 	}
 	return lh
 }
+
+// makeSpecTable pairs each verb of a handlers map with its CommandSpec, or
+// with one that accepts any number of arguments if none is provided
+func makeSpecTable(h handlers, specs []*CommandSpec) specTable {
+	res := make(specTable, len(h))
+	for _, s := range specs {
+		verb := normalizeVerb(resp.BulkString(s.Name))
+		if _, ok := h[verb]; ok {
+			res[verb] = s
+		}
+	}
+	for verb := range h {
+		if _, ok := res[verb]; !ok {
+			res[verb] = &CommandSpec{
+				Name:  strings.ToLower(string(verb)),
+				Arity: -1,
+			}
+		}
+	}
+	return res
+}
+
+// specTableOf keys a set of CommandSpecs by the verbs that they describe
+func specTableOf(specs []*CommandSpec) specTable {
+	res := make(specTable, len(specs))
+	for _, s := range specs {
+		res[normalizeVerb(resp.BulkString(s.Name))] = s
+	}
+	return res
+}
+
+// wrappedSpecs keys the CommandSpecs of a set of Handlers by verb, along
+// with the ones provided for the Handler that they wrap. If both describe a
+// verb, the outer CommandSpec is kept, as it's the one that receives the
+// command
+func wrappedSpecs(outer, wrapped []*CommandSpec) specTable {
+	res := specTableOf(wrapped)
+	for verb, s := range specTableOf(outer) {
+		res[verb] = s
+	}
+	return res
+}
+
+func (t specTable) sorted() []*CommandSpec {
+	res := make([]*CommandSpec, 0, len(t))
+	for _, s := range t {
+		res = append(res, s)
+	}
+	slices.SortFunc(res, func(l, r *CommandSpec) int {
+		return strings.Compare(l.Name, r.Name)
+	})
+	return res
+}
+
+// lookup returns the CommandSpec of a verb, or one that only names it if the
+// verb isn't in the table
+func (t specTable) lookup(verb resp.BulkString) *CommandSpec {
+	if s, ok := t[normalizeVerb(verb)]; ok {
+		return s
+	}
+	return &CommandSpec{Name: strings.ToLower(string(verb))}
+}
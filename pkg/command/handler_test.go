@@ -3,8 +3,10 @@ package command_test
 import (
 	"testing"
 
+	"github.com/kode4food/respect/pkg/acl"
 	"github.com/kode4food/respect/pkg/command"
 	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,3 +19,40 @@ func TestNewHandler(t *testing.T) {
 	})
 	as.NotNil(h)
 }
+
+func TestStream(t *testing.T) {
+	as := assert.New(t)
+	r := newTestResponder()
+
+	as.Nil(command.Stream(r, func(w *resp.Writer) error {
+		if err := w.WriteArrayHeader(2); err != nil {
+			return err
+		}
+		if err := w.WriteString("first"); err != nil {
+			return err
+		}
+		return w.WriteInteger(2)
+	}))
+	as.Equal(
+		resp.MakeArray(resp.BulkString("first"), resp.Integer(2)),
+		<-r.output,
+	)
+
+	err := command.Stream(r, func(w *resp.Writer) error {
+		return w.WriteArrayHeader(1)
+	})
+	as.EqualError(err, "ERR incomplete aggregate: 1 element(s) missing")
+}
+
+func TestWrapDoesNotCallHandlers(t *testing.T) {
+	as := assert.New(t)
+	verb := func(_ command.Responder, args ...resp.Value) error {
+		return args[0].(resp.Error)
+	}
+	as.NotPanics(func() {
+		command.Use(
+			command.ACLWrap(acl.NewACL(), command.IntrospectionWrap(verb)),
+			command.Transactions(storage.NewMemory()),
+		)
+	})
+}
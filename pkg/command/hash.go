@@ -0,0 +1,222 @@
+package command
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/kode4food/respect/pkg/glob"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+// Error messages
+const (
+	ErrHashNotInteger = "ERR hash value is not an integer"
+	ErrOverflow       = "ERR increment or decrement would overflow"
+	ErrExpectedField  = "ERR expected bulk string as field"
+)
+
+// pairedFields reports a field without a value as the wrong number of
+// arguments, rather than as the syntax error that parsing it would raise
+func pairedFields(h Handler) Handler {
+	return func(r Responder, args ...resp.Value) error {
+		if len(args)%2 == 0 {
+			return resp.MakeError(ErrWrongArity, hsetSpec.Name)
+		}
+		return h(r, args...)
+	}
+}
+
+func hashSetOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	fields, err := asFields(a.Values("field"))
+	if err != nil {
+		return nil, err
+	}
+	values := a.Values("value")
+	added := 0
+	err = storage.MutateHash(s, key, true, func(h *storage.Hash) error {
+		for i, f := range fields {
+			if h.Set(f, values[i]) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(added), nil
+}
+
+func hashGetOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	field, err := asField(a.Value("field"))
+	if err != nil {
+		return nil, err
+	}
+	var res resp.Value = resp.NullValue
+	err = storage.InspectHash(s, key, func(h *storage.Hash) error {
+		if v, ok := h.Get(field); ok {
+			res = v
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func hashDeleteOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	fields, err := asFields(a.Values("field"))
+	if err != nil {
+		return nil, err
+	}
+	deleted := 0
+	err = storage.MutateHash(s, key, false, func(h *storage.Hash) error {
+		for _, f := range fields {
+			if h.Delete(f) {
+				deleted++
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(deleted), nil
+}
+
+func hashLenOp(s storage.Storage, a *Args) (resp.Value, error) {
+	var res int
+	err := storage.InspectHash(s, a.Key("key"), func(h *storage.Hash) error {
+		res = h.Len()
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(res), nil
+}
+
+func hashExistsOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	field, err := asField(a.Value("field"))
+	if err != nil {
+		return nil, err
+	}
+	res := resp.ZeroInteger
+	err = storage.InspectHash(s, key, func(h *storage.Hash) error {
+		if _, ok := h.Get(field); ok {
+			res = resp.Integer(1)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func hashGetAllOp(
+	s storage.Storage, proto int, a *Args,
+) (resp.Value, error) {
+	res := resp.EmptyMap
+	err := storage.InspectHash(s, a.Key("key"), func(h *storage.Hash) error {
+		res = h.Snapshot().(*resp.Map)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	if proto < RESP3 {
+		return flattenMap(res), nil
+	}
+	return res, nil
+}
+
+func hashIncrementOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	field, err := asField(a.Value("field"))
+	if err != nil {
+		return nil, err
+	}
+	by := a.Int("increment")
+	var res int64
+	err = storage.MutateHash(s, key, true, func(h *storage.Hash) error {
+		var cur int64
+		if v, ok := h.Get(field); ok {
+			i, err := asInteger(v)
+			if err != nil {
+				return resp.MakeError(ErrHashNotInteger)
+			}
+			cur = i
+		}
+		sum, err := checkedAdd(cur, by)
+		if err != nil {
+			return err
+		}
+		res = sum
+		h.Set(field, resp.BulkString(strconv.FormatInt(res, 10)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(res), nil
+}
+
+func hashScanOp(s storage.Storage, a *Args) (resp.Value, error) {
+	cursor, err := asCursor(a.Value("cursor"))
+	if err != nil {
+		return nil, err
+	}
+	opts, err := scanOptionsOf(a)
+	if err != nil {
+		return nil, err
+	}
+	var next uint64
+	res := []resp.Value{}
+	err = storage.InspectHash(s, a.Key("key"), func(h *storage.Hash) error {
+		next = h.Scan(cursor, opts.count,
+			func(f resp.BulkString, v resp.Value) {
+				if opts.match != "" && !glob.Match(opts.match, string(f)) {
+					return
+				}
+				res = append(res, f)
+				if !opts.noValues {
+					res = append(res, v)
+				}
+			},
+		)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.MakeArray(
+		resp.BulkString(strconv.FormatUint(next, 10)),
+		resp.MakeArray(res...),
+	), nil
+}
+
+func asField(v resp.Value) (resp.BulkString, error) {
+	if f, ok := v.(resp.BulkString); ok {
+		return f, nil
+	}
+	return "", resp.MakeError(ErrExpectedField)
+}
+
+func asFields(args []resp.Value) ([]resp.BulkString, error) {
+	res := make([]resp.BulkString, len(args))
+	for i, v := range args {
+		f, err := asField(v)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = f
+	}
+	return res, nil
+}
@@ -0,0 +1,109 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type resp3Responder struct {
+	*testResponder
+}
+
+func (resp3Responder) Protocol() int {
+	return command.RESP3
+}
+
+func TestHash(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"HGET", "hash", "field"}, resp.NullValue},
+		{[]string{"HLEN", "hash"}, resp.Integer(0)},
+		{[]string{"HSET", "hash", "a", "1", "b", "2"}, resp.Integer(2)},
+		{[]string{"HSET", "hash", "b", "3", "c", "4"}, resp.Integer(1)},
+		{[]string{"HGET", "hash", "b"}, resp.BulkString("3")},
+		{[]string{"HLEN", "hash"}, resp.Integer(3)},
+		{[]string{"HEXISTS", "hash", "c"}, resp.Integer(1)},
+		{[]string{"HEXISTS", "hash", "d"}, resp.Integer(0)},
+		{[]string{"HINCRBY", "hash", "c", "-10"}, resp.Integer(-6)},
+		{[]string{"HINCRBY", "hash", "d", "5"}, resp.Integer(5)},
+		{[]string{"HGET", "hash", "d"}, resp.BulkString("5")},
+		{[]string{"HSET", "hash", "max", "9223372036854775807"}, resp.Integer(1)},
+		{[]string{"HINCRBY", "hash", "max", "1"}, resp.MakeError(command.ErrOverflow)},
+		{[]string{"HSET", "hash", "str", "abc"}, resp.Integer(1)},
+		{[]string{"HINCRBY", "hash", "str", "1"}, resp.MakeError(command.ErrHashNotInteger)},
+		{[]string{"HDEL", "hash", "a", "b", "z"}, resp.Integer(2)},
+		{[]string{"HSET", "hash", "a"}, resp.MakeError(command.ErrWrongArity, "hset")},
+		{[]string{"HSET", "hash", "a", "1", "b"}, resp.MakeError(command.ErrWrongArity, "hset")},
+		{[]string{"TYPE", "hash"}, resp.SimpleString("hash")},
+		{[]string{"LPUSH", "hash", "a"}, storage.WrongType},
+		{[]string{"HDEL", "hash", "c", "d", "max", "str"}, resp.Integer(4)},
+		{[]string{"TYPE", "hash"}, resp.SimpleString("none")},
+	})
+}
+
+func TestHashGetAll(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand("HSET", "hash", "a", "1", "b", "2")...))
+	<-r.output
+
+	as.Nil(h(r, makeCommand("HGETALL", "hash")...))
+	res := <-r.output
+	as.Equal(resp.ArrayTag, res.Tag())
+	as.ElementsMatch(
+		makeCommand("a", "1", "b", "2"), res.(*resp.Array).Elements(),
+	)
+
+	r3 := resp3Responder{r}
+	as.Nil(h(r3, makeCommand("HGETALL", "hash")...))
+	as.True(resp.MakeMap(map[resp.BulkString]resp.Value{
+		"a": resp.BulkString("1"),
+		"b": resp.BulkString("2"),
+	}).Equal(<-r.output))
+
+	as.Nil(h(r3, makeCommand("HGETALL", "missing")...))
+	as.True(resp.EmptyMap.Equal(<-r.output))
+	as.Nil(h(r, makeCommand("HGETALL", "missing")...))
+	as.Equal(resp.EmptyArray, <-r.output)
+}
+
+func TestHashScan(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand(
+		"HSET", "hash", "a1", "1", "a2", "2", "b1", "3",
+	)...))
+	<-r.output
+
+	as.Nil(h(r, makeCommand(
+		"HSCAN", "hash", "0", "MATCH", "a*", "COUNT", "100",
+	)...))
+	res := (<-r.output).(*resp.Array).Elements()
+	as.Equal(resp.BulkString("0"), res[0])
+	as.ElementsMatch(
+		makeCommand("a1", "1", "a2", "2"), res[1].(*resp.Array).Elements(),
+	)
+
+	as.Nil(h(r, makeCommand("HSCAN", "hash", "0", "NOVALUES")...))
+	res = (<-r.output).(*resp.Array).Elements()
+	as.ElementsMatch(
+		makeCommand("a1", "a2", "b1"), res[1].(*resp.Array).Elements(),
+	)
+
+	testCommands(t, h, [][2]any{
+		{
+			[]string{"HSCAN", "missing", "0"},
+			resp.MakeArray(resp.BulkString("0"), resp.EmptyArray),
+		},
+		{
+			[]string{"HSCAN", "hash", "0", "TYPE", "string"},
+			resp.MakeError(command.ErrSyntax),
+		},
+	})
+}
@@ -0,0 +1,308 @@
+package command
+
+import (
+	"maps"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// Error messages
+const (
+	ErrInvalidCommand = "ERR Invalid command specified"
+	ErrInvalidArgs    = "ERR Invalid number of arguments specified for command"
+	ErrNoKeyArgs      = "ERR The command has no key arguments"
+)
+
+// Described is implemented by Responders that know the CommandSpecs of
+// commands that their client can issue, such as those processed by the
+// Handlers that wrap Introspection
+type Described interface {
+	CommandSpecs() []*CommandSpec
+}
+
+// Introspection creates a Handler for the COMMAND command, which describes
+// the commands that its client can issue
+func Introspection(specs ...*CommandSpec) Handler {
+	return IntrospectionWrap(NoHandler, specs...)
+}
+
+// IntrospectionWrap creates a Handler for the COMMAND command, falling back
+// to the wrapped Handler for all other commands. Commands are described by
+// the CommandSpecs provided for them and, if the Responder is Described, by
+// its CommandSpecs as well
+func IntrospectionWrap(next Handler, specs ...*CommandSpec) Handler {
+	t := wrappedSpecs(introspectionSpecs, specs)
+	return Wrap(Handlers{
+		"COMMAND": func(r Responder, args ...resp.Value) error {
+			return commandOp(r, describedSpecs(r, t), args)
+		},
+	}, next, introspectionSpecs...)
+}
+
+// describedSpecs adds the CommandSpecs that a Responder is Described by to
+// the provided ones. The Responder's are kept if both describe a verb, as
+// they belong to Handlers further out
+func describedSpecs(r Responder, specs specTable) specTable {
+	d, ok := r.(Described)
+	if !ok {
+		return specs
+	}
+	res := maps.Clone(specs)
+	for verb, s := range specTableOf(d.CommandSpecs()) {
+		res[verb] = s
+	}
+	return res
+}
+
+func commandOp(r Responder, specs specTable, args []resp.Value) error {
+	proto := ProtocolOf(r)
+	if len(args) == 0 {
+		return Emit(r, commandInfos(specs.sorted(), proto))
+	}
+	sub, ok := args[0].(resp.BulkString)
+	if !ok {
+		return resp.MakeError(ErrExpectedBulkString)
+	}
+	rest := args[1:]
+	switch normalizeVerb(sub) {
+	case "INFO":
+		return commandInfo(r, specs, proto, rest)
+	case "COUNT":
+		return commandCount(r, specs, rest)
+	case "DOCS":
+		return commandDocs(r, specs, proto, rest)
+	case "GETKEYS":
+		return commandGetKeys(r, specs, rest)
+	default:
+		return resp.MakeError(ErrUnknownSubcommand, sub)
+	}
+}
+
+func commandInfo(
+	r Responder, specs specTable, proto int, args []resp.Value,
+) error {
+	res, err := lookupSpecs(specs, args)
+	if err != nil {
+		return err
+	}
+	return Emit(r, commandInfos(res, proto))
+}
+
+func commandCount(r Responder, specs specTable, args []resp.Value) error {
+	if len(args) != 0 {
+		return resp.MakeError(ErrWrongArity, "command|count")
+	}
+	return Emit(r, resp.Integer(len(specs)))
+}
+
+func commandDocs(
+	r Responder, specs specTable, proto int, args []resp.Value,
+) error {
+	res, err := lookupSpecs(specs, args)
+	if err != nil {
+		return err
+	}
+	pairs := make([][2]resp.Value, 0, len(res))
+	for _, s := range res {
+		if s != nil {
+			pairs = append(pairs, [2]resp.Value{
+				resp.BulkString(s.Name), specDocs(s, proto),
+			})
+		}
+	}
+	return Emit(r, protocolMap(resp.MakeMapFromPairs(pairs...), proto))
+}
+
+func commandGetKeys(r Responder, specs specTable, args []resp.Value) error {
+	if len(args) == 0 {
+		return resp.MakeError(ErrWrongArity, "command|getkeys")
+	}
+	verb, ok := args[0].(resp.BulkString)
+	if !ok {
+		return resp.MakeError(ErrInvalidCommand)
+	}
+	s, ok := specs[normalizeVerb(verb)]
+	if !ok {
+		return resp.MakeError(ErrInvalidCommand)
+	}
+	if s.CheckArity(args) != nil {
+		return resp.MakeError(ErrInvalidArgs)
+	}
+	keys, err := s.Keys(args)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return resp.MakeError(ErrNoKeyArgs)
+	}
+	res := make([]resp.Value, len(keys))
+	for i, k := range keys {
+		res[i] = k.ToValue()
+	}
+	return Emit(r, resp.MakeArray(res...))
+}
+
+// lookupSpecs returns the CommandSpecs of the named commands, leaving a nil
+// in place of any that aren't in the table. If no names are provided, every
+// CommandSpec in the table is returned
+func lookupSpecs(specs specTable, args []resp.Value) ([]*CommandSpec, error) {
+	if len(args) == 0 {
+		return specs.sorted(), nil
+	}
+	names, err := asBulkStrings(args)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*CommandSpec, len(names))
+	for i, n := range names {
+		res[i] = specs[normalizeVerb(n)]
+	}
+	return res, nil
+}
+
+func commandInfos(specs []*CommandSpec, proto int) *resp.Array {
+	res := make([]resp.Value, len(specs))
+	for i, s := range specs {
+		if s == nil {
+			res[i] = resp.NullValue
+			continue
+		}
+		res[i] = specInfo(s, proto)
+	}
+	return resp.MakeArray(res...)
+}
+
+// specInfo describes a command in the form that COMMAND INFO replies with:
+// its name, arity, flags, key positions, ACL categories, tips, key
+// specifications and subcommands
+func specInfo(s *CommandSpec, proto int) *resp.Array {
+	return resp.MakeArray(
+		resp.BulkString(s.Name),
+		resp.Integer(s.Arity),
+		simpleStrings(proto, "", s.Flags.Names()...),
+		resp.Integer(s.FirstKey),
+		resp.Integer(s.LastKey),
+		resp.Integer(s.Step),
+		simpleStrings(proto, "@", s.ACLCategories()...),
+		resp.EmptyArray,
+		keySpecs(s, proto),
+		resp.EmptyArray,
+	)
+}
+
+// keySpecs describes the key positions of a command as the index at which
+// its keys begin and the range that they're found in from there
+func keySpecs(s *CommandSpec, proto int) *resp.Array {
+	if s.FirstKey <= 0 {
+		return resp.EmptyArray
+	}
+	last := s.LastKey
+	if last >= 0 {
+		last -= s.FirstKey
+	}
+	var flags []string
+	if s.Flags.Has(FlagReadOnly) {
+		flags = append(flags, "RO")
+	}
+	if s.Flags.Has(FlagWrite) {
+		flags = append(flags, "RW")
+	}
+	return resp.MakeArray(protocolMap(resp.MakeMapFromPairs(
+		[2]resp.Value{
+			resp.BulkString("flags"), simpleStrings(proto, "", flags...),
+		},
+		[2]resp.Value{
+			resp.BulkString("begin_search"),
+			protocolMap(resp.MakeMapFromPairs(
+				[2]resp.Value{resp.BulkString("type"), resp.BulkString("index")},
+				[2]resp.Value{
+					resp.BulkString("spec"),
+					protocolMap(resp.MakeMapFromPairs([2]resp.Value{
+						resp.BulkString("index"), resp.Integer(s.FirstKey),
+					}), proto),
+				},
+			), proto),
+		},
+		[2]resp.Value{
+			resp.BulkString("find_keys"),
+			protocolMap(resp.MakeMapFromPairs(
+				[2]resp.Value{resp.BulkString("type"), resp.BulkString("range")},
+				[2]resp.Value{
+					resp.BulkString("spec"),
+					protocolMap(resp.MakeMapFromPairs(
+						[2]resp.Value{
+							resp.BulkString("lastkey"), resp.Integer(last),
+						},
+						[2]resp.Value{
+							resp.BulkString("keystep"), resp.Integer(s.Step),
+						},
+						[2]resp.Value{
+							resp.BulkString("limit"), resp.Integer(0),
+						},
+					), proto),
+				},
+			), proto),
+		},
+	), proto))
+}
+
+// specDocs describes a command in the form that COMMAND DOCS replies with
+func specDocs(s *CommandSpec, proto int) resp.Value {
+	pairs := [][2]resp.Value{
+		{resp.BulkString("summary"), resp.BulkString(s.Summary)},
+		{resp.BulkString("group"), resp.BulkString(s.Group)},
+	}
+	if len(s.Args) != 0 {
+		pairs = append(pairs, [2]resp.Value{
+			resp.BulkString("arguments"), argDocs(s.Args, proto),
+		})
+	}
+	return protocolMap(resp.MakeMapFromPairs(pairs...), proto)
+}
+
+func argDocs(args []ArgSpec, proto int) *resp.Array {
+	res := make([]resp.Value, len(args))
+	for i, a := range args {
+		pairs := [][2]resp.Value{
+			{resp.BulkString("name"), resp.BulkString(a.Name)},
+			{resp.BulkString("type"), resp.BulkString(a.Type.String())},
+		}
+		if a.Token != "" {
+			pairs = append(pairs, [2]resp.Value{
+				resp.BulkString("token"), resp.BulkString(a.Token),
+			})
+		}
+		var flags []string
+		if a.Optional {
+			flags = append(flags, "optional")
+		}
+		if a.Multiple {
+			flags = append(flags, "multiple")
+		}
+		if len(flags) != 0 {
+			pairs = append(pairs, [2]resp.Value{
+				resp.BulkString("flags"), simpleStrings(proto, "", flags...),
+			})
+		}
+		if len(a.Args) != 0 {
+			pairs = append(pairs, [2]resp.Value{
+				resp.BulkString("arguments"), argDocs(a.Args, proto),
+			})
+		}
+		res[i] = protocolMap(resp.MakeMapFromPairs(pairs...), proto)
+	}
+	return resp.MakeArray(res...)
+}
+
+// simpleStrings creates a Set of SimpleStrings, each with the provided
+// prefix, or the Array that RESP2 clients expect in its place
+func simpleStrings(proto int, prefix string, s ...string) resp.Value {
+	res := make([]resp.Value, len(s))
+	for i, v := range s {
+		res[i] = resp.SimpleString(prefix + v)
+	}
+	if proto < RESP3 {
+		return resp.MakeArray(res...)
+	}
+	return resp.MakeSet(res...)
+}
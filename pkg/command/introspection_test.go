@@ -0,0 +1,171 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/pubsub"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type describedResponder struct {
+	*testResponder
+	specs []*command.CommandSpec
+}
+
+func (r describedResponder) CommandSpecs() []*command.CommandSpec {
+	return r.specs
+}
+
+func TestCommandInfo(t *testing.T) {
+	as := assert.New(t)
+	h := command.IntrospectionWrap(
+		command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+	)
+	r := newTestResponder()
+
+	as.Nil(h(r, makeCommand("COMMAND", "INFO", "get", "bogus")...))
+	res := (<-r.output).(*resp.Array)
+	as.Equal(2, res.Count())
+	as.Equal(resp.NullValue, res.Values[1])
+
+	get := res.Values[0].(*resp.Array).Values
+	as.Equal(resp.BulkString("get"), get[0])
+	as.Equal(resp.Integer(2), get[1])
+	as.Equal(resp.MakeArray(
+		resp.SimpleString("readonly"), resp.SimpleString("fast"),
+	), get[2])
+	as.Equal(resp.Integer(1), get[3])
+	as.Equal(resp.Integer(1), get[4])
+	as.Equal(resp.Integer(1), get[5])
+	as.Equal(resp.MakeArray(
+		resp.SimpleString("@string"), resp.SimpleString("@read"),
+		resp.SimpleString("@fast"),
+	), get[6])
+	as.Equal(1, get[8].(*resp.Array).Count())
+
+	as.Nil(h(resp3Responder{r}, makeCommand("COMMAND", "INFO", "sinter")...))
+	sinter := (<-r.output).(*resp.Array).Values[0].(*resp.Array).Values
+	as.Equal(resp.Integer(-1), sinter[4])
+	as.True(resp.MakeSet(resp.SimpleString("readonly")).Equal(sinter[2]))
+	spec := sinter[8].(*resp.Array).Values[0].(*resp.Map)
+	find, _ := spec.Get(resp.BulkString("find_keys"))
+	keys, _ := find.(*resp.Map).Get(resp.BulkString("spec"))
+	last, _ := keys.(*resp.Map).Get(resp.BulkString("lastkey"))
+	as.Equal(resp.Integer(-1), last)
+
+	as.Nil(h(r, makeCommand("COMMAND")...))
+	all := (<-r.output).(*resp.Array)
+	as.Equal(len(command.StorageSpecs())+1, all.Count())
+}
+
+func TestCommandCount(t *testing.T) {
+	h := command.Introspection()
+	testCommands(t, h, [][2]any{
+		{[]string{"COMMAND", "COUNT"}, resp.Integer(1)},
+		{[]string{"COMMAND", "COUNT", "extra"},
+			resp.MakeError(command.ErrWrongArity, "command|count")},
+		{[]string{"COMMAND", "BOGUS"},
+			resp.MakeError(command.ErrUnknownSubcommand, "BOGUS")},
+	})
+}
+
+func TestCommandGetKeys(t *testing.T) {
+	h := command.IntrospectionWrap(command.PubSubWrap(
+		pubsub.NewBroker(), command.Storage(storage.NewMemory()),
+	), append(command.StorageSpecs(), command.PubSubSpecs()...)...)
+	testCommands(t, h, [][2]any{
+		{[]string{"COMMAND", "GETKEYS", "SET", "key", "value", "EX", "10"},
+			bulkStrings("key")},
+		{[]string{"COMMAND", "GETKEYS", "sdiffstore", "dst", "a", "b"},
+			bulkStrings("dst", "a", "b")},
+		{[]string{"COMMAND", "GETKEYS", "PING"},
+			resp.MakeError(command.ErrNoKeyArgs)},
+		{[]string{"COMMAND", "GETKEYS", "BOGUS", "key"},
+			resp.MakeError(command.ErrInvalidCommand)},
+		{[]string{"COMMAND", "GETKEYS", "GET"},
+			resp.MakeError(command.ErrInvalidArgs)},
+		{[]string{"COMMAND", "GETKEYS"},
+			resp.MakeError(command.ErrWrongArity, "command|getkeys")},
+	})
+}
+
+func TestCommandDocs(t *testing.T) {
+	as := assert.New(t)
+	h := command.IntrospectionWrap(
+		command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+	)
+	r := newTestResponder()
+
+	as.Nil(h(r, makeCommand("COMMAND", "DOCS", "get", "bogus")...))
+	docs := (<-r.output).(*resp.Array)
+	as.Equal(2, docs.Count())
+	as.Equal(resp.BulkString("get"), docs.Values[0])
+	as.Equal(6, docs.Values[1].(*resp.Array).Count())
+
+	as.Nil(h(resp3Responder{r}, makeCommand("COMMAND", "DOCS", "set")...))
+	set, _ := (<-r.output).(*resp.Map).Get(resp.BulkString("set"))
+	args, _ := set.(*resp.Map).Get(resp.BulkString("arguments"))
+	as.Equal(5, args.(*resp.Array).Count())
+	exp := args.(*resp.Array).Values[4].(*resp.Map)
+	get := func(m *resp.Map, key string) resp.Value {
+		v, _ := m.Get(resp.BulkString(key))
+		return v
+	}
+	as.Equal(resp.BulkString("oneof"), get(exp, "type"))
+	as.True(resp.MakeSet(resp.SimpleString("optional")).Equal(
+		get(exp, "flags"),
+	))
+	ex := get(exp, "arguments").(*resp.Array).Values[0].(*resp.Map)
+	as.Equal(resp.BulkString("EX"), get(ex, "token"))
+	as.Equal(resp.BulkString("integer"), get(ex, "type"))
+}
+
+func TestCommandRegisteredHandlers(t *testing.T) {
+	as := assert.New(t)
+	custom := &command.CommandSpec{Name: "customverb", Arity: -1}
+	h := command.IntrospectionWrap(command.NewHandler(command.Handlers{
+		"CustomVerb": func(r command.Responder, _ ...resp.Value) error {
+			return command.Emit(r, resp.OK)
+		},
+	}, custom), custom)
+	r := newTestResponder()
+
+	as.Nil(h(r, makeCommand("COMMAND", "INFO", "customverb")...))
+	info := (<-r.output).(*resp.Array).Values[0].(*resp.Array).Values
+	as.Equal(resp.BulkString("customverb"), info[0])
+	as.Equal(resp.Integer(-1), info[1])
+	as.Equal(resp.Integer(0), info[3])
+}
+
+func TestCommandComposedHandlers(t *testing.T) {
+	as := assert.New(t)
+	h := command.IntrospectionWrap(
+		command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+	)
+	r := newTestResponder()
+
+	as.Nil(h(r, makeCommand(
+		"COMMAND", "INFO", "get", "subscribe", "auth", "multi", "hello",
+	)...))
+	info := (<-r.output).(*resp.Array).Values
+	as.Equal(resp.BulkString("get"), info[0].(*resp.Array).Values[0])
+	for _, v := range info[1:] {
+		as.Equal(resp.NullValue, v)
+	}
+
+	// The commands of the Handlers that wrap Introspection are described
+	// by the Responder
+	outer := append(command.ACLSpecs(), command.TransactionSpecs()...)
+	d := describedResponder{r, outer}
+	as.Nil(h(d, makeCommand("COMMAND", "COUNT")...))
+	as.Equal(
+		resp.Integer(len(command.StorageSpecs())+1+len(outer)), <-r.output,
+	)
+	as.Nil(h(d, makeCommand("COMMAND", "INFO", "auth", "multi")...))
+	info = (<-r.output).(*resp.Array).Values
+	as.Equal(resp.BulkString("auth"), info[0].(*resp.Array).Values[0])
+	as.Equal(resp.BulkString("multi"), info[1].(*resp.Array).Values[0])
+}
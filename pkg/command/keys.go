@@ -0,0 +1,124 @@
+package command
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/kode4food/respect/pkg/glob"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+type scanOptions struct {
+	match    string
+	keyType  string
+	count    int
+	noValues bool
+}
+
+// Error messages
+const (
+	ErrInvalidCursor = "ERR invalid cursor"
+)
+
+// typeNone is reported by TYPE for keys that don't exist
+const typeNone = "none"
+
+const defaultScanCount = 10
+
+func keysOp(s storage.Storage, a *Args) (resp.Value, error) {
+	pattern := string(a.String("pattern"))
+	res := []resp.Value{}
+	err := s.IterateKeys(storage.EmptyKey, func(k storage.Key) error {
+		if glob.Match(pattern, k.String()) {
+			res = append(res, k.ToValue())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.MakeArray(res...), nil
+}
+
+func scanOp(s storage.Storage, a *Args) (resp.Value, error) {
+	cursor, err := asCursor(a.Value("cursor"))
+	if err != nil {
+		return nil, err
+	}
+	opts, err := scanOptionsOf(a)
+	if err != nil {
+		return nil, err
+	}
+	res := []resp.Value{}
+	next, err := s.ScanKeys(storage.EmptyKey, cursor, opts.count,
+		func(k storage.Key) error {
+			if opts.match != "" && !glob.Match(opts.match, k.String()) {
+				return nil
+			}
+			if opts.keyType != "" {
+				t, err := keyType(s, k)
+				if err != nil || t != opts.keyType {
+					return err
+				}
+			}
+			res = append(res, k.ToValue())
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp.MakeArray(
+		resp.BulkString(strconv.FormatUint(next, 10)),
+		resp.MakeArray(res...),
+	), nil
+}
+
+// scanOptionsOf gathers the MATCH and COUNT options shared by the SCAN
+// family of commands, as well as any command-specific options
+func scanOptionsOf(a *Args) (*scanOptions, error) {
+	res := &scanOptions{
+		match:    string(a.String("pattern")),
+		keyType:  string(a.String("type")),
+		count:    defaultScanCount,
+		noValues: a.Has("novalues"),
+	}
+	if a.Has("count") {
+		c := a.Int("count")
+		if c < 1 {
+			return nil, resp.MakeError(ErrSyntax)
+		}
+		res.count = int(c)
+	}
+	return res, nil
+}
+
+func asCursor(v resp.Value) (uint64, error) {
+	if s, ok := v.(resp.BulkString); ok {
+		if c, err := strconv.ParseUint(string(s), 10, 64); err == nil {
+			return c, nil
+		}
+	}
+	return 0, resp.MakeError(ErrInvalidCursor)
+}
+
+func typeOp(s storage.Storage, a *Args) (resp.Value, error) {
+	t, err := keyType(s, a.Key("key"))
+	if err != nil {
+		return nil, err
+	}
+	return resp.SimpleString(t), nil
+}
+
+func keyType(s storage.Storage, key storage.Key) (string, error) {
+	k, err := s.Kind(key)
+	switch {
+	case errors.Is(err, storage.KeyNotFound):
+		return typeNone, nil
+	case err != nil:
+		return "", err
+	default:
+		return k.String(), nil
+	}
+}
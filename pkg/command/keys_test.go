@@ -0,0 +1,89 @@
+package command_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeys(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	r := newTestResponder()
+
+	for _, k := range []string{"user:1", "user:2", "users", "other"} {
+		as.Nil(h(r, makeCommand("SET", k, "value")...))
+		<-r.output
+	}
+
+	as.Nil(h(r, makeCommand("KEYS", "user:*")...))
+	res := (<-r.output).(*resp.Array)
+	as.ElementsMatch(
+		[]resp.Value{resp.BulkString("user:1"), resp.BulkString("user:2")},
+		res.Elements(),
+	)
+
+	as.Nil(h(r, makeCommand("KEYS", "*")...))
+	as.Equal(4, (<-r.output).(*resp.Array).Count())
+
+	testCommands(t, h, [][2]any{
+		{[]string{"TYPE", "users"}, resp.SimpleString("string")},
+		{[]string{"TYPE", "missing"}, resp.SimpleString("none")},
+		{[]string{"KEYS", "nothing*"}, resp.EmptyArray},
+	})
+}
+
+func TestScan(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	r := newTestResponder()
+
+	for i := 0; i < 100; i++ {
+		as.Nil(h(r, makeCommand("SET", fmt.Sprintf("key:%d", i), "v")...))
+		<-r.output
+	}
+	as.Nil(h(r, makeCommand("SET", "other", "v")...))
+	<-r.output
+
+	seen := map[resp.Value]int{}
+	cursor := "0"
+	for {
+		as.Nil(h(r, makeCommand(
+			"SCAN", cursor, "MATCH", "key:*", "COUNT", "7",
+		)...))
+		res := (<-r.output).(*resp.Array).Elements()
+		for _, k := range res[1].(*resp.Array).Elements() {
+			seen[k]++
+		}
+		cursor = string(res[0].(resp.BulkString))
+		if cursor == "0" {
+			break
+		}
+	}
+	as.Equal(100, len(seen))
+	for k, c := range seen {
+		as.Equal(1, c, k)
+	}
+
+	testCommands(t, h, [][2]any{
+		{[]string{"SCAN", "abc"}, resp.MakeError(command.ErrInvalidCursor)},
+		{[]string{"SCAN", "0", "COUNT"}, resp.MakeError(command.ErrSyntax)},
+		{[]string{"SCAN", "0", "COUNT", "0"}, resp.MakeError(command.ErrSyntax)},
+		{[]string{"SCAN", "0", "BOGUS", "1"}, resp.MakeError(command.ErrSyntax)},
+		{
+			[]string{"SCAN", "0", "MATCH", "none*", "COUNT", "1000"},
+			resp.MakeArray(resp.BulkString("0"), resp.EmptyArray),
+		},
+		{
+			[]string{"SCAN", "0", "MATCH", "o*", "TYPE", "string", "COUNT", "1000"},
+			resp.MakeArray(
+				resp.BulkString("0"),
+				resp.MakeArray(resp.BulkString("other")),
+			),
+		},
+	})
+}
@@ -0,0 +1,113 @@
+package command
+
+import (
+	"errors"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+// Error messages
+const (
+	ErrNotPositive = "ERR value is out of range, must be positive"
+)
+
+func pushOp(push func(*storage.List, ...resp.Value)) argsOp {
+	return func(s storage.Storage, a *Args) (resp.Value, error) {
+		key := a.Key("key")
+		var res int
+		err := storage.MutateList(s, key, true, func(l *storage.List) error {
+			push(l, a.Values("element")...)
+			res = l.Len()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Integer(res), nil
+	}
+}
+
+func popOp(pop func(*storage.List, int) resp.Values) argsOp {
+	return func(s storage.Storage, a *Args) (resp.Value, error) {
+		key := a.Key("key")
+		count := 1
+		if a.Has("count") {
+			c := a.Int("count")
+			if c < 0 {
+				return nil, resp.MakeError(ErrNotPositive)
+			}
+			count = int(c)
+		}
+		var res resp.Values
+		err := storage.MutateList(s, key, false, func(l *storage.List) error {
+			res = pop(l, count)
+			return nil
+		})
+		switch {
+		case errors.Is(err, storage.KeyNotFound):
+			return resp.NullValue, nil
+		case err != nil:
+			return nil, err
+		case a.Has("count"):
+			return resp.MakeArray(res...), nil
+		default:
+			return res[0], nil
+		}
+	}
+}
+
+func listLenOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	var res int
+	err := storage.InspectList(s, key, func(l *storage.List) error {
+		res = l.Len()
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(res), nil
+}
+
+func listIndexOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key, idx := a.Key("key"), int(a.Int("index"))
+	var res resp.Value = resp.NullValue
+	err := storage.InspectList(s, key, func(l *storage.List) error {
+		if v, ok := l.Index(idx); ok {
+			res = v
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func listRangeOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	start, stop := int(a.Int("start")), int(a.Int("stop"))
+	res := resp.EmptyArray
+	err := storage.InspectList(s, key, func(l *storage.List) error {
+		res = resp.MakeArray(l.Range(start, stop)...)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func listTrimOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	start, stop := int(a.Int("start")), int(a.Int("stop"))
+	err := storage.MutateList(s, key, false, func(l *storage.List) error {
+		l.Trim(start, stop)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.OK, nil
+}
@@ -0,0 +1,55 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+func bulkStrings(s ...string) *resp.Array {
+	return resp.MakeArray(makeCommand(s...)...)
+}
+
+func TestList(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"LLEN", "list"}, resp.Integer(0)},
+		{[]string{"LPOP", "list"}, resp.NullValue},
+		{[]string{"LRANGE", "list", "0", "-1"}, resp.EmptyArray},
+		{[]string{"RPUSH", "list", "c", "d", "e"}, resp.Integer(3)},
+		{[]string{"LPUSH", "list", "b", "a"}, resp.Integer(5)},
+		{[]string{"LRANGE", "list", "0", "-1"}, bulkStrings("a", "b", "c", "d", "e")},
+		{[]string{"LRANGE", "list", "-2", "10"}, bulkStrings("d", "e")},
+		{[]string{"LINDEX", "list", "1"}, resp.BulkString("b")},
+		{[]string{"LINDEX", "list", "-1"}, resp.BulkString("e")},
+		{[]string{"LINDEX", "list", "5"}, resp.NullValue},
+		{[]string{"LPOP", "list"}, resp.BulkString("a")},
+		{[]string{"RPOP", "list", "2"}, bulkStrings("e", "d")},
+		{[]string{"LLEN", "list"}, resp.Integer(2)},
+		{[]string{"TYPE", "list"}, resp.SimpleString("list")},
+		{[]string{"RPUSH", "list", "x", "y", "z"}, resp.Integer(5)},
+		{[]string{"LTRIM", "list", "1", "-2"}, resp.OK},
+		{[]string{"LRANGE", "list", "0", "-1"}, bulkStrings("c", "x", "y")},
+		{[]string{"LTRIM", "list", "5", "10"}, resp.OK},
+		{[]string{"TYPE", "list"}, resp.SimpleString("none")},
+		{[]string{"LPOP", "list", "-1"}, resp.MakeError(command.ErrNotPositive)},
+		{[]string{"LINDEX", "list", "one"}, resp.MakeError(command.ErrNotInteger)},
+	})
+}
+
+func TestListWrongType(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"SET", "str", "value"}, resp.OK},
+		{[]string{"LPUSH", "str", "a"}, storage.WrongType},
+		{[]string{"LRANGE", "str", "0", "-1"}, storage.WrongType},
+		{[]string{"RPUSH", "list", "a"}, resp.Integer(1)},
+		{[]string{"GET", "list"}, storage.WrongType},
+		{[]string{"SET", "list", "value", "GET"}, storage.WrongType},
+		{[]string{"TYPE", "list"}, resp.SimpleString("list")},
+		{[]string{"SET", "list", "value"}, resp.OK},
+		{[]string{"GET", "list"}, resp.BulkString("value")},
+	})
+}
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// Middleware wraps a Handler with behavior that applies to every command
+// that passes through it. A Middleware sees the Responder and the arguments
+// of each command, including its verb, and the error returned by the Handler
+// that it wraps
+type Middleware func(Handler) Handler
+
+// Error messages
+const (
+	ErrPanic = "ERR panic processing %s: %v"
+)
+
+// Use wraps a Handler with the provided Middleware. The first Middleware is
+// outermost, so it sees each command first and its resulting error last
+func Use(h Handler, m ...Middleware) Handler {
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](h)
+	}
+	return h
+}
+
+// Chain combines Middleware into a single Middleware that applies them in
+// the order provided
+func Chain(m ...Middleware) Middleware {
+	return func(h Handler) Handler {
+		return Use(h, m...)
+	}
+}
+
+// Verb returns the normalized verb of a command's arguments, or an empty
+// string if it has none
+func Verb(args ...resp.Value) resp.BulkString {
+	if len(args) == 0 {
+		return ""
+	}
+	if v, ok := args[0].(resp.BulkString); ok {
+		return normalizeVerb(v)
+	}
+	return ""
+}
+
+// Recover is Middleware that converts a panic raised while processing a
+// command into an error reply, so that it doesn't take the server down
+func Recover(next Handler) Handler {
+	return func(r Responder, args ...resp.Value) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = resp.MakeError(ErrPanic, Verb(args...), rec)
+			}
+		}()
+		return next(r, args...)
+	}
+}
+
+// Logger creates Middleware that logs every command to the provided
+// slog.Logger. Commands that succeed are logged at the Debug level, and
+// those that fail are logged at the Warn level along with their error
+func Logger(l *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(r Responder, args ...resp.Value) error {
+			start := time.Now()
+			err := next(r, args...)
+			level := slog.LevelDebug
+			if err != nil {
+				level = slog.LevelWarn
+			}
+			ctx := context.Background()
+			if !l.Enabled(ctx, level) {
+				return err
+			}
+			attrs := []slog.Attr{
+				slog.String("verb", string(Verb(args...))),
+				slog.Int("args", argCount(args)),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if ss := SessionOf(r); ss != nil {
+				attrs = append(attrs, slog.String("user", ss.User()))
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			l.LogAttrs(ctx, level, "command", attrs...)
+			return err
+		}
+	}
+}
+
+func argCount(args []resp.Value) int {
+	if len(args) == 0 {
+		return 0
+	}
+	return len(args) - 1
+}
@@ -0,0 +1,107 @@
+package command_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	as := assert.New(t)
+	var calls []string
+	trace := func(name string) command.Middleware {
+		return func(next command.Handler) command.Handler {
+			return func(r command.Responder, args ...resp.Value) error {
+				calls = append(calls, name+">"+string(command.Verb(args...)))
+				err := next(r, args...)
+				calls = append(calls, name+"<")
+				return err
+			}
+		}
+	}
+
+	h := command.Use(
+		command.Storage(storage.NewMemory()),
+		trace("a"), command.Chain(trace("b"), trace("c")),
+	)
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand("set", "key", "value")...))
+	as.Equal(resp.OK, <-r.output)
+	as.Equal([]string{"a>SET", "b>SET", "c>SET", "c<", "b<", "a<"}, calls)
+
+	as.Equal(resp.BulkString(""), command.Verb())
+	as.Equal(resp.BulkString(""), command.Verb(resp.Integer(1)))
+}
+
+func TestMiddlewareError(t *testing.T) {
+	as := assert.New(t)
+	var seen error
+	capture := func(next command.Handler) command.Handler {
+		return func(r command.Responder, args ...resp.Value) error {
+			seen = next(r, args...)
+			return seen
+		}
+	}
+
+	h := command.Use(command.Storage(storage.NewMemory()), capture)
+	err := h(newTestResponder(), makeCommand("BOGUS")...)
+	as.NotNil(err)
+	as.Equal(err, seen)
+}
+
+func TestRecover(t *testing.T) {
+	as := assert.New(t)
+	h := command.Use(command.NewHandler(command.Handlers{
+		"BOOM": func(command.Responder, ...resp.Value) error {
+			panic("kaboom")
+		},
+	}), command.Recover)
+
+	err := h(newTestResponder(), makeCommand("boom")...)
+	as.Equal(resp.MakeError(command.ErrPanic, "BOOM", "kaboom"), err)
+}
+
+func TestLogger(t *testing.T) {
+	as := assert.New(t)
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+	h := command.Use(command.NewHandler(command.Handlers{
+		"OK": func(r command.Responder, _ ...resp.Value) error {
+			return command.Emit(r, resp.OK)
+		},
+		"FAIL": func(command.Responder, ...resp.Value) error {
+			return errors.New("failed")
+		},
+	}), command.Logger(l))
+
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand("ok", "arg")...))
+	as.Equal(resp.OK, <-r.output)
+	as.NotNil(h(r, makeCommand("fail")...))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	as.Equal(2, len(lines))
+	as.Contains(lines[0], "level=DEBUG")
+	as.Contains(lines[0], "verb=OK args=1")
+	as.Contains(lines[0], "user=default")
+	as.Contains(lines[1], "level=WARN")
+	as.Contains(lines[1], "verb=FAIL args=0")
+	as.Contains(lines[1], "failed")
+
+	var info bytes.Buffer
+	quiet := command.Use(h, command.Logger(slog.New(
+		slog.NewTextHandler(&info, nil),
+	)))
+	as.Nil(quiet(r, makeCommand("ok")...))
+	<-r.output
+	as.Equal(0, info.Len())
+}
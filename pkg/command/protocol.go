@@ -0,0 +1,41 @@
+package command
+
+import "github.com/kode4food/respect/pkg/resp"
+
+// Versioned is implemented by Responders that know which version of the RESP
+// protocol their client speaks
+type Versioned interface {
+	Protocol() int
+}
+
+// RESP protocol versions
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
+// ProtocolOf returns the RESP protocol version spoken by a Responder's
+// client. Clients are assumed to speak RESP2 unless they've said otherwise
+func ProtocolOf(r Responder) int {
+	if v, ok := r.(Versioned); ok {
+		return v.Protocol()
+	}
+	return RESP2
+}
+
+// flattenMap converts a Map into the Array of alternating keys and values
+// that RESP2 clients expect in its place
+func flattenMap(m resp.Mapped) *resp.Array {
+	res := make([]resp.Value, 0, m.Count()*2)
+	_ = m.ForEach(func(k, v resp.Value) error {
+		res = append(res, k, v)
+		return nil
+	})
+	return resp.MakeArray(res...)
+}
+
+// flattenSet converts a Set into the Array that RESP2 clients expect in its
+// place
+func flattenSet(s *resp.Set) *resp.Array {
+	return resp.MakeArray(s.Elements()...)
+}
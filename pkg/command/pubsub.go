@@ -0,0 +1,366 @@
+package command
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kode4food/respect/pkg/pubsub"
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// subscriber is the part of a Session that tracks its pub/sub subscriptions,
+// delivering messages to the Responder of its client. Messages are queued
+// and then written by the subscriber's own goroutine, so that a client that
+// is slow to read them doesn't hold up the clients that publish them
+type subscriber struct {
+	Responder
+	channels map[resp.BulkString]struct{}
+	patterns map[resp.BulkString]struct{}
+	pending  chan resp.Value
+	overflow sync.Once
+}
+
+// Error messages
+const (
+	ErrSubscribedMode = "ERR Can't execute '%s': only (P|S)SUBSCRIBE / " +
+		"(P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context"
+)
+
+// maxPendingMessages is the number of messages that can be queued for a
+// subscriber before it's considered to have fallen too far behind. Further
+// messages are dropped, and its client is disconnected
+const maxPendingMessages = 1024
+
+// Pub/Sub reply kinds
+const (
+	kindSubscribe    = resp.BulkString("subscribe")
+	kindUnsubscribe  = resp.BulkString("unsubscribe")
+	kindPSubscribe   = resp.BulkString("psubscribe")
+	kindPUnsubscribe = resp.BulkString("punsubscribe")
+	kindMessage      = resp.BulkString("message")
+	kindPMessage     = resp.BulkString("pmessage")
+	kindPong         = resp.BulkString("pong")
+)
+
+// subscribedVerbs are the only commands that RESP2 clients can issue while
+// they have subscriptions
+var subscribedVerbs = map[resp.BulkString]bool{
+	"SUBSCRIBE":    true,
+	"SSUBSCRIBE":   true,
+	"PSUBSCRIBE":   true,
+	"UNSUBSCRIBE":  true,
+	"SUNSUBSCRIBE": true,
+	"PUNSUBSCRIBE": true,
+	"PING":         true,
+	"QUIT":         true,
+	"RESET":        true,
+}
+
+// compile-time checks for interface implementation
+var _ pubsub.Subscriber = (*subscriber)(nil)
+
+func PubSub(b *pubsub.Broker) Handler {
+	return PubSubWrap(b, NoHandler)
+}
+
+func PubSubWrap(b *pubsub.Broker, next Handler) Handler {
+	h := Wrap(Handlers{
+		"SUBSCRIBE":    subscribeOp(b, kindSubscribe, false),
+		"UNSUBSCRIBE":  unsubscribeOp(b, kindUnsubscribe, false),
+		"PSUBSCRIBE":   subscribeOp(b, kindPSubscribe, true),
+		"PUNSUBSCRIBE": unsubscribeOp(b, kindPUnsubscribe, true),
+		"PUBLISH":      publishOp(b),
+		"PUBSUB":       pubSubOp(b),
+		"PING":         wrapArgsHandler(pingSpec, pingOp),
+	}, next, pubSubSpecs...)
+	return func(r Responder, args ...resp.Value) error {
+		if err := checkSubscribedMode(r, args); err != nil {
+			return err
+		}
+		return h(r, args...)
+	}
+}
+
+func subscribeOp(
+	b *pubsub.Broker, kind resp.BulkString, pattern bool,
+) Handler {
+	spec, add := subscribeSpec, b.Subscribe
+	if pattern {
+		spec, add = psubscribeSpec, b.PSubscribe
+	}
+	return wrapArgsHandler(spec, func(r Responder, a *Args) error {
+		names, err := asBulkStrings(a.Values(argName(pattern)))
+		if err != nil {
+			return err
+		}
+		sub, err := subscriberOf(r, b)
+		if err != nil {
+			return err
+		}
+		// A subscription is confirmed before it's added to the Broker, as
+		// its messages are delivered by the subscriber's own goroutine and
+		// would otherwise race ahead of the confirmation
+		subs := sub.names(pattern)
+		for _, name := range names {
+			_, ok := subs[name]
+			subs[name] = struct{}{}
+			if err := sub.confirm(r, kind, name); err != nil {
+				return err
+			}
+			if !ok {
+				add(sub, name)
+			}
+		}
+		return nil
+	})
+}
+
+func unsubscribeOp(
+	b *pubsub.Broker, kind resp.BulkString, pattern bool,
+) Handler {
+	spec, remove := unsubscribeSpec, b.Unsubscribe
+	if pattern {
+		spec, remove = punsubscribeSpec, b.PUnsubscribe
+	}
+	return wrapArgsHandler(spec, func(r Responder, a *Args) error {
+		names, err := asBulkStrings(a.Values(argName(pattern)))
+		if err != nil {
+			return err
+		}
+		sub, err := subscriberOf(r, b)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			names = sortedNames(sub.names(pattern))
+		}
+		if len(names) == 0 {
+			return sub.confirm(r, kind, resp.NullValue)
+		}
+		for _, name := range names {
+			if remove(sub, name) {
+				delete(sub.names(pattern), name)
+			}
+			if err := sub.confirm(r, kind, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func publishOp(b *pubsub.Broker) Handler {
+	return wrapArgsHandler(publishSpec, func(r Responder, a *Args) error {
+		names, err := asBulkStrings(
+			[]resp.Value{a.Value("channel"), a.Value("message")},
+		)
+		if err != nil {
+			return err
+		}
+		return Emit(r, resp.Integer(b.Publish(names[0], names[1])))
+	})
+}
+
+func pubSubOp(b *pubsub.Broker) Handler {
+	return wrapArgsHandler(pubSubSpec, func(r Responder, a *Args) error {
+		sub, ok := a.Value("subcommand").(resp.BulkString)
+		if !ok {
+			return resp.MakeError(ErrSyntax)
+		}
+		rest := a.Values("arg")
+		switch normalizeVerb(sub) {
+		case "CHANNELS":
+			sa, err := pubSubChannelsSpec.parseSubcommand(rest)
+			if err != nil {
+				return err
+			}
+			pattern := "*"
+			if sa.Has("pattern") {
+				pattern = string(sa.String("pattern"))
+			}
+			channels := b.Channels(pattern)
+			res := make([]resp.Value, len(channels))
+			for i, c := range channels {
+				res[i] = c
+			}
+			return Emit(r, resp.MakeArray(res...))
+		case "NUMSUB":
+			sa, err := pubSubNumSubSpec.parseSubcommand(rest)
+			if err != nil {
+				return err
+			}
+			names, err := asBulkStrings(sa.Values("channel"))
+			if err != nil {
+				return err
+			}
+			res := make([]resp.Value, 0, len(names)*2)
+			for _, c := range names {
+				res = append(res, c, resp.Integer(b.NumSub(c)))
+			}
+			return Emit(r, resp.MakeArray(res...))
+		case "NUMPAT":
+			if _, err := pubSubNumPatSpec.parseSubcommand(rest); err != nil {
+				return err
+			}
+			return Emit(r, resp.Integer(b.NumPat()))
+		default:
+			return resp.MakeError(ErrUnknownSubcommand, sub)
+		}
+	})
+}
+
+func pingOp(r Responder, a *Args) error {
+	if ss := SessionOf(r); ss != nil && ss.sub.subscribed() &&
+		ProtocolOf(r) == RESP2 {
+		var msg resp.Value = resp.BulkString("")
+		if a.Has("message") {
+			msg = a.Value("message")
+		}
+		return Emit(r, resp.MakeArray(kindPong, msg))
+	}
+	if a.Has("message") {
+		return Emit(r, a.Value("message"))
+	}
+	return Emit(r, resp.SimpleString("PONG"))
+}
+
+// argName is the name of the argument that holds the channels or the
+// patterns of a subscription
+func argName(pattern bool) string {
+	if pattern {
+		return "pattern"
+	}
+	return "channel"
+}
+
+// checkSubscribedMode rejects the commands that RESP2 clients can't issue
+// while they have subscriptions, as their replies would be indistinguishable
+// from the messages being delivered
+func checkSubscribedMode(r Responder, args []resp.Value) error {
+	if len(args) == 0 || ProtocolOf(r) != RESP2 {
+		return nil
+	}
+	ss := SessionOf(r)
+	if ss == nil || !ss.sub.subscribed() {
+		return nil
+	}
+	verb, ok := args[0].(resp.BulkString)
+	if !ok || subscribedVerbs[normalizeVerb(verb)] {
+		return nil
+	}
+	return resp.MakeError(ErrSubscribedMode, strings.ToLower(string(verb)))
+}
+
+// subscriberOf returns the subscriber of a Responder's Session, creating it
+// if necessary. A new subscriber is removed from the Broker once its
+// Responder is closed. Messages are never delivered to the Responder that
+// EXEC replays a command with, but to the one that it captures for
+func subscriberOf(r Responder, b *pubsub.Broker) (*subscriber, error) {
+	ss, err := sessionOf(r)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := r.(*capture); ok {
+		r = c.Responder
+	}
+	if ss.sub == nil {
+		sub := &subscriber{
+			Responder: r,
+			channels:  map[resp.BulkString]struct{}{},
+			patterns:  map[resp.BulkString]struct{}{},
+			pending:   make(chan resp.Value, maxPendingMessages),
+		}
+		go sub.deliverLoop(b)
+		ss.sub = sub
+	}
+	return ss.sub, nil
+}
+
+// Message implements pubsub.Subscriber
+func (s *subscriber) Message(channel, payload resp.BulkString) {
+	s.enqueue(s.frame(kindMessage, channel, payload))
+}
+
+// PMessage implements pubsub.Subscriber
+func (s *subscriber) PMessage(pattern, channel, payload resp.BulkString) {
+	s.enqueue(s.frame(kindPMessage, pattern, channel, payload))
+}
+
+// enqueue queues a message without blocking. If the queue is full, the
+// message is dropped and the client is disconnected, if its Responder can
+// be closed
+func (s *subscriber) enqueue(v resp.Value) {
+	select {
+	case s.pending <- v:
+	default:
+		s.overflow.Do(func() {
+			if c, ok := s.Responder.(io.Closer); ok {
+				_ = c.Close()
+			}
+		})
+	}
+}
+
+// deliverLoop writes queued messages to the subscriber's Responder until
+// it's closed, at which point the subscriber is removed from the Broker
+func (s *subscriber) deliverLoop(b *pubsub.Broker) {
+	defer b.UnsubscribeAll(s)
+	for {
+		select {
+		case <-s.Closed():
+			return
+		case v := <-s.pending:
+			if err := Emit(s.Responder, v); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// confirm emits the reply to a subscription change, which includes the
+// number of subscriptions that remain. It's emitted to the Responder of the
+// command, which differs from the subscriber's when EXEC is replaying it
+func (s *subscriber) confirm(
+	r Responder, kind resp.BulkString, name resp.Value,
+) error {
+	return Emit(r, frame(r, kind, name, resp.Integer(s.count())))
+}
+
+// frame constructs a Push for RESP3 clients, or the Array that RESP2
+// clients expect in its place
+func (s *subscriber) frame(v ...resp.Value) resp.Value {
+	return frame(s.Responder, v...)
+}
+
+func frame(r Responder, v ...resp.Value) resp.Value {
+	if ProtocolOf(r) == RESP3 {
+		return resp.MakePush(v...)
+	}
+	return resp.MakeArray(v...)
+}
+
+func (s *subscriber) names(pattern bool) map[resp.BulkString]struct{} {
+	if pattern {
+		return s.patterns
+	}
+	return s.channels
+}
+
+func (s *subscriber) count() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+func (s *subscriber) subscribed() bool {
+	return s != nil && s.count() > 0
+}
+
+func sortedNames(names map[resp.BulkString]struct{}) []resp.BulkString {
+	res := make([]resp.BulkString, 0, len(names))
+	for n := range names {
+		res = append(res, n)
+	}
+	slices.Sort(res)
+	return res
+}
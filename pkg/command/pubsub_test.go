@@ -0,0 +1,196 @@
+package command_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/pubsub"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPubSub(t *testing.T) {
+	as := assert.New(t)
+	b := pubsub.NewBroker()
+	h := command.PubSubWrap(b, command.Storage(storage.NewMemory()))
+	sub := newTestResponder()
+	pub := newTestResponder()
+	run := func(r *testResponder, args ...string) resp.Value {
+		as.Nil(h(r, makeCommand(args...)...))
+		return <-r.output
+	}
+	frame := func(args ...any) resp.Value {
+		res := make([]resp.Value, len(args))
+		for i, a := range args {
+			switch a := a.(type) {
+			case string:
+				res[i] = resp.BulkString(a)
+			case int:
+				res[i] = resp.Integer(a)
+			}
+		}
+		return resp.MakeArray(res...)
+	}
+
+	as.Equal(resp.SimpleString("PONG"), run(sub, "PING"))
+	as.Nil(h(sub, makeCommand("SUBSCRIBE", "news", "sports")...))
+	as.Equal(frame("subscribe", "news", 1), <-sub.output)
+	as.Equal(frame("subscribe", "sports", 2), <-sub.output)
+	as.Equal(frame("psubscribe", "n*", 3), run(sub, "PSUBSCRIBE", "n*"))
+
+	as.Equal(resp.Integer(2), run(pub, "PUBLISH", "news", "hello"))
+	as.Equal(frame("message", "news", "hello"), <-sub.output)
+	as.Equal(frame("pmessage", "n*", "news", "hello"), <-sub.output)
+	as.Equal(resp.Integer(0), run(pub, "PUBLISH", "weather", "sunny"))
+
+	as.Equal(frame("pong", ""), run(sub, "PING"))
+	as.Equal(frame("pong", "hi"), run(sub, "PING", "hi"))
+	as.ErrorContains(
+		h(sub, makeCommand("GET", "key")...),
+		resp.MakeError(command.ErrSubscribedMode, "get").Error(),
+	)
+
+	as.Equal(
+		frame("news", 1, "missing", 0),
+		run(pub, "PUBSUB", "NUMSUB", "news", "missing"),
+	)
+	as.Equal(resp.Integer(1), run(pub, "PUBSUB", "numpat"))
+	as.Equal(frame("news", "sports"), run(pub, "PUBSUB", "CHANNELS"))
+	as.Equal(frame("sports"), run(pub, "PUBSUB", "CHANNELS", "s*"))
+	as.ErrorContains(
+		h(pub, makeCommand("PUBSUB", "NUMPAT", "extra")...),
+		resp.MakeError(command.ErrWrongArity, "pubsub|numpat").Error(),
+	)
+	as.ErrorContains(
+		h(pub, makeCommand("PUBSUB", "BOGUS")...),
+		resp.MakeError(command.ErrUnknownSubcommand, "BOGUS").Error(),
+	)
+
+	as.Nil(h(sub, makeCommand("UNSUBSCRIBE")...))
+	as.Equal(frame("unsubscribe", "news", 2), <-sub.output)
+	as.Equal(frame("unsubscribe", "sports", 1), <-sub.output)
+	as.Equal(frame("punsubscribe", "n*", 0), run(sub, "PUNSUBSCRIBE"))
+	as.Equal(
+		resp.MakeArray(resp.BulkString("unsubscribe"), resp.NullValue,
+			resp.Integer(0)),
+		run(sub, "UNSUBSCRIBE"),
+	)
+	as.Equal(resp.NullValue, run(sub, "GET", "key"))
+	as.Equal(resp.Integer(0), run(pub, "PUBLISH", "news", "hello"))
+}
+
+func TestPubSubResp3(t *testing.T) {
+	as := assert.New(t)
+	b := pubsub.NewBroker()
+	h := command.PubSubWrap(b, command.Storage(storage.NewMemory()))
+	r := newTestResponder()
+	r3 := resp3Responder{r}
+
+	as.Nil(h(r3, makeCommand("SUBSCRIBE", "news")...))
+	as.Equal(resp.MakePush(
+		resp.BulkString("subscribe"), resp.BulkString("news"), resp.Integer(1),
+	), <-r.output)
+
+	as.Equal(1, b.Publish("news", "hello"))
+	as.Equal(resp.MakePush(
+		resp.BulkString("message"), resp.BulkString("news"),
+		resp.BulkString("hello"),
+	), <-r.output)
+
+	as.Nil(h(r3, makeCommand("SET", "key", "value")...))
+	as.Equal(resp.OK, <-r.output)
+	as.Nil(h(r3, makeCommand("PING")...))
+	as.Equal(resp.SimpleString("PONG"), <-r.output)
+}
+
+func TestPubSubClosed(t *testing.T) {
+	as := assert.New(t)
+	b := pubsub.NewBroker()
+	h := command.PubSub(b)
+	r := newTestResponder()
+
+	as.Nil(h(r, makeCommand("SUBSCRIBE", "news")...))
+	<-r.output
+	as.Equal(1, b.NumSub("news"))
+
+	close(r.closed)
+	as.Eventually(func() bool {
+		return b.NumSub("news") == 0
+	}, time.Second, time.Millisecond)
+	as.Equal(0, b.Publish("news", "hello"))
+}
+
+func TestPubSubConfirmFirst(t *testing.T) {
+	as := assert.New(t)
+	b := pubsub.NewBroker()
+	h := command.PubSub(b)
+	r := newTestResponder()
+	r.output = make(chan resp.Value)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h(r, makeCommand("SUBSCRIBE", "news")...)
+	}()
+
+	// Nothing is delivered until the subscription has been confirmed
+	time.Sleep(10 * time.Millisecond)
+	as.Equal(0, b.Publish("news", "early"))
+	as.Equal(
+		resp.MakeArray(
+			resp.BulkString("subscribe"), resp.BulkString("news"),
+			resp.Integer(1),
+		),
+		<-r.output,
+	)
+	as.Nil(<-done)
+	as.Equal(1, b.Publish("news", "hello"))
+	as.Equal(
+		resp.MakeArray(
+			resp.BulkString("message"), resp.BulkString("news"),
+			resp.BulkString("hello"),
+		),
+		<-r.output,
+	)
+}
+
+type closableResponder struct {
+	*testResponder
+	close sync.Once
+}
+
+func (r *closableResponder) Close() error {
+	r.close.Do(func() { close(r.closed) })
+	return nil
+}
+
+func TestPubSubSlowSubscriber(t *testing.T) {
+	as := assert.New(t)
+	b := pubsub.NewBroker()
+	h := command.PubSub(b)
+	r := &closableResponder{testResponder: newTestResponder()}
+
+	as.Nil(h(r, makeCommand("SUBSCRIBE", "news")...))
+	<-r.output
+
+	// The subscriber never reads its messages, but publishing mustn't block
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 4096; i++ {
+			b.Publish("news", "hello")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		as.Fail("publishing blocked on a slow subscriber")
+	}
+
+	as.Eventually(func() bool {
+		return b.NumSub("news") == 0
+	}, time.Second, time.Millisecond)
+	as.True(command.IsClosed(r))
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// Session holds the state that a client's connection accumulates across
+	// commands
+	Session struct {
+		user          string
+		authenticated bool
+		tx            transaction
+		sub           *subscriber
+	}
+
+	// Sessioned is implemented by Responders that maintain a Session for
+	// their client
+	Sessioned interface {
+		Session() *Session
+	}
+)
+
+// Error messages
+const (
+	ErrNoSession = "ERR this command requires a session"
+)
+
+// DefaultUser is the user that a Session acts as until it authenticates
+const DefaultUser = acl.DefaultUser
+
+// NewSession creates a new Session for a client's connection
+func NewSession() *Session {
+	return &Session{
+		user: DefaultUser,
+	}
+}
+
+// User returns the name of the user that the Session acts as
+func (s *Session) User() string {
+	return s.user
+}
+
+// Authenticated reports whether the Session has explicitly authenticated as
+// its user
+func (s *Session) Authenticated() bool {
+	return s.authenticated
+}
+
+// InTransaction reports whether the Session has begun a transaction using
+// MULTI that it hasn't yet executed or discarded
+func (s *Session) InTransaction() bool {
+	return s.tx.active
+}
+
+// SetUser authenticates the Session as the named user
+func (s *Session) SetUser(user string) {
+	s.user = user
+	s.authenticated = true
+}
+
+// SessionOf returns the Session maintained by a Responder, or nil if it
+// doesn't maintain one
+func SessionOf(r Responder) *Session {
+	if s, ok := r.(Sessioned); ok {
+		return s.Session()
+	}
+	return nil
+}
+
+func sessionOf(r Responder) (*Session, error) {
+	if ss := SessionOf(r); ss != nil {
+		return ss, nil
+	}
+	return nil, resp.MakeError(ErrNoSession)
+}
@@ -0,0 +1,213 @@
+package command
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/kode4food/respect/pkg/glob"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+// setCombiner produces a new Set from the Sets stored at several Keys
+type setCombiner func(...*storage.Set) *storage.Set
+
+func setAddOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	members, err := asFields(a.Values("member"))
+	if err != nil {
+		return nil, err
+	}
+	added := 0
+	err = storage.MutateSet(s, key, true, func(set *storage.Set) error {
+		for _, m := range members {
+			if set.Add(m) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Integer(added), nil
+}
+
+func setRemoveOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	members, err := asFields(a.Values("member"))
+	if err != nil {
+		return nil, err
+	}
+	removed := 0
+	err = storage.MutateSet(s, key, false, func(set *storage.Set) error {
+		for _, m := range members {
+			if set.Remove(m) {
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(removed), nil
+}
+
+func setCardOp(s storage.Storage, a *Args) (resp.Value, error) {
+	res := 0
+	err := storage.InspectSet(s, a.Key("key"), func(set *storage.Set) error {
+		res = set.Len()
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(res), nil
+}
+
+func setIsMemberOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	member, err := asField(a.Value("member"))
+	if err != nil {
+		return nil, err
+	}
+	res := resp.Integer(0)
+	err = storage.InspectSet(s, key, func(set *storage.Set) error {
+		if set.Contains(member) {
+			res = 1
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func setMembersOp(
+	s storage.Storage, proto int, a *Args,
+) (resp.Value, error) {
+	res := resp.EmptySet
+	err := storage.InspectSet(s, a.Key("key"), func(set *storage.Set) error {
+		res = set.Snapshot().(*resp.Set)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	if proto < RESP3 {
+		return flattenSet(res), nil
+	}
+	return res, nil
+}
+
+// setCombineOp produces an op that replies with the combination of the Sets
+// stored at the Keys it is given. Missing Keys are treated as empty Sets
+func setCombineOp(combine setCombiner) protocolOp {
+	return func(
+		s storage.Storage, proto int, a *Args,
+	) (resp.Value, error) {
+		keys := a.Keys("key")
+		var res *resp.Set
+		err := s.Atomic(func(tx storage.Storage) error {
+			set, err := combineSets(tx, keys, combine)
+			if err != nil {
+				return err
+			}
+			res = set.Snapshot().(*resp.Set)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if proto < RESP3 {
+			return flattenSet(res), nil
+		}
+		return res, nil
+	}
+}
+
+// setStoreOp produces an op that replaces the value of its destination Key
+// with the combination of the Sets stored at the rest, replying with the
+// size of the result. An empty result removes the destination Key
+func setStoreOp(combine setCombiner) argsOp {
+	return func(s storage.Storage, a *Args) (resp.Value, error) {
+		dest := a.Key("destination")
+		keys := a.Keys("key")
+		res := 0
+		err := s.Atomic(func(tx storage.Storage) error {
+			set, err := combineSets(tx, keys, combine)
+			if err != nil {
+				return err
+			}
+			res = set.Len()
+			_, err = tx.Delete(dest)
+			if err != nil && !errors.Is(err, storage.KeyNotFound) {
+				return err
+			}
+			return tx.Mutate(dest, func(storage.Mutable) (
+				storage.Mutable, error,
+			) {
+				return set, nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Integer(res), nil
+	}
+}
+
+// combineSets must be called within an Atomic function, which guarantees
+// that the Sets it reads can't be modified until the function returns
+func combineSets(
+	s storage.Storage, keys []storage.Key, combine setCombiner,
+) (*storage.Set, error) {
+	sets := make([]*storage.Set, len(keys))
+	for i, k := range keys {
+		err := storage.InspectSet(s, k, func(set *storage.Set) error {
+			sets[i] = set
+			return nil
+		})
+		switch {
+		case errors.Is(err, storage.KeyNotFound):
+			sets[i] = storage.NewSet()
+		case err != nil:
+			return nil, err
+		}
+	}
+	return combine(sets...), nil
+}
+
+func setDifference(sets ...*storage.Set) *storage.Set {
+	return storage.Difference(sets[0], sets[1:]...)
+}
+
+func setScanOp(s storage.Storage, a *Args) (resp.Value, error) {
+	cursor, err := asCursor(a.Value("cursor"))
+	if err != nil {
+		return nil, err
+	}
+	opts, err := scanOptionsOf(a)
+	if err != nil {
+		return nil, err
+	}
+	var next uint64
+	res := []resp.Value{}
+	err = storage.InspectSet(s, a.Key("key"), func(set *storage.Set) error {
+		next = set.Scan(cursor, opts.count, func(m resp.BulkString) {
+			if opts.match == "" || glob.Match(opts.match, string(m)) {
+				res = append(res, m)
+			}
+		})
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.MakeArray(
+		resp.BulkString(strconv.FormatUint(next, 10)),
+		resp.MakeArray(res...),
+	), nil
+}
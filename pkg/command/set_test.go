@@ -0,0 +1,125 @@
+package command_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"SCARD", "set"}, resp.Integer(0)},
+		{[]string{"SMEMBERS", "set"}, resp.EmptyArray},
+		{[]string{"SADD", "set", "a", "b", "c"}, resp.Integer(3)},
+		{[]string{"SADD", "set", "c", "d"}, resp.Integer(1)},
+		{[]string{"SCARD", "set"}, resp.Integer(4)},
+		{[]string{"SISMEMBER", "set", "d"}, resp.Integer(1)},
+		{[]string{"SISMEMBER", "set", "e"}, resp.Integer(0)},
+		{[]string{"SREM", "set", "a", "e"}, resp.Integer(1)},
+		{[]string{"SADD", "set"}, resp.MakeError(command.ErrWrongArity, "sadd")},
+		{[]string{"TYPE", "set"}, resp.SimpleString("set")},
+		{[]string{"LPUSH", "set", "a"}, storage.WrongType},
+		{[]string{"SREM", "set", "b", "c", "d"}, resp.Integer(3)},
+		{[]string{"TYPE", "set"}, resp.SimpleString("none")},
+		{[]string{"SREM", "set", "a"}, resp.Integer(0)},
+	})
+}
+
+func TestSetCombine(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	r := newTestResponder()
+	testCommands(t, h, [][2]any{
+		{[]string{"SADD", "a", "1", "2", "3", "4"}, resp.Integer(4)},
+		{[]string{"SADD", "b", "3", "4", "5"}, resp.Integer(3)},
+		{[]string{"SADD", "c", "4", "6"}, resp.Integer(2)},
+		{[]string{"SET", "str", "value"}, resp.OK},
+	})
+
+	for _, tc := range []struct {
+		args     []string
+		expected []resp.Value
+	}{
+		{[]string{"SMEMBERS", "c"}, makeCommand("4", "6")},
+		{[]string{"SUNION", "a", "b", "c"}, makeCommand("1", "2", "3", "4", "5", "6")},
+		{[]string{"SINTER", "a", "b", "c"}, makeCommand("4")},
+		{[]string{"SINTER", "a", "missing"}, makeCommand()},
+		{[]string{"SDIFF", "a", "b", "c"}, makeCommand("1", "2")},
+		{[]string{"SDIFF", "missing", "a"}, makeCommand()},
+	} {
+		as.Nil(h(r, makeCommand(tc.args...)...))
+		res := <-r.output
+		as.Equal(resp.ArrayTag, res.Tag(), tc.args)
+		as.ElementsMatch(tc.expected, res.(*resp.Array).Elements(), tc.args)
+	}
+
+	r3 := resp3Responder{r}
+	as.Nil(h(r3, makeCommand("SINTER", "a", "b")...))
+	as.True(resp.MakeSet(makeCommand("3", "4")...).Equal(<-r.output))
+
+	as.ErrorIs(h(r, makeCommand("SUNION", "a", "str")...), storage.WrongType)
+}
+
+func TestSetStore(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"SADD", "a", "1", "2", "3"}, resp.Integer(3)},
+		{[]string{"SADD", "b", "2", "3", "4"}, resp.Integer(3)},
+		{[]string{"SET", "dest", "value"}, resp.OK},
+		{[]string{"EXPIRE", "dest", "100"}, resp.Integer(1)},
+		{[]string{"SINTERSTORE", "dest", "a", "b"}, resp.Integer(2)},
+		{[]string{"TYPE", "dest"}, resp.SimpleString("set")},
+		{[]string{"TTL", "dest"}, resp.Integer(-1)},
+		{[]string{"SUNIONSTORE", "a", "a", "b"}, resp.Integer(4)},
+		{[]string{"SCARD", "a"}, resp.Integer(4)},
+		{[]string{"SDIFFSTORE", "dest", "b", "a"}, resp.Integer(0)},
+		{[]string{"TYPE", "dest"}, resp.SimpleString("none")},
+		{[]string{"SDIFFSTORE", "dest"}, resp.MakeError(command.ErrWrongArity, "sdiffstore")},
+	})
+
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand("SMEMBERS", "a")...))
+	as.ElementsMatch(
+		makeCommand("1", "2", "3", "4"), (<-r.output).(*resp.Array).Elements(),
+	)
+}
+
+func TestSetStoreAtomic(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand("SADD", "a", "x")...))
+	<-r.output
+
+	// Moving a member back and forth between two sets with STORE commands
+	// must never leave an observer seeing it in both sets, or in neither
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r := newTestResponder()
+		for i := 0; i < 1000; i++ {
+			for _, cmd := range [][]string{
+				{"SUNIONSTORE", "b", "a"},
+				{"SDIFFSTORE", "a", "a", "b"},
+				{"SUNIONSTORE", "a", "b"},
+				{"SDIFFSTORE", "b", "b", "a"},
+			} {
+				as.Nil(h(r, makeCommand(cmd...)...))
+				<-r.output
+			}
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		as.Nil(h(r, makeCommand("SUNION", "a", "b")...))
+		as.Equal(1, (<-r.output).(*resp.Array).Count())
+	}
+	wg.Wait()
+}
@@ -0,0 +1,509 @@
+package command
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+type (
+	// zaddOptions are the flags that precede the score/member pairs of ZADD
+	zaddOptions struct {
+		nx, xx    bool
+		gt, lt    bool
+		changed   bool
+		increment bool
+	}
+
+	// zrangeOptions are the flags that follow the start and stop arguments
+	// of ZRANGE
+	zrangeOptions struct {
+		by         string
+		rev        bool
+		limit      storage.Limit
+		limited    bool
+		withScores bool
+	}
+)
+
+// Error messages
+const (
+	ErrNotFloat   = "ERR value is not a valid float"
+	ErrScoreNaN   = "ERR resulting score is not a number (NaN)"
+	ErrScoreRange = "ERR min or max is not a float"
+	ErrLexRange   = "ERR min or max not valid string range item"
+	ErrXXAndNX    = "ERR XX and NX options at the same time are not " +
+		"compatible"
+	ErrGTLTAndNX = "ERR GT, LT, and/or NX options at the same time are " +
+		"not compatible"
+	ErrIncrementPair = "ERR INCR option supports a single " +
+		"increment-element pair"
+	ErrLimitWithoutBy = "ERR syntax error, LIMIT is only supported in " +
+		"combination with either BYSCORE or BYLEX"
+	ErrWithScoresByLex = "ERR syntax error, WITHSCORES not supported in " +
+		"combination with BYLEX"
+)
+
+// The alternatives of the sortby argument of ZRANGE
+const (
+	byScore = "byscore"
+	byLex   = "bylex"
+)
+
+func zaddOp(s storage.Storage, proto int, a *Args) (resp.Value, error) {
+	opts := &zaddOptions{
+		nx:        a.Has("nx"),
+		xx:        a.Has("xx"),
+		gt:        a.Has("gt"),
+		lt:        a.Has("lt"),
+		changed:   a.Has("change"),
+		increment: a.Has("increment"),
+	}
+	switch {
+	case opts.nx && opts.xx:
+		return nil, resp.MakeError(ErrXXAndNX)
+	case opts.gt && opts.lt, opts.nx && (opts.gt || opts.lt):
+		return nil, resp.MakeError(ErrGTLTAndNX)
+	}
+	members, err := asFields(a.Values("member"))
+	if err != nil {
+		return nil, err
+	}
+	if opts.increment && len(members) != 1 {
+		return nil, resp.MakeError(ErrIncrementPair)
+	}
+	return zadd(s, proto, a.Key("key"), opts, a.Floats("score"), members)
+}
+
+func zincrbyOp(s storage.Storage, proto int, a *Args) (resp.Value, error) {
+	member, err := asField(a.Value("member"))
+	if err != nil {
+		return nil, err
+	}
+	return zadd(s, proto, a.Key("key"),
+		&zaddOptions{increment: true},
+		[]float64{a.Float("increment")}, []resp.BulkString{member},
+	)
+}
+
+// zadd adds or updates the members of a sorted set with the scores at the
+// same positions, replying as ZADD does for the options
+func zadd(
+	s storage.Storage, proto int, key storage.Key, opts *zaddOptions,
+	scores []float64, members []resp.BulkString,
+) (resp.Value, error) {
+	var res resp.Value = resp.NullValue
+	added, changed := 0, 0
+	err := storage.MutateSortedSet(s, key, !opts.xx,
+		func(z *storage.SortedSet) error {
+			for i, m := range members {
+				old, exists := z.Score(m)
+				score, ok, err := opts.apply(old, exists, scores[i])
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+				res = scoreValue(score, proto)
+				switch {
+				case !exists:
+					added++
+				case score != old:
+					changed++
+				default:
+					continue
+				}
+				z.Add(m, score)
+			}
+			return nil
+		},
+	)
+	switch {
+	case err != nil && !errors.Is(err, storage.KeyNotFound):
+		return nil, err
+	case opts.increment:
+		return res, nil
+	case opts.changed:
+		return resp.Integer(added + changed), nil
+	default:
+		return resp.Integer(added), nil
+	}
+}
+
+// apply returns the score that a member should be given, and whether the
+// options allow the member to be added or updated
+func (o *zaddOptions) apply(
+	old float64, exists bool, score float64,
+) (float64, bool, error) {
+	switch {
+	case exists && o.nx, !exists && o.xx:
+		return 0, false, nil
+	case !exists:
+		return score, true, nil
+	}
+	if o.increment {
+		score += old
+		if math.IsNaN(score) {
+			return 0, false, resp.MakeError(ErrScoreNaN)
+		}
+	}
+	if o.gt && score <= old || o.lt && score >= old {
+		return 0, false, nil
+	}
+	return score, true, nil
+}
+
+func zremOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	members, err := asFields(a.Values("member"))
+	if err != nil {
+		return nil, err
+	}
+	removed := 0
+	err = storage.MutateSortedSet(s, key, false,
+		func(z *storage.SortedSet) error {
+			for _, m := range members {
+				if z.Remove(m) {
+					removed++
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(removed), nil
+}
+
+func zcardOp(s storage.Storage, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	res := 0
+	err := storage.InspectSortedSet(s, key, func(z *storage.SortedSet) error {
+		res = z.Len()
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(res), nil
+}
+
+func zscoreOp(s storage.Storage, proto int, a *Args) (resp.Value, error) {
+	key := a.Key("key")
+	member, err := asField(a.Value("member"))
+	if err != nil {
+		return nil, err
+	}
+	var res resp.Value = resp.NullValue
+	err = storage.InspectSortedSet(s, key, func(z *storage.SortedSet) error {
+		if score, ok := z.Score(member); ok {
+			res = scoreValue(score, proto)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func zrankOp(rev bool) protocolOp {
+	return func(
+		s storage.Storage, proto int, a *Args,
+	) (resp.Value, error) {
+		key := a.Key("key")
+		withScore := a.Has("withscore")
+		member, err := asField(a.Value("member"))
+		if err != nil {
+			return nil, err
+		}
+		var res resp.Value = resp.NullValue
+		err = storage.InspectSortedSet(s, key,
+			func(z *storage.SortedSet) error {
+				rank, ok := z.Rank(member)
+				if !ok {
+					return nil
+				}
+				if rev {
+					rank = z.Len() - rank - 1
+				}
+				res = resp.Integer(rank)
+				if withScore {
+					score, _ := z.Score(member)
+					res = resp.MakeArray(res, scoreValue(score, proto))
+				}
+				return nil
+			},
+		)
+		if err != nil && !errors.Is(err, storage.KeyNotFound) {
+			return nil, err
+		}
+		return res, nil
+	}
+}
+
+func zcountOp(s storage.Storage, a *Args) (resp.Value, error) {
+	min, max, err := asScoreBounds(a.Value("min"), a.Value("max"))
+	if err != nil {
+		return nil, err
+	}
+	key := a.Key("key")
+	res := 0
+	err = storage.InspectSortedSet(s, key, func(z *storage.SortedSet) error {
+		res = z.CountByScore(min, max)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return resp.Integer(res), nil
+}
+
+func zrangeOp(s storage.Storage, proto int, a *Args) (resp.Value, error) {
+	opts, err := zrangeOptionsOf(a, a.Choice("sortby"), a.Has("rev"))
+	if err != nil {
+		return nil, err
+	}
+	start, stop := a.Value("start"), a.Value("stop")
+	if opts.rev && opts.by != "" {
+		start, stop = stop, start
+	}
+	return zrange(s, proto, a.Key("key"), opts, start, stop)
+}
+
+// zrangeAlias produces one of the legacy forms of ZRANGE, such as
+// ZRANGEBYSCORE, whose sorting and direction are implied by the form
+func zrangeAlias(by string, rev bool) protocolOp {
+	return func(s storage.Storage, proto int, a *Args) (resp.Value, error) {
+		opts, err := zrangeOptionsOf(a, by, rev)
+		if err != nil {
+			return nil, err
+		}
+		key := a.Key("key")
+		if by == "" {
+			start := resp.Integer(a.Int("start"))
+			stop := resp.Integer(a.Int("stop"))
+			return zrange(s, proto, key, opts, start, stop)
+		}
+		return zrange(s, proto, key, opts, a.Value("min"), a.Value("max"))
+	}
+}
+
+// zrange replies with the members of a sorted set that fall between the
+// bounds, which are indexes unless the options sort by score or by lex
+func zrange(
+	s storage.Storage, proto int, key storage.Key, opts *zrangeOptions,
+	start, stop resp.Value,
+) (resp.Value, error) {
+	sel, err := opts.selector(start, stop)
+	if err != nil {
+		return nil, err
+	}
+	var res []storage.ScoredMember
+	err = storage.InspectSortedSet(s, key, func(z *storage.SortedSet) error {
+		res = sel(z)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storage.KeyNotFound) {
+		return nil, err
+	}
+	return scoredMembers(res, opts.withScores, proto), nil
+}
+
+// selector parses the bounds of a range according to the options, returning
+// a function that selects the requested members
+func (o *zrangeOptions) selector(
+	start, stop resp.Value,
+) (func(*storage.SortedSet) []storage.ScoredMember, error) {
+	switch o.by {
+	case byScore:
+		min, max, err := asScoreBounds(start, stop)
+		if err != nil {
+			return nil, err
+		}
+		return func(z *storage.SortedSet) []storage.ScoredMember {
+			return z.RangeByScore(min, max, o.rev, o.limit)
+		}, nil
+	case byLex:
+		min, max, err := asLexBounds(start, stop)
+		if err != nil {
+			return nil, err
+		}
+		return func(z *storage.SortedSet) []storage.ScoredMember {
+			return z.RangeByLex(min, max, o.rev, o.limit)
+		}, nil
+	default:
+		from, to, err := asRange(start, stop)
+		if err != nil {
+			return nil, err
+		}
+		return func(z *storage.SortedSet) []storage.ScoredMember {
+			return z.Range(from, to, o.rev)
+		}, nil
+	}
+}
+
+// zrangeOptionsOf gathers the LIMIT and WITHSCORES options of the ZRANGE
+// family of commands
+func zrangeOptionsOf(a *Args, by string, rev bool) (*zrangeOptions, error) {
+	res := &zrangeOptions{
+		by:         by,
+		rev:        rev,
+		limit:      storage.NoLimit,
+		limited:    a.Has("limit"),
+		withScores: a.Has("withscores"),
+	}
+	if res.limited {
+		res.limit = storage.Limit{
+			Offset: int(a.Int("offset")),
+			Count:  int(a.Int("count")),
+		}
+	}
+	switch {
+	case res.limited && res.by == "":
+		return nil, resp.MakeError(ErrLimitWithoutBy)
+	case res.withScores && res.by == byLex:
+		return nil, resp.MakeError(ErrWithScoresByLex)
+	}
+	if res.limit.Offset < 0 {
+		res.limit.Count = 0
+	}
+	return res, nil
+}
+
+// scoredMembers returns a set of members, optionally along with their scores.
+// RESP3 clients receive each member and its score as a pair, whereas RESP2
+// clients receive them interleaved
+func scoredMembers(
+	members []storage.ScoredMember, withScores bool, proto int,
+) resp.Value {
+	res := make([]resp.Value, 0, len(members))
+	for _, m := range members {
+		switch {
+		case !withScores:
+			res = append(res, m.Member)
+		case proto < RESP3:
+			res = append(res, m.Member, scoreValue(m.Score, proto))
+		default:
+			res = append(res,
+				resp.MakeArray(m.Member, scoreValue(m.Score, proto)),
+			)
+		}
+	}
+	return resp.MakeArray(res...)
+}
+
+// scoreValue returns a score as a Double for RESP3 clients, and as a bulk
+// string for RESP2 clients
+func scoreValue(score float64, proto int) resp.Value {
+	if proto >= RESP3 {
+		return resp.Double(score)
+	}
+	return resp.BulkString(formatScore(score))
+}
+
+func formatScore(score float64) string {
+	switch abs := math.Abs(score); {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	case abs == 0 || abs >= 1e-5 && abs < 1e17:
+		return strconv.FormatFloat(score, 'f', -1, 64)
+	default:
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+}
+
+// asFloat parses an argument as a score, which may be infinite but may not be
+// NaN
+func asFloat(v resp.Value) (float64, error) {
+	switch v := v.(type) {
+	case resp.Integer:
+		return float64(v), nil
+	case resp.Double:
+		if math.IsNaN(float64(v)) {
+			return 0, resp.MakeError(ErrNotFloat)
+		}
+		return float64(v), nil
+	case resp.String:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil || math.IsNaN(f) {
+			return 0, resp.MakeError(ErrNotFloat)
+		}
+		return f, nil
+	default:
+		return 0, resp.MakeError(ErrNotFloat)
+	}
+}
+
+func asScoreBounds(
+	min, max resp.Value,
+) (storage.ScoreBound, storage.ScoreBound, error) {
+	lo, err := asScoreBound(min)
+	if err != nil {
+		return lo, lo, err
+	}
+	hi, err := asScoreBound(max)
+	return lo, hi, err
+}
+
+// asScoreBound parses a score, which is exclusive if prefixed by '('
+func asScoreBound(v resp.Value) (storage.ScoreBound, error) {
+	var res storage.ScoreBound
+	s, ok := v.(resp.BulkString)
+	if !ok {
+		return res, resp.MakeError(ErrScoreRange)
+	}
+	if strings.HasPrefix(string(s), "(") {
+		res.Exclusive = true
+		s = s[1:]
+	}
+	f, err := asFloat(s)
+	if err != nil {
+		return res, resp.MakeError(ErrScoreRange)
+	}
+	res.Score = f
+	return res, nil
+}
+
+func asLexBounds(
+	min, max resp.Value,
+) (storage.LexBound, storage.LexBound, error) {
+	lo, err := asLexBound(min)
+	if err != nil {
+		return lo, lo, err
+	}
+	hi, err := asLexBound(max)
+	return lo, hi, err
+}
+
+// asLexBound parses a lexicographical bound, which is either '-' or '+' for
+// the infinities, or a member prefixed by '[' if inclusive or '(' if not
+func asLexBound(v resp.Value) (storage.LexBound, error) {
+	var res storage.LexBound
+	s, ok := v.(resp.BulkString)
+	if !ok || len(s) == 0 {
+		return res, resp.MakeError(ErrLexRange)
+	}
+	switch {
+	case s == "-":
+		res.Infinity = -1
+	case s == "+":
+		res.Infinity = 1
+	case s[0] == '[':
+		res.Member = s[1:]
+	case s[0] == '(':
+		res.Member = s[1:]
+		res.Exclusive = true
+	default:
+		return res, resp.MakeError(ErrLexRange)
+	}
+	return res, nil
+}
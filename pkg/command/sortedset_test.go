@@ -0,0 +1,116 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedSet(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"ZCARD", "zset"}, resp.Integer(0)},
+		{[]string{"ZADD", "zset", "1", "a", "2", "b", "3", "c"}, resp.Integer(3)},
+		{[]string{"ZADD", "zset", "4", "c", "5", "d"}, resp.Integer(1)},
+		{[]string{"ZADD", "zset", "CH", "5", "c", "6", "e"}, resp.Integer(2)},
+		{[]string{"ZADD", "zset", "NX", "9", "a", "7", "f"}, resp.Integer(1)},
+		{[]string{"ZADD", "zset", "XX", "9", "g"}, resp.Integer(0)},
+		{[]string{"ZADD", "zset", "GT", "CH", "0", "a", "8", "b"}, resp.Integer(1)},
+		{[]string{"ZADD", "zset", "INCR", "1", "a"}, resp.BulkString("2")},
+		{[]string{"ZADD", "zset", "NX", "INCR", "1", "a"}, resp.NullValue},
+		{[]string{"ZADD", "zset", "NX", "XX", "1", "a"}, resp.MakeError(command.ErrXXAndNX)},
+		{[]string{"ZADD", "zset", "GT", "LT", "1", "a"}, resp.MakeError(command.ErrGTLTAndNX)},
+		{[]string{"ZADD", "zset", "INCR", "1", "a", "2", "b"}, resp.MakeError(command.ErrIncrementPair)},
+		{[]string{"ZADD", "zset", "1", "a", "2"}, resp.MakeError(command.ErrSyntax)},
+		{[]string{"ZADD", "zset", "x", "a"}, resp.MakeError(command.ErrNotFloat)},
+		{[]string{"ZADD", "zset", "nan", "a"}, resp.MakeError(command.ErrNotFloat)},
+		{[]string{"ZCARD", "zset"}, resp.Integer(6)},
+		{[]string{"ZSCORE", "zset", "c"}, resp.BulkString("5")},
+		{[]string{"ZSCORE", "zset", "z"}, resp.NullValue},
+		{[]string{"ZINCRBY", "zset", "0.5", "c"}, resp.BulkString("5.5")},
+		{[]string{"ZINCRBY", "zset", "-inf", "z"}, resp.BulkString("-inf")},
+		{[]string{"ZINCRBY", "zset", "+inf", "z"}, resp.MakeError(command.ErrScoreNaN)},
+		{[]string{"ZRANK", "zset", "z"}, resp.Integer(0)},
+		{[]string{"ZRANK", "zset", "c"}, resp.Integer(3)},
+		{[]string{"ZREVRANK", "zset", "c"}, resp.Integer(3)},
+		{[]string{"ZRANK", "zset", "c", "WITHSCORE"}, resp.MakeArray(
+			resp.Integer(3), resp.BulkString("5.5"),
+		)},
+		{[]string{"ZRANK", "zset", "missing"}, resp.NullValue},
+		{[]string{"ZCOUNT", "zset", "(2", "6"}, resp.Integer(3)},
+		{[]string{"ZCOUNT", "zset", "-inf", "+inf"}, resp.Integer(7)},
+		{[]string{"ZCOUNT", "zset", "x", "1"}, resp.MakeError(command.ErrScoreRange)},
+		{[]string{"ZREM", "zset", "z", "y"}, resp.Integer(1)},
+		{[]string{"TYPE", "zset"}, resp.SimpleString("zset")},
+		{[]string{"LPUSH", "zset", "a"}, storage.WrongType},
+		{[]string{"ZREM", "zset", "a", "b", "c", "d", "e", "f"}, resp.Integer(6)},
+		{[]string{"TYPE", "zset"}, resp.SimpleString("none")},
+		{[]string{"ZREM", "zset", "a"}, resp.Integer(0)},
+	})
+}
+
+func TestSortedSetRange(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"ZRANGE", "zset", "0", "-1"}, resp.EmptyArray},
+		{[]string{
+			"ZADD", "zset", "1", "a", "2", "b", "3", "c", "4", "d", "5", "e",
+		}, resp.Integer(5)},
+		{[]string{"ZRANGE", "zset", "0", "-1"}, bulkStrings("a", "b", "c", "d", "e")},
+		{[]string{"ZRANGE", "zset", "-2", "-1"}, bulkStrings("d", "e")},
+		{[]string{"ZRANGE", "zset", "0", "1", "REV"}, bulkStrings("e", "d")},
+		{[]string{"ZREVRANGE", "zset", "0", "1"}, bulkStrings("e", "d")},
+		{[]string{"ZRANGE", "zset", "0", "1", "WITHSCORES"},
+			bulkStrings("a", "1", "b", "2"),
+		},
+		{[]string{"ZRANGE", "zset", "(1", "3", "BYSCORE"}, bulkStrings("b", "c")},
+		{[]string{"ZRANGE", "zset", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", "1", "2"},
+			bulkStrings("d", "c"),
+		},
+		{[]string{"ZRANGEBYSCORE", "zset", "2", "+inf", "LIMIT", "1", "-1"},
+			bulkStrings("c", "d", "e"),
+		},
+		{[]string{"ZREVRANGEBYSCORE", "zset", "(5", "(2", "WITHSCORES"},
+			bulkStrings("d", "4", "c", "3"),
+		},
+		{[]string{"ZRANGE", "zset", "0", "1", "LIMIT", "0", "1"},
+			resp.MakeError(command.ErrLimitWithoutBy),
+		},
+		{[]string{"ZRANGE", "zset", "-", "+", "BYLEX", "WITHSCORES"},
+			resp.MakeError(command.ErrWithScoresByLex),
+		},
+		{[]string{"ZRANGE", "zset", "0", "1", "BOGUS"}, resp.MakeError(command.ErrSyntax)},
+		{[]string{"ZRANGE", "zset", "a", "1"}, resp.MakeError(command.ErrNotInteger)},
+
+		{[]string{"ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d"}, resp.Integer(4)},
+		{[]string{"ZRANGE", "lex", "[b", "+", "BYLEX"}, bulkStrings("b", "c", "d")},
+		{[]string{"ZRANGEBYLEX", "lex", "-", "(c"}, bulkStrings("a", "b")},
+		{[]string{"ZREVRANGEBYLEX", "lex", "+", "-", "LIMIT", "0", "2"},
+			bulkStrings("d", "c"),
+		},
+		{[]string{"ZRANGEBYLEX", "lex", "b", "c"}, resp.MakeError(command.ErrLexRange)},
+	})
+}
+
+func TestSortedSetResp3(t *testing.T) {
+	as := assert.New(t)
+	h := command.Storage(storage.NewMemory())
+	r := resp3Responder{newTestResponder()}
+	as.Nil(h(r, makeCommand("ZADD", "zset", "1.5", "a", "2", "b")...))
+	as.Equal(resp.Integer(2), <-r.output)
+
+	as.Nil(h(r, makeCommand("ZSCORE", "zset", "a")...))
+	as.Equal(resp.Double(1.5), <-r.output)
+
+	as.Nil(h(r, makeCommand("ZINCRBY", "zset", "1", "a")...))
+	as.Equal(resp.Double(2.5), <-r.output)
+
+	as.Nil(h(r, makeCommand("ZRANGE", "zset", "0", "-1", "WITHSCORES")...))
+	as.Equal(resp.MakeArray(
+		resp.MakeArray(resp.BulkString("b"), resp.Double(2)),
+		resp.MakeArray(resp.BulkString("a"), resp.Double(2.5)),
+	), <-r.output)
+}
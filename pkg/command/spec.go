@@ -0,0 +1,560 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+type (
+	// CommandSpec describes a command: how many arguments it accepts, how it
+	// behaves, where its keys are found, and the arguments that it parses
+	CommandSpec struct {
+		Name    string
+		Group   string
+		Summary string
+
+		// Arity is the number of arguments that the command accepts,
+		// including its verb. A negative Arity is the minimum number of
+		// arguments accepted
+		Arity int
+		Flags Flag
+
+		// FirstKey, LastKey and Step locate the keys of the command by their
+		// positions, where the verb is at position zero. A negative LastKey
+		// counts back from the final argument, and a zero FirstKey means the
+		// command has no keys
+		FirstKey int
+		LastKey  int
+		Step     int
+
+		// Categories are the ACL categories that the command belongs to in
+		// addition to those implied by its Group and Flags
+		Categories []string
+		Args       []ArgSpec
+	}
+
+	// Flag describes the behavior of a command
+	Flag uint8
+
+	// ArgSpec describes an argument of a command. Arguments with a Token
+	// are introduced by that keyword, and can appear in any order once the
+	// positional arguments have been parsed
+	ArgSpec struct {
+		Name     string
+		Type     ArgType
+		Token    string
+		Optional bool
+		Multiple bool
+
+		// Args are the alternatives of a OneOf argument, or the members of
+		// a Block argument
+		Args []ArgSpec
+	}
+
+	// ArgType is the type that an argument is parsed as
+	ArgType uint8
+
+	// Args holds the arguments of a command, parsed by name according to its
+	// CommandSpec
+	Args struct {
+		values  map[string][]any
+		choices map[string]string
+	}
+
+	argParser struct {
+		input []resp.Value
+		res   *Args
+	}
+)
+
+// Command flags
+const (
+	FlagReadOnly Flag = 1 << iota
+	FlagWrite
+	FlagFast
+	FlagBlocking
+	FlagAdmin
+	FlagNoAuth
+)
+
+// Argument types
+const (
+	ArgString ArgType = iota
+	ArgKey
+	ArgInteger
+	ArgDouble
+	ArgPureToken
+	ArgOneOf
+	ArgBlock
+)
+
+// Command groups
+const (
+	GroupGeneric     = "generic"
+	GroupString      = "string"
+	GroupList        = "list"
+	GroupHash        = "hash"
+	GroupSet         = "set"
+	GroupSortedSet   = "sorted-set"
+	GroupPubSub      = "pubsub"
+	GroupTransaction = "transactions"
+	GroupConnection  = "connection"
+	GroupServer      = "server"
+)
+
+// Error messages
+const (
+	ErrWrongArity = "ERR wrong number of arguments for '%s' command"
+)
+
+var (
+	flagNames = []struct {
+		flag Flag
+		name string
+	}{
+		{FlagReadOnly, "readonly"},
+		{FlagWrite, "write"},
+		{FlagFast, "fast"},
+		{FlagBlocking, "blocking"},
+		{FlagAdmin, "admin"},
+		{FlagNoAuth, "no_auth"},
+	}
+
+	argTypeNames = []string{
+		ArgString:    "string",
+		ArgKey:       "key",
+		ArgInteger:   "integer",
+		ArgDouble:    "double",
+		ArgPureToken: "pure-token",
+		ArgOneOf:     "oneof",
+		ArgBlock:     "block",
+	}
+
+	groupCategories = map[string]string{
+		GroupGeneric:     acl.CategoryKeyspace,
+		GroupString:      acl.CategoryString,
+		GroupList:        acl.CategoryList,
+		GroupHash:        acl.CategoryHash,
+		GroupSet:         acl.CategorySet,
+		GroupSortedSet:   acl.CategorySortedSet,
+		GroupPubSub:      acl.CategoryPubSub,
+		GroupTransaction: acl.CategoryTransaction,
+		GroupConnection:  acl.CategoryConnection,
+	}
+)
+
+// Has reports whether all of the provided flags are set
+func (f Flag) Has(flags Flag) bool {
+	return f&flags == flags
+}
+
+// Names returns the names of the flags that are set
+func (f Flag) Names() []string {
+	var res []string
+	for _, fn := range flagNames {
+		if f.Has(fn.flag) {
+			res = append(res, fn.name)
+		}
+	}
+	return res
+}
+
+// String returns the name of the ArgType
+func (t ArgType) String() string {
+	if int(t) < len(argTypeNames) {
+		return argTypeNames[t]
+	}
+	return argTypeNames[ArgString]
+}
+
+// ACLCategories returns every ACL category that the command belongs to
+func (s *CommandSpec) ACLCategories() []string {
+	var res []string
+	if c, ok := groupCategories[s.Group]; ok {
+		res = append(res, c)
+	}
+	if s.Flags.Has(FlagReadOnly) {
+		res = append(res, acl.CategoryRead)
+	}
+	if s.Flags.Has(FlagWrite) {
+		res = append(res, acl.CategoryWrite)
+	}
+	if s.Flags.Has(FlagAdmin) {
+		res = append(res, acl.CategoryAdmin, acl.CategoryDangerous)
+	}
+	if s.Flags.Has(FlagBlocking) {
+		res = append(res, acl.CategoryBlocking)
+	}
+	if s.Flags.Has(FlagFast) {
+		res = append(res, acl.CategoryFast)
+	} else {
+		res = append(res, acl.CategorySlow)
+	}
+	return append(res, s.Categories...)
+}
+
+// CheckArity returns an error if a command's arguments, including its verb,
+// don't satisfy the Arity of the CommandSpec
+func (s *CommandSpec) CheckArity(args []resp.Value) error {
+	return s.checkArity(len(args))
+}
+
+func (s *CommandSpec) checkArity(n int) error {
+	if s.Arity >= 0 && n != s.Arity || s.Arity < 0 && n < -s.Arity {
+		return resp.MakeError(ErrWrongArity, strings.ToLower(s.Name))
+	}
+	return nil
+}
+
+// Keys returns the keys found among a command's arguments, including its
+// verb, in the form that the Storage resolves them to. An argument in a key
+// position that can't be resolved to a Key is reported as an error
+func (s *CommandSpec) Keys(args []resp.Value) ([]storage.Key, error) {
+	if s.FirstKey <= 0 {
+		return nil, nil
+	}
+	last := s.LastKey
+	if last < 0 {
+		last += len(args)
+	}
+	step := s.Step
+	if step <= 0 {
+		step = 1
+	}
+	var res []storage.Key
+	for i := s.FirstKey; i <= last && i < len(args); i += step {
+		key, err := storage.AsKey(args[i])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, key)
+	}
+	return res, nil
+}
+
+// Parse checks the arguments of a command, as received by its Handler,
+// against the CommandSpec and parses them by name
+func (s *CommandSpec) Parse(args ...resp.Value) (*Args, error) {
+	if err := s.checkArity(len(args) + 1); err != nil {
+		return nil, err
+	}
+	return s.parse(args)
+}
+
+// parseSubcommand parses the arguments that follow a subcommand, for a
+// CommandSpec whose Arity counts both the verb and the subcommand
+func (s *CommandSpec) parseSubcommand(args []resp.Value) (*Args, error) {
+	if err := s.checkArity(len(args) + 2); err != nil {
+		return nil, err
+	}
+	return s.parse(args)
+}
+
+func (s *CommandSpec) parse(args []resp.Value) (*Args, error) {
+	p := &argParser{
+		input: args,
+		res: &Args{
+			values:  map[string][]any{},
+			choices: map[string]string{},
+		},
+	}
+	if err := p.parseArgs(s.Args); err != nil {
+		return nil, err
+	}
+	if len(p.input) != 0 {
+		return nil, resp.MakeError(ErrSyntax)
+	}
+	return p.res, nil
+}
+
+// Has reports whether the named argument was provided
+func (a *Args) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// Choice returns the name of the alternative that was provided for the named
+// OneOf argument, or an empty string if none was
+func (a *Args) Choice(name string) string {
+	return a.choices[name]
+}
+
+// Value returns the named String argument as it was received
+func (a *Args) Value(name string) resp.Value {
+	v, _ := a.first(name).(resp.Value)
+	return v
+}
+
+// Values returns every value of the named String argument as it was received
+func (a *Args) Values(name string) []resp.Value {
+	return allOf[resp.Value](a.values[name])
+}
+
+// String returns the named String argument, or an empty string if it wasn't
+// received as a bulk string
+func (a *Args) String(name string) resp.BulkString {
+	s, _ := a.first(name).(resp.BulkString)
+	return s
+}
+
+// Key returns the named Key argument
+func (a *Args) Key(name string) storage.Key {
+	k, _ := a.first(name).(storage.Key)
+	return k
+}
+
+// Keys returns every value of the named Key argument
+func (a *Args) Keys(name string) []storage.Key {
+	return allOf[storage.Key](a.values[name])
+}
+
+// Int returns the named Integer argument
+func (a *Args) Int(name string) int64 {
+	i, _ := a.first(name).(int64)
+	return i
+}
+
+// Float returns the named Double argument
+func (a *Args) Float(name string) float64 {
+	f, _ := a.first(name).(float64)
+	return f
+}
+
+// Floats returns every value of the named Double argument
+func (a *Args) Floats(name string) []float64 {
+	return allOf[float64](a.values[name])
+}
+
+func (a *Args) first(name string) any {
+	if v := a.values[name]; len(v) > 0 {
+		return v[0]
+	}
+	return nil
+}
+
+func (a *Args) add(name string, value any) {
+	a.values[name] = append(a.values[name], value)
+}
+
+// parseArgs parses each run of positional arguments in order, followed by
+// any arguments that are introduced by their tokens
+func (p *argParser) parseArgs(specs []ArgSpec) error {
+	for len(specs) > 0 {
+		i := 0
+		for ; i < len(specs) && specs[i].positional(); i++ {
+			if err := p.parsePositional(specs[i], specs[i+1:]); err != nil {
+				return err
+			}
+		}
+		j := i
+		for j < len(specs) && !specs[j].positional() {
+			j++
+		}
+		if err := p.parseClauses(specs[i:j]); err != nil {
+			return err
+		}
+		specs = specs[j:]
+	}
+	return nil
+}
+
+// parsePositional parses a positional argument. An argument that can be
+// repeated consumes as many values as it can, leaving enough for the
+// required positional arguments after it, and stopping at the first value
+// that introduces one of the clauses that follow. An optional argument is
+// skipped if there are no values left for it
+func (p *argParser) parsePositional(spec ArgSpec, rest []ArgSpec) error {
+	reserved := 0
+	for _, r := range rest {
+		if r.positional() && !r.Optional {
+			reserved += r.width()
+		}
+	}
+	first := 1
+	if spec.Optional {
+		first = 0
+	}
+	end := len(p.input)
+	for i := first; i < len(p.input); i++ {
+		if matchClause(rest, p.input[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	if spec.Optional && end-reserved < spec.width() {
+		return nil
+	}
+	if !spec.Multiple {
+		return p.parseArg(spec)
+	}
+	for n := spec.width(); n == spec.width() || n <= end-reserved; {
+		if err := p.parseArg(spec); err != nil {
+			return err
+		}
+		n += spec.width()
+	}
+	return nil
+}
+
+func (p *argParser) parseClauses(specs []ArgSpec) error {
+	seen := make([]bool, len(specs))
+	for len(p.input) > 0 {
+		i := matchClause(specs, p.input[0])
+		if i < 0 {
+			break
+		}
+		if seen[i] && !specs[i].Multiple {
+			return resp.MakeError(ErrSyntax)
+		}
+		seen[i] = true
+		if err := p.parseArg(specs[i]); err != nil {
+			return err
+		}
+	}
+	for i, spec := range specs {
+		if !seen[i] && !spec.Optional {
+			return resp.MakeError(ErrSyntax)
+		}
+	}
+	return nil
+}
+
+// matchClause returns the index of the argument that a value introduces, or
+// -1 if it introduces none of them
+func matchClause(specs []ArgSpec, v resp.Value) int {
+	kw := asKeyword(v)
+	for i, spec := range specs {
+		if !spec.positional() && spec.matches(kw) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *argParser) parseArg(spec ArgSpec) error {
+	if spec.Token != "" && spec.Type != ArgPureToken {
+		if len(p.input) == 0 || !spec.matches(asKeyword(p.input[0])) {
+			return resp.MakeError(ErrSyntax)
+		}
+		p.input = p.input[1:]
+	}
+	switch spec.Type {
+	case ArgPureToken:
+		return p.parsePureToken(spec)
+	case ArgOneOf:
+		return p.parseOneOf(spec)
+	case ArgBlock:
+		for _, member := range spec.Args {
+			if err := p.parseArg(member); err != nil {
+				return err
+			}
+		}
+		p.res.add(spec.Name, true)
+		return nil
+	default:
+		return p.parseValue(spec)
+	}
+}
+
+func (p *argParser) parsePureToken(spec ArgSpec) error {
+	if len(p.input) == 0 || !spec.matches(asKeyword(p.input[0])) {
+		return resp.MakeError(ErrSyntax)
+	}
+	p.input = p.input[1:]
+	p.res.add(spec.Name, true)
+	return nil
+}
+
+func (p *argParser) parseOneOf(spec ArgSpec) error {
+	if len(p.input) == 0 {
+		return resp.MakeError(ErrSyntax)
+	}
+	kw := asKeyword(p.input[0])
+	for _, alt := range spec.Args {
+		if !alt.matches(kw) {
+			continue
+		}
+		if err := p.parseArg(alt); err != nil {
+			return err
+		}
+		p.res.choices[spec.Name] = alt.Name
+		p.res.add(spec.Name, true)
+		return nil
+	}
+	return resp.MakeError(ErrSyntax)
+}
+
+func (p *argParser) parseValue(spec ArgSpec) error {
+	if len(p.input) == 0 {
+		return resp.MakeError(ErrSyntax)
+	}
+	v := p.input[0]
+	res, err := spec.Type.convert(v)
+	if err != nil {
+		return err
+	}
+	p.input = p.input[1:]
+	p.res.add(spec.Name, res)
+	return nil
+}
+
+func (t ArgType) convert(v resp.Value) (any, error) {
+	switch t {
+	case ArgKey:
+		return storage.AsKey(v)
+	case ArgInteger:
+		return asInteger(v)
+	case ArgDouble:
+		return asFloat(v)
+	default:
+		return v, nil
+	}
+}
+
+// positional reports whether the argument is identified by its position
+// rather than by a token
+func (a ArgSpec) positional() bool {
+	return a.Token == "" && a.Type != ArgOneOf
+}
+
+// width is the number of input values that a positional argument consumes
+func (a ArgSpec) width() int {
+	if a.Type != ArgBlock {
+		return 1
+	}
+	res := 0
+	for _, member := range a.Args {
+		res += member.width()
+	}
+	return res
+}
+
+// matches reports whether a keyword introduces the argument
+func (a ArgSpec) matches(kw string) bool {
+	switch {
+	case a.Type == ArgOneOf:
+		for _, alt := range a.Args {
+			if alt.matches(kw) {
+				return true
+			}
+		}
+		return false
+	case a.Token != "":
+		return strings.ToUpper(a.Token) == kw
+	default:
+		return false
+	}
+}
+
+func allOf[T any](values []any) []T {
+	res := make([]T, 0, len(values))
+	for _, v := range values {
+		if t, ok := v.(T); ok {
+			res = append(res, t)
+		}
+	}
+	return res
+}
@@ -0,0 +1,200 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecParse(t *testing.T) {
+	as := assert.New(t)
+	spec := &command.CommandSpec{
+		Name:  "test",
+		Arity: -4,
+		Args: []command.ArgSpec{
+			{Name: "dest", Type: command.ArgKey},
+			{Name: "src", Type: command.ArgKey, Multiple: true},
+			{Name: "count", Type: command.ArgInteger},
+			{Name: "weight", Type: command.ArgDouble, Token: "WEIGHT",
+				Optional: true},
+			{Name: "limit", Type: command.ArgBlock, Token: "LIMIT",
+				Optional: true, Args: []command.ArgSpec{
+					{Name: "offset", Type: command.ArgInteger},
+					{Name: "size", Type: command.ArgInteger},
+				}},
+			{Name: "order", Type: command.ArgOneOf, Optional: true,
+				Args: []command.ArgSpec{
+					{Name: "asc", Type: command.ArgPureToken, Token: "ASC"},
+					{Name: "desc", Type: command.ArgPureToken, Token: "DESC"},
+				}},
+		},
+	}
+
+	a, err := spec.Parse(makeCommand(
+		"d", "s1", "s2", "10", "desc", "limit", "5", "20", "WEIGHT", "1.5",
+	)...)
+	as.Nil(err)
+	as.Equal(storage.Key{"d"}, a.Key("dest"))
+	as.Equal([]storage.Key{{"s1"}, {"s2"}}, a.Keys("src"))
+	as.Equal(int64(10), a.Int("count"))
+	as.Equal(1.5, a.Float("weight"))
+	as.True(a.Has("limit"))
+	as.Equal(int64(5), a.Int("offset"))
+	as.Equal(int64(20), a.Int("size"))
+	as.Equal("desc", a.Choice("order"))
+	as.True(a.Has("desc"))
+	as.False(a.Has("asc"))
+
+	a, err = spec.Parse(makeCommand("d", "s1", "10")...)
+	as.Nil(err)
+	as.Equal([]storage.Key{{"s1"}}, a.Keys("src"))
+	as.False(a.Has("weight"))
+	as.Equal("", a.Choice("order"))
+
+	for _, tc := range []struct {
+		args []string
+		err  resp.Error
+	}{
+		{[]string{"d", "s1"}, resp.MakeError(command.ErrWrongArity, "test")},
+		{[]string{"d", "s1", "ten"}, resp.MakeError(command.ErrNotInteger)},
+		{[]string{"d", "s1", "1", "WEIGHT", "1", "BOGUS"},
+			resp.MakeError(command.ErrSyntax)},
+		{[]string{"d", "s1", "1", "ASC", "DESC"},
+			resp.MakeError(command.ErrSyntax)},
+		{[]string{"d", "s1", "1", "LIMIT", "5"},
+			resp.MakeError(command.ErrSyntax)},
+		{[]string{"d", "s1", "1", "WEIGHT", "x"},
+			resp.MakeError(command.ErrNotFloat)},
+		{[]string{"d", "s1", "1", "WEIGHT", "1", "WEIGHT", "2"},
+			resp.MakeError(command.ErrSyntax)},
+	} {
+		_, err := spec.Parse(makeCommand(tc.args...)...)
+		as.Equal(tc.err, err, tc.args)
+	}
+}
+
+func TestSpecMetadata(t *testing.T) {
+	as := assert.New(t)
+	specs := append(command.StorageSpecs(), command.PubSubSpecs()...)
+	lookup := func(name string) (*command.CommandSpec, bool) {
+		for _, s := range specs {
+			if s.Name == name {
+				return s, true
+			}
+		}
+		return nil, false
+	}
+
+	set, ok := lookup("set")
+	as.True(ok)
+	as.Equal("set", set.Name)
+	as.Equal(-3, set.Arity)
+	as.True(set.Flags.Has(command.FlagWrite))
+	as.Equal([]string{"write"}, set.Flags.Names())
+	as.Equal([]string{
+		acl.CategoryString, acl.CategoryWrite, acl.CategorySlow,
+	}, set.ACLCategories())
+
+	get, _ := lookup("get")
+	as.Equal([]string{"readonly", "fast"}, get.Flags.Names())
+	as.Nil(get.CheckArity(makeCommand("GET", "key")))
+	as.Equal(
+		resp.MakeError(command.ErrWrongArity, "get"),
+		get.CheckArity(makeCommand("GET")),
+	)
+
+	sinter, _ := lookup("sinter")
+	keys, err := sinter.Keys(makeCommand("SINTER", "a", "b", "c"))
+	as.Nil(err)
+	as.Equal([]storage.Key{{"a"}, {"b"}, {"c"}}, keys)
+	_, err = sinter.Keys([]resp.Value{
+		resp.BulkString("SINTER"), resp.Integer(1),
+	})
+	as.NotNil(err)
+	ping, _ := lookup("ping")
+	keys, err = ping.Keys(makeCommand("PING", "hello"))
+	as.Nil(err)
+	as.Nil(keys)
+
+	_, ok = lookup("bogus")
+	as.False(ok)
+
+	as.Greater(len(specs), 60)
+	names := map[string]bool{}
+	for _, s := range command.Specs() {
+		as.False(names[s.Name], s.Name)
+		names[s.Name] = true
+	}
+}
+
+func TestSpecValidation(t *testing.T) {
+	as := assert.New(t)
+	called := false
+	h := command.NewHandler(command.Handlers{
+		"SPECVALIDATED": func(r command.Responder, _ ...resp.Value) error {
+			called = true
+			return command.Emit(r, resp.OK)
+		},
+	}, &command.CommandSpec{
+		Name:  "specvalidated",
+		Arity: 2,
+	})
+
+	r := newTestResponder()
+	as.Equal(
+		resp.MakeError(command.ErrWrongArity, "specvalidated"),
+		h(r, makeCommand("SPECVALIDATED")...),
+	)
+	as.False(called)
+	as.Nil(h(r, makeCommand("specvalidated", "arg")...))
+	as.Equal(resp.OK, <-r.output)
+	as.True(called)
+}
+
+func TestSpecOwnership(t *testing.T) {
+	as := assert.New(t)
+	custom := func(r command.Responder, args ...resp.Value) error {
+		return command.Emit(r, resp.Integer(len(args)))
+	}
+	r := newTestResponder()
+
+	h := command.NewHandler(command.Handlers{"GET": custom})
+	as.Nil(h(r, makeCommand("GET", "a", "b")...))
+	as.Equal(resp.Integer(2), <-r.output)
+
+	h = command.Wrap(command.Handlers{"GET": custom},
+		command.Storage(storage.NewMemory()),
+	)
+	as.Nil(h(r, makeCommand("GET")...))
+	as.Equal(resp.Integer(0), <-r.output)
+	as.Equal(
+		resp.MakeError(command.ErrWrongArity, "set"),
+		h(r, makeCommand("SET", "key")...),
+	)
+
+	s := storage.NewMemory()
+	testCommands(t, command.Use(
+		command.NewHandler(command.Handlers{"GET": custom}),
+		command.Transactions(s, command.WithSpecs(
+			&command.CommandSpec{Name: "get", Arity: -1},
+		)),
+	), [][2]any{
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"GET"}, command.Queued},
+		{[]string{"EXEC"}, resp.MakeArray(resp.Integer(0))},
+	})
+}
+
+func TestSpecTransaction(t *testing.T) {
+	testCommands(t, newTransactional(), [][2]any{
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"SET", "key", "value"}, command.Queued},
+		{[]string{"GET"}, resp.MakeError(command.ErrWrongArity, "get")},
+		{[]string{"EXEC"}, resp.MakeError(command.ErrExecAborted)},
+		{[]string{"GET", "key"}, resp.NullValue},
+	})
+}
@@ -0,0 +1,581 @@
+package command
+
+import (
+	"slices"
+
+	"github.com/kode4food/respect/pkg/acl"
+)
+
+var (
+	keyArg     = ArgSpec{Name: "key", Type: ArgKey}
+	keysArg    = ArgSpec{Name: "key", Type: ArgKey, Multiple: true}
+	valueArg   = ArgSpec{Name: "value", Type: ArgString}
+	fieldArg   = ArgSpec{Name: "field", Type: ArgString}
+	fieldsArg  = ArgSpec{Name: "field", Type: ArgString, Multiple: true}
+	memberArg  = ArgSpec{Name: "member", Type: ArgString}
+	membersArg = ArgSpec{Name: "member", Type: ArgString, Multiple: true}
+	startArg   = ArgSpec{Name: "start", Type: ArgInteger}
+	stopArg    = ArgSpec{Name: "stop", Type: ArgInteger}
+	minArg     = ArgSpec{Name: "min", Type: ArgString}
+	maxArg     = ArgSpec{Name: "max", Type: ArgString}
+
+	// Cursors are unsigned, so they're parsed by the command rather than as
+	// Integers
+	cursorArg = ArgSpec{Name: "cursor", Type: ArgString}
+	matchArg  = ArgSpec{
+		Name: "pattern", Type: ArgString, Token: "MATCH", Optional: true,
+	}
+	countArg = ArgSpec{
+		Name: "count", Type: ArgInteger, Token: "COUNT", Optional: true,
+	}
+
+	withScoreArg = ArgSpec{
+		Name: "withscore", Type: ArgPureToken, Token: "WITHSCORE",
+		Optional: true,
+	}
+	withScoresArg = ArgSpec{
+		Name: "withscores", Type: ArgPureToken, Token: "WITHSCORES",
+		Optional: true,
+	}
+	limitArg = ArgSpec{
+		Name: "limit", Type: ArgBlock, Token: "LIMIT", Optional: true,
+		Args: []ArgSpec{
+			{Name: "offset", Type: ArgInteger},
+			{Name: "count", Type: ArgInteger},
+		},
+	}
+
+	elementsArg = ArgSpec{Name: "element", Type: ArgString, Multiple: true}
+	popCountArg = ArgSpec{Name: "count", Type: ArgInteger, Optional: true}
+
+	subcommandArgs = []ArgSpec{
+		{Name: "subcommand", Type: ArgString},
+		{Name: "arg", Type: ArgString, Optional: true, Multiple: true},
+	}
+)
+
+var (
+	getSpec = keySpec("get", GroupString, 2, FlagReadOnly|FlagFast).
+		describe("Returns the string value of a key")
+	delSpec = keySpec("del", GroupGeneric, 2, FlagWrite).
+		describe("Deletes a key")
+
+	setSpec = keySpec("set", GroupString, -3, FlagWrite,
+		valueArg,
+		ArgSpec{
+			Name:     "condition",
+			Type:     ArgOneOf,
+			Optional: true,
+			Args: []ArgSpec{
+				{Name: "nx", Type: ArgPureToken, Token: "NX"},
+				{Name: "xx", Type: ArgPureToken, Token: "XX"},
+			},
+		},
+		ArgSpec{
+			Name:     "get",
+			Type:     ArgPureToken,
+			Token:    "GET",
+			Optional: true,
+		},
+		ArgSpec{
+			Name:     "expiration",
+			Type:     ArgOneOf,
+			Optional: true,
+			Args: []ArgSpec{
+				{Name: "seconds", Type: ArgInteger, Token: "EX"},
+				{Name: "milliseconds", Type: ArgInteger, Token: "PX"},
+				{Name: "unix-time-seconds", Type: ArgInteger, Token: "EXAT"},
+				{Name: "unix-time-milliseconds", Type: ArgInteger,
+					Token: "PXAT"},
+				{Name: "keepttl", Type: ArgPureToken, Token: "KEEPTTL"},
+			},
+		},
+	).describe("Sets the string value of a key, ignoring its type")
+
+	incrSpec = keySpec("incr", GroupString, 2, FlagWrite|FlagFast).
+			describe("Increments the integer value of a key by one")
+	decrSpec = keySpec("decr", GroupString, 2, FlagWrite|FlagFast).
+			describe("Decrements the integer value of a key by one")
+	appendSpec = keySpec("append", GroupString, 3, FlagWrite|FlagFast,
+		valueArg,
+	).describe("Appends a string to the value of a key")
+	getSetSpec = keySpec("getset", GroupString, 3, FlagWrite|FlagFast,
+		valueArg,
+	).describe("Returns the previous string value of a key after setting it")
+	setNXSpec = keySpec("setnx", GroupString, 3, FlagWrite|FlagFast,
+		valueArg,
+	).describe("Sets the string value of a key only when it doesn't exist")
+	incrBySpec = keySpec("incrby", GroupString, 3, FlagWrite|FlagFast,
+		ArgSpec{Name: "increment", Type: ArgInteger},
+	).describe("Increments the integer value of a key by a number")
+	decrBySpec = keySpec("decrby", GroupString, 3, FlagWrite|FlagFast,
+		ArgSpec{Name: "decrement", Type: ArgInteger},
+	).describe("Decrements the integer value of a key by a number")
+	incrByFloatSpec = keySpec("incrbyfloat", GroupString, 3,
+		FlagWrite|FlagFast, ArgSpec{Name: "increment", Type: ArgDouble},
+	).describe("Increments the floating point value of a key by a number")
+)
+
+var (
+	keysSpec = &CommandSpec{
+		Name: "keys", Group: GroupGeneric, Arity: 2, Flags: FlagReadOnly,
+		Summary:    "Returns all key names that match a pattern",
+		Categories: []string{acl.CategoryDangerous},
+		Args:       []ArgSpec{{Name: "pattern", Type: ArgString}},
+	}
+	scanSpec = &CommandSpec{
+		Name: "scan", Group: GroupGeneric, Arity: -2, Flags: FlagReadOnly,
+		Summary: "Iterates over the key names in the database",
+		Args: []ArgSpec{
+			cursorArg, matchArg, countArg,
+			{Name: "type", Type: ArgString, Token: "TYPE", Optional: true},
+		},
+	}
+	typeSpec = keySpec("type", GroupGeneric, 2, FlagReadOnly|FlagFast).
+			describe("Determines the type of value stored at a key")
+
+	expireSpec = keySpec("expire", GroupGeneric, 3, FlagWrite|FlagFast,
+		ArgSpec{Name: "seconds", Type: ArgInteger},
+	).describe("Sets the expiration time of a key in seconds")
+	pexpireSpec = keySpec("pexpire", GroupGeneric, 3, FlagWrite|FlagFast,
+		ArgSpec{Name: "milliseconds", Type: ArgInteger},
+	).describe("Sets the expiration time of a key in milliseconds")
+	expireAtSpec = keySpec("expireat", GroupGeneric, 3, FlagWrite|FlagFast,
+		ArgSpec{Name: "unix-time-seconds", Type: ArgInteger},
+	).describe("Sets the expiration time of a key to a Unix timestamp")
+	pexpireAtSpec = keySpec("pexpireat", GroupGeneric, 3, FlagWrite|FlagFast,
+		ArgSpec{Name: "unix-time-milliseconds", Type: ArgInteger},
+	).describe("Sets the expiration time of a key to a Unix ms timestamp")
+	ttlSpec = keySpec("ttl", GroupGeneric, 2, FlagReadOnly|FlagFast).
+		describe("Returns the expiration time in seconds of a key")
+	pttlSpec = keySpec("pttl", GroupGeneric, 2, FlagReadOnly|FlagFast).
+			describe("Returns the expiration time in milliseconds of a key")
+	persistSpec = keySpec("persist", GroupGeneric, 2, FlagWrite|FlagFast).
+			describe("Removes the expiration time of a key")
+)
+
+var (
+	lpushSpec = keySpec("lpush", GroupList, -3, FlagWrite|FlagFast,
+		elementsArg,
+	).describe("Prepends one or more elements to a list")
+	rpushSpec = keySpec("rpush", GroupList, -3, FlagWrite|FlagFast,
+		elementsArg,
+	).describe("Appends one or more elements to a list")
+	lpopSpec = keySpec("lpop", GroupList, -2, FlagWrite|FlagFast,
+		popCountArg,
+	).describe("Returns and removes the first elements of a list")
+	rpopSpec = keySpec("rpop", GroupList, -2, FlagWrite|FlagFast,
+		popCountArg,
+	).describe("Returns and removes the last elements of a list")
+	llenSpec = keySpec("llen", GroupList, 2, FlagReadOnly|FlagFast).
+			describe("Returns the length of a list")
+	lindexSpec = keySpec("lindex", GroupList, 3, FlagReadOnly,
+		ArgSpec{Name: "index", Type: ArgInteger},
+	).describe("Returns an element from a list by its index")
+	lrangeSpec = keySpec("lrange", GroupList, 4, FlagReadOnly,
+		startArg, stopArg,
+	).describe("Returns a range of elements from a list")
+	ltrimSpec = keySpec("ltrim", GroupList, 4, FlagWrite, startArg, stopArg).
+			describe("Removes elements from both ends of a list")
+)
+
+var (
+	hsetSpec = keySpec("hset", GroupHash, -4, FlagWrite|FlagFast,
+		ArgSpec{
+			Name: "data", Type: ArgBlock, Multiple: true,
+			Args: []ArgSpec{fieldArg, valueArg},
+		},
+	).describe("Creates or modifies the value of fields in a hash")
+	hgetSpec = keySpec("hget", GroupHash, 3, FlagReadOnly|FlagFast, fieldArg).
+			describe("Returns the value of a field in a hash")
+	hdelSpec = keySpec("hdel", GroupHash, -3, FlagWrite|FlagFast, fieldsArg).
+			describe("Deletes one or more fields and their values from a hash")
+	hlenSpec = keySpec("hlen", GroupHash, 2, FlagReadOnly|FlagFast).
+			describe("Returns the number of fields in a hash")
+	hexistsSpec = keySpec("hexists", GroupHash, 3, FlagReadOnly|FlagFast,
+		fieldArg,
+	).describe("Determines whether a field exists in a hash")
+	hgetAllSpec = keySpec("hgetall", GroupHash, 2, FlagReadOnly).
+			describe("Returns all fields and values in a hash")
+	hincrBySpec = keySpec("hincrby", GroupHash, 4, FlagWrite|FlagFast,
+		fieldArg, ArgSpec{Name: "increment", Type: ArgInteger},
+	).describe("Increments the integer value of a field in a hash")
+	hscanSpec = keySpec("hscan", GroupHash, -3, FlagReadOnly,
+		cursorArg, matchArg, countArg,
+		ArgSpec{
+			Name: "novalues", Type: ArgPureToken, Token: "NOVALUES",
+			Optional: true,
+		},
+	).describe("Iterates over the fields and values of a hash")
+)
+
+var (
+	saddSpec = keySpec("sadd", GroupSet, -3, FlagWrite|FlagFast, membersArg).
+			describe("Adds one or more members to a set")
+	sremSpec = keySpec("srem", GroupSet, -3, FlagWrite|FlagFast, membersArg).
+			describe("Removes one or more members from a set")
+	scardSpec = keySpec("scard", GroupSet, 2, FlagReadOnly|FlagFast).
+			describe("Returns the number of members in a set")
+	sisMemberSpec = keySpec("sismember", GroupSet, 3, FlagReadOnly|FlagFast,
+		memberArg,
+	).describe("Determines whether a member belongs to a set")
+	smembersSpec = keySpec("smembers", GroupSet, 2, FlagReadOnly).
+			describe("Returns all members of a set")
+	sinterSpec = multiKeySpec("sinter", GroupSet, -2, FlagReadOnly).
+			describe("Returns the intersect of multiple sets")
+	sunionSpec = multiKeySpec("sunion", GroupSet, -2, FlagReadOnly).
+			describe("Returns the union of multiple sets")
+	sdiffSpec = multiKeySpec("sdiff", GroupSet, -2, FlagReadOnly).
+			describe("Returns the difference of multiple sets")
+	sinterStoreSpec = storeSpec("sinterstore", GroupSet, -3, FlagWrite).
+			describe("Stores the intersect of multiple sets in a key")
+	sunionStoreSpec = storeSpec("sunionstore", GroupSet, -3, FlagWrite).
+			describe("Stores the union of multiple sets in a key")
+	sdiffStoreSpec = storeSpec("sdiffstore", GroupSet, -3, FlagWrite).
+			describe("Stores the difference of multiple sets in a key")
+	sscanSpec = keySpec("sscan", GroupSet, -3, FlagReadOnly,
+		cursorArg, matchArg, countArg,
+	).describe("Iterates over the members of a set")
+)
+
+var (
+	zaddSpec = keySpec("zadd", GroupSortedSet, -4, FlagWrite|FlagFast,
+		ArgSpec{Name: "nx", Type: ArgPureToken, Token: "NX", Optional: true},
+		ArgSpec{Name: "xx", Type: ArgPureToken, Token: "XX", Optional: true},
+		ArgSpec{Name: "gt", Type: ArgPureToken, Token: "GT", Optional: true},
+		ArgSpec{Name: "lt", Type: ArgPureToken, Token: "LT", Optional: true},
+		ArgSpec{
+			Name: "change", Type: ArgPureToken, Token: "CH", Optional: true,
+		},
+		ArgSpec{
+			Name: "increment", Type: ArgPureToken, Token: "INCR",
+			Optional: true,
+		},
+		ArgSpec{
+			Name: "data", Type: ArgBlock, Multiple: true,
+			Args: []ArgSpec{
+				{Name: "score", Type: ArgDouble},
+				memberArg,
+			},
+		},
+	).describe("Adds members to a sorted set, or updates their scores")
+	zincrBySpec = keySpec("zincrby", GroupSortedSet, 4, FlagWrite|FlagFast,
+		ArgSpec{Name: "increment", Type: ArgDouble}, memberArg,
+	).describe("Increments the score of a member in a sorted set")
+	zremSpec = keySpec("zrem", GroupSortedSet, -3, FlagWrite|FlagFast,
+		membersArg,
+	).describe("Removes one or more members from a sorted set")
+	zcardSpec = keySpec("zcard", GroupSortedSet, 2, FlagReadOnly|FlagFast).
+			describe("Returns the number of members in a sorted set")
+	zscoreSpec = keySpec("zscore", GroupSortedSet, 3, FlagReadOnly|FlagFast,
+		memberArg,
+	).describe("Returns the score of a member in a sorted set")
+	zrankSpec = keySpec("zrank", GroupSortedSet, -3, FlagReadOnly|FlagFast,
+		memberArg, withScoreArg,
+	).describe("Returns the index of a member in a sorted set")
+	zrevRankSpec = keySpec("zrevrank", GroupSortedSet, -3,
+		FlagReadOnly|FlagFast, memberArg, withScoreArg,
+	).describe("Returns the reverse index of a member in a sorted set")
+	zcountSpec = keySpec("zcount", GroupSortedSet, 4, FlagReadOnly|FlagFast,
+		minArg, maxArg,
+	).describe("Counts the members in a sorted set within a score range")
+	zrangeSpec = keySpec("zrange", GroupSortedSet, -4, FlagReadOnly,
+		ArgSpec{Name: "start", Type: ArgString},
+		ArgSpec{Name: "stop", Type: ArgString},
+		ArgSpec{
+			Name: "sortby", Type: ArgOneOf, Optional: true,
+			Args: []ArgSpec{
+				{Name: "byscore", Type: ArgPureToken, Token: "BYSCORE"},
+				{Name: "bylex", Type: ArgPureToken, Token: "BYLEX"},
+			},
+		},
+		ArgSpec{
+			Name: "rev", Type: ArgPureToken, Token: "REV", Optional: true,
+		},
+		limitArg, withScoresArg,
+	).describe("Returns members in a sorted set within a range")
+	zrevRangeSpec = keySpec("zrevrange", GroupSortedSet, -4, FlagReadOnly,
+		startArg, stopArg, withScoresArg,
+	).describe("Returns members in a sorted set within a reverse range")
+	zrangeByScoreSpec = keySpec("zrangebyscore", GroupSortedSet, -4,
+		FlagReadOnly, minArg, maxArg, withScoresArg, limitArg,
+	).describe("Returns members in a sorted set within a range of scores")
+	zrevRangeByScoreSpec = keySpec("zrevrangebyscore", GroupSortedSet, -4,
+		FlagReadOnly, maxArg, minArg, withScoresArg, limitArg,
+	).describe("Returns members in a sorted set within a reverse score range")
+	zrangeByLexSpec = keySpec("zrangebylex", GroupSortedSet, -4,
+		FlagReadOnly, minArg, maxArg, limitArg,
+	).describe("Returns members in a sorted set within a lexical range")
+	zrevRangeByLexSpec = keySpec("zrevrangebylex", GroupSortedSet, -4,
+		FlagReadOnly, maxArg, minArg, limitArg,
+	).describe("Returns members in a sorted set in reverse lexical range")
+)
+
+var watchSpec = multiKeySpec("watch", GroupTransaction, -2, FlagFast).
+	describe("Monitors changes to keys to abort a transaction")
+
+var (
+	subscribeSpec = &CommandSpec{
+		Name: "subscribe", Group: GroupPubSub, Arity: -2,
+		Summary: "Listens for messages published to channels",
+		Args: []ArgSpec{
+			{Name: "channel", Type: ArgString, Multiple: true},
+		},
+	}
+	unsubscribeSpec = &CommandSpec{
+		Name: "unsubscribe", Group: GroupPubSub, Arity: -1,
+		Summary: "Stops listening to messages posted to channels",
+		Args: []ArgSpec{
+			{Name: "channel", Type: ArgString, Optional: true,
+				Multiple: true},
+		},
+	}
+	psubscribeSpec = &CommandSpec{
+		Name: "psubscribe", Group: GroupPubSub, Arity: -2,
+		Summary: "Listens for messages published to channels that match " +
+			"one or more patterns",
+		Args: []ArgSpec{
+			{Name: "pattern", Type: ArgString, Multiple: true},
+		},
+	}
+	punsubscribeSpec = &CommandSpec{
+		Name: "punsubscribe", Group: GroupPubSub, Arity: -1,
+		Summary: "Stops listening to messages published to channels that " +
+			"match one or more patterns",
+		Args: []ArgSpec{
+			{Name: "pattern", Type: ArgString, Optional: true,
+				Multiple: true},
+		},
+	}
+	publishSpec = &CommandSpec{
+		Name: "publish", Group: GroupPubSub, Arity: 3,
+		Flags: FlagFast, Summary: "Posts a message to a channel",
+		Args: []ArgSpec{
+			{Name: "channel", Type: ArgString},
+			{Name: "message", Type: ArgString},
+		},
+	}
+	pubSubSpec = &CommandSpec{
+		Name: "pubsub", Group: GroupPubSub, Arity: -2,
+		Summary: "Inspects the state of the Pub/Sub subsystem",
+		Args:    subcommandArgs,
+	}
+	pubSubChannelsSpec = &CommandSpec{
+		Name: "pubsub|channels", Arity: -2,
+		Args: []ArgSpec{
+			{Name: "pattern", Type: ArgString, Optional: true},
+		},
+	}
+	pubSubNumSubSpec = &CommandSpec{
+		Name: "pubsub|numsub", Arity: -2,
+		Args: []ArgSpec{
+			{Name: "channel", Type: ArgString, Optional: true,
+				Multiple: true},
+		},
+	}
+	pubSubNumPatSpec = &CommandSpec{Name: "pubsub|numpat", Arity: 2}
+
+	pingSpec = &CommandSpec{
+		Name: "ping", Group: GroupConnection, Arity: -1,
+		Flags:   FlagFast,
+		Summary: "Returns the server's liveliness response",
+		Args: []ArgSpec{
+			{Name: "message", Type: ArgString, Optional: true},
+		},
+	}
+)
+
+var (
+	authSpec = &CommandSpec{
+		Name: "auth", Group: GroupConnection, Arity: -2,
+		Flags:   FlagFast | FlagNoAuth,
+		Summary: "Authenticates the connection",
+		Args: []ArgSpec{
+			{Name: "username", Type: ArgString, Optional: true},
+			{Name: "password", Type: ArgString},
+		},
+	}
+	aclSpec = &CommandSpec{
+		Name: "acl", Group: GroupServer, Arity: -2,
+		Flags: FlagAdmin, Summary: "Manages users and their permissions",
+		Args: subcommandArgs,
+	}
+	aclSetUserSpec = &CommandSpec{
+		Name: "acl|setuser", Arity: -3,
+		Args: []ArgSpec{
+			{Name: "username", Type: ArgString},
+			{Name: "rule", Type: ArgString, Optional: true, Multiple: true},
+		},
+	}
+	aclGetUserSpec = &CommandSpec{
+		Name: "acl|getuser", Arity: 3,
+		Args: []ArgSpec{{Name: "username", Type: ArgString}},
+	}
+	aclDelUserSpec = &CommandSpec{
+		Name: "acl|deluser", Arity: -3,
+		Args: []ArgSpec{
+			{Name: "username", Type: ArgString, Multiple: true},
+		},
+	}
+	aclListSpec   = &CommandSpec{Name: "acl|list", Arity: 2}
+	aclWhoAmISpec = &CommandSpec{Name: "acl|whoami", Arity: 2}
+	aclLogSpec    = &CommandSpec{
+		Name: "acl|log", Arity: -2,
+		Args: []ArgSpec{
+			{Name: "count", Type: ArgInteger, Optional: true},
+			{Name: "reset", Type: ArgPureToken, Token: "RESET",
+				Optional: true},
+		},
+	}
+)
+
+var (
+	// storageSpecs describe the commands that Storage processes
+	storageSpecs = []*CommandSpec{
+		getSpec, setSpec, delSpec,
+		incrSpec, decrSpec, incrBySpec, decrBySpec, incrByFloatSpec,
+		appendSpec, getSetSpec, setNXSpec,
+
+		keysSpec, scanSpec, typeSpec,
+		expireSpec, pexpireSpec, expireAtSpec, pexpireAtSpec,
+		ttlSpec, pttlSpec, persistSpec,
+
+		lpushSpec, rpushSpec, lpopSpec, rpopSpec,
+		llenSpec, lindexSpec, lrangeSpec, ltrimSpec,
+
+		hsetSpec, hgetSpec, hdelSpec, hlenSpec,
+		hexistsSpec, hgetAllSpec, hincrBySpec, hscanSpec,
+
+		saddSpec, sremSpec, scardSpec, sisMemberSpec, smembersSpec,
+		sinterSpec, sunionSpec, sdiffSpec,
+		sinterStoreSpec, sunionStoreSpec, sdiffStoreSpec, sscanSpec,
+
+		zaddSpec, zincrBySpec, zremSpec, zcardSpec, zscoreSpec,
+		zrankSpec, zrevRankSpec, zcountSpec,
+		zrangeSpec, zrevRangeSpec, zrangeByScoreSpec, zrevRangeByScoreSpec,
+		zrangeByLexSpec, zrevRangeByLexSpec,
+	}
+
+	// transactionSpecs describe the commands that Transactions processes
+	transactionSpecs = []*CommandSpec{
+		{Name: "multi", Group: GroupTransaction, Arity: 1,
+			Flags: FlagFast, Summary: "Starts a transaction",
+		},
+		{Name: "exec", Group: GroupTransaction, Arity: 1,
+			Summary: "Executes all commands in a transaction",
+		},
+		{Name: "discard", Group: GroupTransaction, Arity: 1,
+			Flags: FlagFast, Summary: "Discards a transaction",
+		},
+		watchSpec,
+		{Name: "unwatch", Group: GroupTransaction, Arity: 1,
+			Flags:   FlagFast,
+			Summary: "Forgets about watched keys of a transaction",
+		},
+	}
+
+	// pubSubSpecs describe the commands that PubSub processes
+	pubSubSpecs = []*CommandSpec{
+		subscribeSpec, unsubscribeSpec, psubscribeSpec, punsubscribeSpec,
+		publishSpec, pubSubSpec, pingSpec,
+	}
+
+	// aclSpecs describe the commands that ACL processes
+	aclSpecs = []*CommandSpec{authSpec, aclSpec}
+
+	// introspectionSpecs describe the commands that Introspection processes
+	introspectionSpecs = []*CommandSpec{
+		{Name: "command", Group: GroupServer, Arity: -1,
+			Summary:    "Returns detailed information about commands",
+			Categories: []string{acl.CategoryConnection},
+			Args:       subcommandArgs,
+		},
+	}
+)
+
+// Specs returns the CommandSpecs of the commands that the Handlers of this
+// package process
+func Specs() []*CommandSpec {
+	var res []*CommandSpec
+	for _, s := range [][]*CommandSpec{
+		storageSpecs, transactionSpecs, pubSubSpecs, aclSpecs,
+		introspectionSpecs,
+	} {
+		res = append(res, s...)
+	}
+	return res
+}
+
+// StorageSpecs returns the CommandSpecs of the commands that Storage
+// processes
+func StorageSpecs() []*CommandSpec {
+	return slices.Clone(storageSpecs)
+}
+
+// TransactionSpecs returns the CommandSpecs of the commands that
+// Transactions processes
+func TransactionSpecs() []*CommandSpec {
+	return slices.Clone(transactionSpecs)
+}
+
+// PubSubSpecs returns the CommandSpecs of the commands that PubSub processes
+func PubSubSpecs() []*CommandSpec {
+	return slices.Clone(pubSubSpecs)
+}
+
+// ACLSpecs returns the CommandSpecs of the commands that ACL processes
+func ACLSpecs() []*CommandSpec {
+	return slices.Clone(aclSpecs)
+}
+
+// IntrospectionSpecs returns the CommandSpecs of the commands that
+// Introspection processes
+func IntrospectionSpecs() []*CommandSpec {
+	return slices.Clone(introspectionSpecs)
+}
+
+// keySpec describes a command whose first argument is its only key
+func keySpec(
+	name, group string, arity int, flags Flag, args ...ArgSpec,
+) *CommandSpec {
+	return &CommandSpec{
+		Name:     name,
+		Group:    group,
+		Arity:    arity,
+		Flags:    flags,
+		FirstKey: 1,
+		LastKey:  1,
+		Step:     1,
+		Args:     append([]ArgSpec{keyArg}, args...),
+	}
+}
+
+// multiKeySpec describes a command whose arguments are all keys
+func multiKeySpec(name, group string, arity int, flags Flag) *CommandSpec {
+	return &CommandSpec{
+		Name:     name,
+		Group:    group,
+		Arity:    arity,
+		Flags:    flags,
+		FirstKey: 1,
+		LastKey:  -1,
+		Step:     1,
+		Args:     []ArgSpec{keysArg},
+	}
+}
+
+// storeSpec describes a command that stores the result of reading its source
+// keys in a destination key
+func storeSpec(name, group string, arity int, flags Flag) *CommandSpec {
+	res := multiKeySpec(name, group, arity, flags)
+	res.Args = []ArgSpec{
+		{Name: "destination", Type: ArgKey},
+		keysArg,
+	}
+	return res
+}
+
+func (s *CommandSpec) describe(summary string) *CommandSpec {
+	s.Summary = summary
+	return s
+}
@@ -1,81 +1,215 @@
 package command
 
 import (
+	"errors"
+	"time"
+
 	"github.com/kode4food/respect/pkg/resp"
 	"github.com/kode4food/respect/pkg/storage"
 )
 
-type storageOp func(storage.Storage, ...resp.Value) (resp.Value, error)
+type (
+	// argsOp is a storage operation whose arguments have been parsed
+	// according to its CommandSpec
+	argsOp func(storage.Storage, *Args) (resp.Value, error)
 
-const (
-	// ErrWrongArgumentCount is returned upon the wrong number of arguments
-	ErrWrongArgumentCount = "wrong number of arguments: %d"
+	// protocolOp is an argsOp whose result depends on the version of the
+	// RESP protocol spoken by the client
+	protocolOp func(storage.Storage, int, *Args) (resp.Value, error)
 )
 
 func Storage(s storage.Storage) Handler {
-	h := storageHandlers(s)
-	return NewHandler(h)
+	return StorageWrap(s, NoHandler)
 }
 
 func StorageWrap(s storage.Storage, next Handler) Handler {
-	h := storageHandlers(s)
-	return Wrap(h, next)
+	return atomically(s, func(s storage.Storage) Handler {
+		return Wrap(storageHandlers(s), next, storageSpecs...)
+	})
 }
 
 func storageHandlers(s storage.Storage) Handlers {
 	return Handlers{
-		"GET": wrapStorageOp(s, getOp),
-		"SET": wrapStorageOp(s, setOp),
-		"DEL": wrapStorageOp(s, deleteOp),
+		"GET": wrapArgsOp(s, getSpec, getOp),
+		"SET": wrapArgsOp(s, setSpec, setOp),
+		"DEL": wrapArgsOp(s, delSpec, deleteOp),
+
+		"INCR":        wrapArgsOp(s, incrSpec, incrementOp(1)),
+		"DECR":        wrapArgsOp(s, decrSpec, incrementOp(-1)),
+		"INCRBY":      wrapArgsOp(s, incrBySpec, incrementByOp(1)),
+		"DECRBY":      wrapArgsOp(s, decrBySpec, incrementByOp(-1)),
+		"INCRBYFLOAT": wrapArgsOp(s, incrByFloatSpec, incrementByFloatOp),
+		"APPEND":      wrapArgsOp(s, appendSpec, appendOp),
+		"GETSET":      wrapArgsOp(s, getSetSpec, getSetOp),
+		"SETNX":       wrapArgsOp(s, setNXSpec, setIfNotExistsOp),
+
+		"KEYS": wrapArgsOp(s, keysSpec, keysOp),
+		"SCAN": wrapArgsOp(s, scanSpec, scanOp),
+		"TYPE": wrapArgsOp(s, typeSpec, typeOp),
+
+		"LPUSH":  wrapArgsOp(s, lpushSpec, pushOp((*storage.List).PushFront)),
+		"RPUSH":  wrapArgsOp(s, rpushSpec, pushOp((*storage.List).PushBack)),
+		"LPOP":   wrapArgsOp(s, lpopSpec, popOp((*storage.List).PopFront)),
+		"RPOP":   wrapArgsOp(s, rpopSpec, popOp((*storage.List).PopBack)),
+		"LLEN":   wrapArgsOp(s, llenSpec, listLenOp),
+		"LINDEX": wrapArgsOp(s, lindexSpec, listIndexOp),
+		"LRANGE": wrapArgsOp(s, lrangeSpec, listRangeOp),
+		"LTRIM":  wrapArgsOp(s, ltrimSpec, listTrimOp),
+
+		"HSET":    pairedFields(wrapArgsOp(s, hsetSpec, hashSetOp)),
+		"HGET":    wrapArgsOp(s, hgetSpec, hashGetOp),
+		"HDEL":    wrapArgsOp(s, hdelSpec, hashDeleteOp),
+		"HLEN":    wrapArgsOp(s, hlenSpec, hashLenOp),
+		"HEXISTS": wrapArgsOp(s, hexistsSpec, hashExistsOp),
+		"HGETALL": wrapProtocolOp(s, hgetAllSpec, hashGetAllOp),
+		"HINCRBY": wrapArgsOp(s, hincrBySpec, hashIncrementOp),
+		"HSCAN":   wrapArgsOp(s, hscanSpec, hashScanOp),
+
+		"SADD":      wrapArgsOp(s, saddSpec, setAddOp),
+		"SREM":      wrapArgsOp(s, sremSpec, setRemoveOp),
+		"SCARD":     wrapArgsOp(s, scardSpec, setCardOp),
+		"SISMEMBER": wrapArgsOp(s, sisMemberSpec, setIsMemberOp),
+		"SMEMBERS":  wrapProtocolOp(s, smembersSpec, setMembersOp),
+		"SINTER": wrapProtocolOp(s, sinterSpec,
+			setCombineOp(storage.Intersect),
+		),
+		"SUNION": wrapProtocolOp(s, sunionSpec, setCombineOp(storage.Union)),
+		"SDIFF":  wrapProtocolOp(s, sdiffSpec, setCombineOp(setDifference)),
+		"SINTERSTORE": wrapArgsOp(s, sinterStoreSpec,
+			setStoreOp(storage.Intersect),
+		),
+		"SUNIONSTORE": wrapArgsOp(s, sunionStoreSpec,
+			setStoreOp(storage.Union),
+		),
+		"SDIFFSTORE": wrapArgsOp(s, sdiffStoreSpec,
+			setStoreOp(setDifference),
+		),
+		"SSCAN": wrapArgsOp(s, sscanSpec, setScanOp),
+
+		"ZADD":     wrapProtocolOp(s, zaddSpec, zaddOp),
+		"ZINCRBY":  wrapProtocolOp(s, zincrBySpec, zincrbyOp),
+		"ZREM":     wrapArgsOp(s, zremSpec, zremOp),
+		"ZCARD":    wrapArgsOp(s, zcardSpec, zcardOp),
+		"ZSCORE":   wrapProtocolOp(s, zscoreSpec, zscoreOp),
+		"ZRANK":    wrapProtocolOp(s, zrankSpec, zrankOp(false)),
+		"ZREVRANK": wrapProtocolOp(s, zrevRankSpec, zrankOp(true)),
+		"ZCOUNT":   wrapArgsOp(s, zcountSpec, zcountOp),
+		"ZRANGE":   wrapProtocolOp(s, zrangeSpec, zrangeOp),
+		"ZREVRANGE": wrapProtocolOp(s, zrevRangeSpec,
+			zrangeAlias("", true),
+		),
+		"ZRANGEBYSCORE": wrapProtocolOp(s, zrangeByScoreSpec,
+			zrangeAlias(byScore, false),
+		),
+		"ZREVRANGEBYSCORE": wrapProtocolOp(s, zrevRangeByScoreSpec,
+			zrangeAlias(byScore, true),
+		),
+		"ZRANGEBYLEX": wrapProtocolOp(s, zrangeByLexSpec,
+			zrangeAlias(byLex, false),
+		),
+		"ZREVRANGEBYLEX": wrapProtocolOp(s, zrevRangeByLexSpec,
+			zrangeAlias(byLex, true),
+		),
+
+		"EXPIRE":   wrapArgsOp(s, expireSpec, expireOp("seconds")),
+		"PEXPIRE":  wrapArgsOp(s, pexpireSpec, expireOp("milliseconds")),
+		"EXPIREAT": wrapArgsOp(s, expireAtSpec, expireOp("unix-time-seconds")),
+		"PEXPIREAT": wrapArgsOp(s, pexpireAtSpec,
+			expireOp("unix-time-milliseconds"),
+		),
+		"TTL":     wrapArgsOp(s, ttlSpec, ttlOp(time.Second)),
+		"PTTL":    wrapArgsOp(s, pttlSpec, ttlOp(time.Millisecond)),
+		"PERSIST": wrapArgsOp(s, persistSpec, persistOp),
 	}
 }
 
-func getOp(s storage.Storage, args ...resp.Value) (resp.Value, error) {
-	if len(args) != 1 {
-		return nil, resp.MakeError(ErrWrongArgumentCount, 1)
+func getOp(s storage.Storage, a *Args) (resp.Value, error) {
+	res, err := s.Get(a.Key("key"))
+	if errors.Is(err, storage.KeyNotFound) {
+		return resp.NullValue, nil
 	}
-	key, err := storage.AsKey(args[0])
-	if err != nil {
-		return nil, err
-	}
-	return s.Get(key)
+	return res, err
 }
 
-func setOp(s storage.Storage, args ...resp.Value) (resp.Value, error) {
-	if len(args) != 2 {
-		return nil, resp.MakeError(ErrWrongArgumentCount, 2)
-	}
-	key, err := storage.AsKey(args[0])
+func setOp(s storage.Storage, a *Args) (resp.Value, error) {
+	opts, err := setOptions(a)
 	if err != nil {
 		return nil, err
 	}
-	if _, err = s.Set(key, args[1]); err != nil {
+	get := a.Has("get")
+	opts.ValuesOnly = get
+	old, ok, err := s.SetWith(a.Key("key"), a.Value("value"), opts)
+	switch {
+	case err != nil:
 		return nil, err
+	case get && old != nil:
+		return old, nil
+	case get || !ok:
+		return resp.NullValue, nil
+	default:
+		return resp.OK, nil
 	}
-	return resp.OK, nil
 }
 
-func deleteOp(s storage.Storage, args ...resp.Value) (resp.Value, error) {
-	if len(args) != 1 {
-		return nil, resp.MakeError(ErrWrongArgumentCount, 1)
+func setOptions(a *Args) (storage.SetOptions, error) {
+	var opts storage.SetOptions
+	switch a.Choice("condition") {
+	case "nx":
+		opts.Condition = storage.IfNotExists
+	case "xx":
+		opts.Condition = storage.IfExists
 	}
-	key, err := storage.AsKey(args[0])
-	if err != nil {
-		return nil, err
+	switch exp := a.Choice("expiration"); exp {
+	case "":
+	case "keepttl":
+		opts.KeepDeadline = true
+	default:
+		n := a.Int(exp)
+		if n <= 0 {
+			return opts, resp.MakeError(ErrInvalidExpireTime)
+		}
+		d, err := setDeadlines[exp](resp.Integer(n))
+		if err != nil {
+			return opts, err
+		}
+		opts.Deadline = d
 	}
-	if _, err = s.Delete(key); err != nil {
+	return opts, nil
+}
+
+func deleteOp(s storage.Storage, a *Args) (resp.Value, error) {
+	if _, err := s.Delete(a.Key("key")); err != nil {
 		return nil, err
 	}
 	return resp.OK, nil
 }
 
-func wrapStorageOp(s storage.Storage, op storageOp) Handler {
+func wrapArgsOp(s storage.Storage, spec *CommandSpec, op argsOp) Handler {
 	return func(r Responder, args ...resp.Value) error {
-		value, err := op(s, args...)
+		a, err := spec.Parse(args...)
+		if err != nil {
+			return err
+		}
+		value, err := op(s, a)
+		if err != nil {
+			return err
+		}
+		return Emit(r, value)
+	}
+}
+
+func wrapProtocolOp(
+	s storage.Storage, spec *CommandSpec, op protocolOp,
+) Handler {
+	return func(r Responder, args ...resp.Value) error {
+		a, err := spec.Parse(args...)
+		if err != nil {
+			return err
+		}
+		value, err := op(s, ProtocolOf(r), a)
 		if err != nil {
-			return resp.MakeError(err.Error())
+			return err
 		}
-		r.Emit() <- value
-		return nil
+		return Emit(r, value)
 	}
 }
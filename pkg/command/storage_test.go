@@ -0,0 +1,106 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type testResponder struct {
+	output  chan resp.Value
+	closed  chan struct{}
+	session *command.Session
+}
+
+func newTestResponder() *testResponder {
+	return &testResponder{
+		output:  make(chan resp.Value, 16),
+		closed:  make(chan struct{}),
+		session: command.NewSession(),
+	}
+}
+
+func (r *testResponder) Session() *command.Session {
+	return r.session
+}
+
+func (r *testResponder) Emit() chan<- resp.Value {
+	return r.output
+}
+
+func (r *testResponder) Closed() <-chan struct{} {
+	return r.closed
+}
+
+func makeCommand(args ...string) []resp.Value {
+	res := make([]resp.Value, len(args))
+	for i, a := range args {
+		res[i] = resp.BulkString(a)
+	}
+	return res
+}
+
+func testCommands(t *testing.T, h command.Handler, cases [][2]any) {
+	as := assert.New(t)
+	r := newTestResponder()
+	for _, tc := range cases {
+		args := makeCommand(tc[0].([]string)...)
+		err := h(r, args...)
+		switch expected := tc[1].(type) {
+		case error:
+			as.ErrorContains(err, expected.Error(), tc[0])
+		case resp.Value:
+			as.Nil(err, tc[0])
+			as.Equal(expected, <-r.output, tc[0])
+		}
+	}
+}
+
+func TestStorageSet(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"GET", "key"}, resp.NullValue},
+		{[]string{"SET", "key", "first", "XX"}, resp.NullValue},
+		{[]string{"SET", "key", "first", "NX"}, resp.OK},
+		{[]string{"SET", "key", "second", "NX"}, resp.NullValue},
+		{[]string{"SET", "key", "second", "xx", "get"}, resp.BulkString("first")},
+		{[]string{"SET", "other", "value", "GET"}, resp.NullValue},
+		{[]string{"GET", "key"}, resp.BulkString("second")},
+		{[]string{"SET", "key", "value", "NX", "XX"}, resp.MakeError(command.ErrSyntax)},
+		{[]string{"SET", "key", "value", "EX"}, resp.MakeError(command.ErrSyntax)},
+		{[]string{"SET", "key", "value", "EX", "0"}, resp.MakeError(command.ErrInvalidExpireTime)},
+		{[]string{"SET", "key", "value", "EX", "ten"}, resp.MakeError(command.ErrNotInteger)},
+		{[]string{"SET", "key", "value", "EX", "10", "KEEPTTL"}, resp.MakeError(command.ErrSyntax)},
+	})
+}
+
+func TestStorageExpiry(t *testing.T) {
+	h := command.Storage(storage.NewMemory())
+	testCommands(t, h, [][2]any{
+		{[]string{"TTL", "key"}, resp.Integer(-2)},
+		{[]string{"PERSIST", "key"}, resp.Integer(0)},
+		{[]string{"EXPIRE", "key", "10"}, resp.Integer(0)},
+		{[]string{"SET", "key", "value"}, resp.OK},
+		{[]string{"TTL", "key"}, resp.Integer(-1)},
+		{[]string{"EXPIRE", "key", "10"}, resp.Integer(1)},
+		{[]string{"TTL", "key"}, resp.Integer(10)},
+		{[]string{"PEXPIRE", "key", "20000"}, resp.Integer(1)},
+		{[]string{"TTL", "key"}, resp.Integer(20)},
+		{[]string{"SET", "key", "updated", "KEEPTTL"}, resp.OK},
+		{[]string{"TTL", "key"}, resp.Integer(20)},
+		{[]string{"PERSIST", "key"}, resp.Integer(1)},
+		{[]string{"PERSIST", "key"}, resp.Integer(0)},
+		{[]string{"PTTL", "key"}, resp.Integer(-1)},
+		{[]string{"SET", "key", "value", "PX", "30000"}, resp.OK},
+		{[]string{"TTL", "key"}, resp.Integer(30)},
+		{[]string{"SET", "key", "value"}, resp.OK},
+		{[]string{"TTL", "key"}, resp.Integer(-1)},
+		{[]string{"EXPIREAT", "key", "1"}, resp.Integer(1)},
+		{[]string{"GET", "key"}, resp.NullValue},
+		{[]string{"TTL", "key"}, resp.Integer(-2)},
+		{[]string{"EXPIRE", "key", "NaN"}, resp.MakeError(command.ErrNotInteger)},
+	})
+}
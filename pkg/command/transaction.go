@@ -0,0 +1,383 @@
+package command
+
+import (
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+)
+
+type (
+	// TransactionOption configures the Middleware that Transactions creates
+	TransactionOption func(*queueing)
+
+	// queueing holds what commands are validated against as they're queued
+	queueing struct {
+		specs specTable
+		acl   *acl.ACL
+	}
+
+	// transaction is the part of a Session that tracks MULTI and WATCH
+	transaction struct {
+		queued  [][]resp.Value
+		watched []watchedKey
+		exec    *execution
+		active  bool
+		aborted bool
+	}
+
+	// execution is the part of a transaction that's in effect while EXEC
+	// replays its queued commands. Handlers constructed around its Storage
+	// are reconstructed around the view of it that Atomic provides
+	execution struct {
+		store    storage.Storage
+		view     storage.Storage
+		handlers map[*Handler]Handler
+	}
+
+	watchedKey struct {
+		key      storage.Key
+		revision uint64
+		exists   bool
+	}
+
+	// handlerMaker constructs a Handler around a Storage
+	handlerMaker func(storage.Storage) Handler
+
+	// capture is the Responder that queued commands emit to when they're
+	// executed by EXEC. Values are collected rather than sent, as EXEC holds
+	// exclusive access to the Storage until every command has completed
+	capture struct {
+		Responder
+		output []resp.Value
+	}
+)
+
+// Error messages
+const (
+	ErrNestedMulti         = "ERR MULTI calls can not be nested"
+	ErrExecWithoutMulti    = "ERR EXEC without MULTI"
+	ErrDiscardWithoutMulti = "ERR DISCARD without MULTI"
+	ErrWatchInMulti        = "ERR WATCH inside MULTI is not allowed"
+	ErrExecAborted         = "EXECABORT Transaction discarded because of " +
+		"previous errors."
+)
+
+// Queued is the reply to a command that's been queued by MULTI
+const Queued = resp.SimpleString("QUEUED")
+
+// compile-time checks for interface implementation
+var (
+	_ ValueWriter  = (*capture)(nil)
+	_ StreamWriter = (*capture)(nil)
+	_ Described    = (*capture)(nil)
+)
+
+// controlVerbs are processed immediately, even while commands are queued
+var controlVerbs = map[resp.BulkString]bool{
+	"MULTI":   true,
+	"EXEC":    true,
+	"DISCARD": true,
+	"WATCH":   true,
+	"UNWATCH": true,
+}
+
+// Transactions creates Middleware that queues commands after MULTI and
+// executes them atomically with EXEC. It should wrap the whole chain of
+// Handlers, so that every command other than those that control the
+// transaction is queued. EXEC replays the queued commands through the
+// Handler that the Middleware wraps, while holding exclusive access to the
+// Storage.
+//
+// Only the commands described by the CommandSpecs provided by WithSpecs are
+// queued, and any other command aborts the transaction. Their Handlers can
+// only access the Storage by way of StorageWrap, as anything else would
+// wait on the exclusive access that EXEC holds
+func Transactions(s storage.Storage, opts ...TransactionOption) Middleware {
+	q := &queueing{specs: specTable{}}
+	for _, o := range opts {
+		o(q)
+	}
+	return func(next Handler) Handler {
+		h := Wrap(Handlers{
+			"MULTI":   multiOp,
+			"EXEC":    execOp(s, next),
+			"DISCARD": discardOp,
+			"WATCH":   watchOp(s),
+			"UNWATCH": unwatchOp,
+		}, next, transactionSpecs...)
+		return func(r Responder, args ...resp.Value) error {
+			ss := SessionOf(r)
+			if ss == nil || !ss.tx.active || isControl(args) {
+				return h(r, args...)
+			}
+			return ss.tx.enqueue(r, q, args)
+		}
+	}
+}
+
+// WithSpecs provides the CommandSpecs of the commands that Transactions
+// queues
+func WithSpecs(specs ...*CommandSpec) TransactionOption {
+	return func(q *queueing) {
+		for verb, s := range specTableOf(specs) {
+			q.specs[verb] = s
+		}
+	}
+}
+
+// WithACL checks commands against an ACL as they're queued, so that a
+// transaction that includes a command its user can't run is aborted
+func WithACL(a *acl.ACL) TransactionOption {
+	return func(q *queueing) {
+		q.acl = a
+	}
+}
+
+// atomically constructs a Handler around a Storage. While EXEC replays a
+// transaction against that Storage, commands are instead dispatched to a
+// Handler constructed around the view of it that Atomic provides
+func atomically(s storage.Storage, mk handlerMaker) Handler {
+	h := mk(s)
+	self := &h // identifies the reconstructed Handler within an execution
+	return func(r Responder, args ...resp.Value) error {
+		if e := executionOf(r); e != nil && e.store == s {
+			return e.handler(self, mk)(r, args...)
+		}
+		return h(r, args...)
+	}
+}
+
+func multiOp(r Responder, _ ...resp.Value) error {
+	ss, err := sessionOf(r)
+	if err != nil {
+		return err
+	}
+	if ss.tx.active {
+		return resp.MakeError(ErrNestedMulti)
+	}
+	ss.tx.active = true
+	return Emit(r, resp.OK)
+}
+
+func discardOp(r Responder, _ ...resp.Value) error {
+	ss, err := sessionOf(r)
+	if err != nil {
+		return err
+	}
+	if !ss.tx.active {
+		return resp.MakeError(ErrDiscardWithoutMulti)
+	}
+	ss.tx = transaction{}
+	return Emit(r, resp.OK)
+}
+
+func watchOp(s storage.Storage) Handler {
+	return wrapArgsHandler(watchSpec, func(r Responder, a *Args) error {
+		ss, err := sessionOf(r)
+		if err != nil {
+			return err
+		}
+		if ss.tx.active {
+			return resp.MakeError(ErrWatchInMulti)
+		}
+		for _, k := range a.Keys("key") {
+			rev, ok, err := s.Revision(k)
+			if err != nil {
+				return err
+			}
+			ss.tx.watched = append(ss.tx.watched, watchedKey{
+				key:      k,
+				revision: rev,
+				exists:   ok,
+			})
+		}
+		return Emit(r, resp.OK)
+	})
+}
+
+func unwatchOp(r Responder, _ ...resp.Value) error {
+	ss, err := sessionOf(r)
+	if err != nil {
+		return err
+	}
+	ss.tx.watched = nil
+	return Emit(r, resp.OK)
+}
+
+func execOp(s storage.Storage, next Handler) Handler {
+	return func(r Responder, _ ...resp.Value) error {
+		ss, err := sessionOf(r)
+		if err != nil {
+			return err
+		}
+		tx := ss.tx
+		if !tx.active {
+			return resp.MakeError(ErrExecWithoutMulti)
+		}
+		ss.tx = transaction{}
+		if tx.aborted {
+			return resp.MakeError(ErrExecAborted)
+		}
+
+		var res resp.Value = resp.NullValue
+		err = s.Atomic(func(view storage.Storage) error {
+			if ok, err := tx.unchanged(view); err != nil || !ok {
+				return err
+			}
+			ss.tx.exec = &execution{
+				store:    s,
+				view:     view,
+				handlers: map[*Handler]Handler{},
+			}
+			defer func() { ss.tx.exec = nil }()
+			c := &capture{Responder: r}
+			out := make([]resp.Value, len(tx.queued))
+			for i, args := range tx.queued {
+				out[i] = c.run(next, args)
+			}
+			res = resp.MakeArray(out...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return Emit(r, res)
+	}
+}
+
+// enqueue queues a command once its arguments have been validated against
+// the CommandSpec of its verb and its user's permissions have been checked.
+// A command without a CommandSpec is refused, as nothing is known of how
+// its Handler accesses the Storage
+func (t *transaction) enqueue(
+	r Responder, q *queueing, args []resp.Value,
+) error {
+	switch {
+	case len(args) == 0:
+		t.aborted = true
+		return resp.MakeError(ErrEmptyCommand)
+	case args[0].Tag() != resp.BulkStringTag:
+		t.aborted = true
+		return resp.MakeError(ErrExpectedBulkString)
+	}
+	s, ok := q.specs[normalizeVerb(args[0].(resp.BulkString))]
+	if !ok {
+		t.aborted = true
+		return resp.MakeError(ErrUnknownCommand, args[0])
+	}
+	if err := s.CheckArity(args); err != nil {
+		t.aborted = true
+		return err
+	}
+	if q.acl != nil {
+		if err := checkACL(q.acl, q.specs, r, args); err != nil {
+			t.aborted = true
+			return err
+		}
+	}
+	t.queued = append(t.queued, args)
+	return Emit(r, Queued)
+}
+
+// unchanged reports whether none of the watched Keys have been modified
+func (t *transaction) unchanged(s storage.Storage) (bool, error) {
+	for _, w := range t.watched {
+		rev, ok, err := s.Revision(w.key)
+		if err != nil {
+			return false, err
+		}
+		if rev != w.revision || ok != w.exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// handler returns the Handler that a call to atomically constructed around
+// the transaction's view of its Storage, constructing it if necessary
+func (e *execution) handler(key *Handler, mk handlerMaker) Handler {
+	if h, ok := e.handlers[key]; ok {
+		return h
+	}
+	h := mk(e.view)
+	e.handlers[key] = h
+	return h
+}
+
+// run executes a queued command, returning what it emitted or the error it
+// produced
+func (c *capture) run(h Handler, args []resp.Value) resp.Value {
+	c.output = nil
+	err := h(c, args...)
+	res := c.output
+	switch {
+	case err != nil:
+		return asErrorValue(err)
+	case len(res) == 1:
+		return res[0]
+	default:
+		return resp.MakeArray(res...)
+	}
+}
+
+// Emit returns nil, as values emitted using command.Emit are written using
+// WriteValue instead
+func (c *capture) Emit() chan<- resp.Value {
+	return nil
+}
+
+// WriteValue implements ValueWriter
+func (c *capture) WriteValue(v resp.Value) error {
+	c.output = append(c.output, v)
+	return nil
+}
+
+// Stream implements StreamWriter, collecting the streamed reply
+func (c *capture) Stream(fn func(*resp.Writer) error) error {
+	v, err := Collect(fn)
+	if err != nil {
+		return err
+	}
+	return c.WriteValue(v)
+}
+
+func (c *capture) Protocol() int {
+	return ProtocolOf(c.Responder)
+}
+
+func (c *capture) Session() *Session {
+	return SessionOf(c.Responder)
+}
+
+func (c *capture) CommandSpecs() []*CommandSpec {
+	if d, ok := c.Responder.(Described); ok {
+		return d.CommandSpecs()
+	}
+	return nil
+}
+
+// executionOf returns the execution of the transaction that EXEC is
+// replaying for a Responder's Session, if any
+func executionOf(r Responder) *execution {
+	if ss := SessionOf(r); ss != nil {
+		return ss.tx.exec
+	}
+	return nil
+}
+
+func isControl(args []resp.Value) bool {
+	if len(args) == 0 {
+		return false
+	}
+	verb, ok := args[0].(resp.BulkString)
+	return ok && controlVerbs[normalizeVerb(verb)]
+}
+
+// asErrorValue converts an error into the resp.Error that a client would
+// receive in its place
+func asErrorValue(err error) resp.Error {
+	if e, ok := err.(resp.Error); ok {
+		return e
+	}
+	return resp.MakeError("%s", err.Error())
+}
@@ -0,0 +1,222 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/pubsub"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type sessionlessResponder struct {
+	output chan resp.Value
+	closed chan struct{}
+}
+
+func (r *sessionlessResponder) Emit() chan<- resp.Value {
+	return r.output
+}
+
+func (r *sessionlessResponder) Closed() <-chan struct{} {
+	return r.closed
+}
+
+func newTransactional() command.Handler {
+	s := storage.NewMemory()
+	return command.Use(
+		command.Storage(s),
+		command.Transactions(s, command.WithSpecs(command.StorageSpecs()...)),
+	)
+}
+
+func TestMultiExec(t *testing.T) {
+	h := newTransactional()
+	testCommands(t, h, [][2]any{
+		{[]string{"EXEC"}, resp.MakeError(command.ErrExecWithoutMulti)},
+		{[]string{"DISCARD"}, resp.MakeError(command.ErrDiscardWithoutMulti)},
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"MULTI"}, resp.MakeError(command.ErrNestedMulti)},
+		{[]string{"SET", "a", "1"}, command.Queued},
+		{[]string{"INCR", "a"}, command.Queued},
+		{[]string{"GET", "a"}, command.Queued},
+		{[]string{"EXEC"}, resp.MakeArray(
+			resp.OK, resp.Integer(2), resp.BulkString("2"),
+		)},
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"SET", "a", "3"}, command.Queued},
+		{[]string{"DISCARD"}, resp.OK},
+		{[]string{"GET", "a"}, resp.BulkString("2")},
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"SET", "s", "x"}, command.Queued},
+		{[]string{"INCR", "s"}, command.Queued},
+		{[]string{"EXEC"}, resp.MakeArray(
+			resp.OK, resp.MakeError(command.ErrNotInteger),
+		)},
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"SET", "s", "y"}, command.Queued},
+		{[]string{"UNKNOWN"}, resp.MakeError(
+			command.ErrUnknownCommand, "UNKNOWN",
+		)},
+		{[]string{"EXEC"}, resp.MakeError(command.ErrExecAborted)},
+		{[]string{"GET", "s"}, resp.BulkString("x")},
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"EXEC"}, resp.EmptyArray},
+	})
+}
+
+func TestMultiAborted(t *testing.T) {
+	as := assert.New(t)
+	h := newTransactional()
+	r := newTestResponder()
+	as.Nil(h(r, makeCommand("MULTI")...))
+	as.Equal(resp.OK, <-r.output)
+	as.Nil(h(r, makeCommand("SET", "a", "1")...))
+	as.Equal(command.Queued, <-r.output)
+	as.ErrorContains(
+		h(r, resp.Integer(1)), command.ErrExpectedBulkString,
+	)
+	as.ErrorContains(h(r, makeCommand("EXEC")...), command.ErrExecAborted)
+
+	as.Nil(h(r, makeCommand("GET", "a")...))
+	as.Equal(resp.NullValue, <-r.output)
+}
+
+func TestWatch(t *testing.T) {
+	as := assert.New(t)
+	h := newTransactional()
+	r1 := newTestResponder()
+	r2 := newTestResponder()
+	run := func(r *testResponder, args ...string) resp.Value {
+		as.Nil(h(r, makeCommand(args...)...))
+		return <-r.output
+	}
+
+	as.Equal(resp.OK, run(r1, "WATCH", "a", "b"))
+	as.Equal(resp.OK, run(r2, "SET", "b", "changed"))
+	as.Equal(resp.OK, run(r1, "MULTI"))
+	as.Equal(command.Queued, run(r1, "SET", "a", "1"))
+	as.Equal(resp.NullValue, run(r1, "EXEC"))
+	as.Equal(resp.NullValue, run(r1, "GET", "a"))
+
+	as.Equal(resp.OK, run(r1, "WATCH", "a", "b"))
+	as.Equal(resp.OK, run(r1, "MULTI"))
+	as.ErrorContains(
+		h(r1, makeCommand("WATCH", "c")...), command.ErrWatchInMulti,
+	)
+	as.Equal(command.Queued, run(r1, "SET", "a", "1"))
+	as.Equal(resp.MakeArray(resp.OK), run(r1, "EXEC"))
+
+	as.Equal(resp.OK, run(r1, "WATCH", "a"))
+	as.Equal(resp.Integer(2), run(r2, "INCR", "a"))
+	as.Equal(resp.OK, run(r1, "UNWATCH"))
+	as.Equal(resp.OK, run(r1, "MULTI"))
+	as.Equal(command.Queued, run(r1, "INCR", "a"))
+	as.Equal(resp.MakeArray(resp.Integer(3)), run(r1, "EXEC"))
+
+	as.Equal(resp.OK, run(r1, "WATCH", "missing"))
+	as.Equal(resp.OK, run(r2, "SET", "missing", "value"))
+	as.Equal(resp.OK, run(r2, "DEL", "missing"))
+	as.Equal(resp.OK, run(r1, "MULTI"))
+	as.Equal(resp.NullValue, run(r1, "EXEC"))
+
+	as.Equal(resp.OK, run(r1, "WATCH", "missing"))
+	as.Equal(resp.OK, run(r1, "MULTI"))
+	as.Equal(resp.EmptyArray, run(r1, "EXEC"))
+}
+
+func TestMultiWithoutSession(t *testing.T) {
+	as := assert.New(t)
+	h := newTransactional()
+	r := &sessionlessResponder{
+		output: make(chan resp.Value, 1),
+		closed: make(chan struct{}),
+	}
+	as.ErrorContains(h(r, makeCommand("MULTI")...), command.ErrNoSession)
+	as.Nil(h(r, makeCommand("SET", "a", "1")...))
+	as.Equal(resp.OK, <-r.output)
+}
+
+func TestExecCapture(t *testing.T) {
+	as := assert.New(t)
+	many := make([]resp.Value, 32)
+	for i := range many {
+		many[i] = resp.Integer(i)
+	}
+	s := storage.NewMemory()
+	h := command.Use(command.StorageWrap(s, command.NewHandler(
+		command.Handlers{
+			"MANY": func(r command.Responder, _ ...resp.Value) error {
+				for _, v := range many {
+					if err := command.Emit(r, v); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			"STREAMED": func(r command.Responder, _ ...resp.Value) error {
+				return command.Stream(r, func(w *resp.Writer) error {
+					if err := w.WriteArrayHeader(1); err != nil {
+						return err
+					}
+					return w.WriteString("streamed")
+				})
+			},
+		},
+	)), command.Transactions(s, command.WithSpecs(
+		&command.CommandSpec{Name: "many", Arity: 1},
+		&command.CommandSpec{Name: "streamed", Arity: 1},
+	)))
+	r := newTestResponder()
+	for _, verb := range []string{"MULTI", "MANY", "STREAMED"} {
+		as.Nil(h(r, makeCommand(verb)...))
+		<-r.output
+	}
+	as.Nil(h(r, makeCommand("EXEC")...))
+	as.Equal(resp.MakeArray(
+		resp.MakeArray(many...),
+		resp.MakeArray(resp.BulkString("streamed")),
+	), <-r.output)
+}
+
+func TestMultiQueuesEverything(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	b := pubsub.NewBroker()
+	specs := command.Specs()
+	h := command.Use(
+		command.ACLWrap(acl.NewACL(), command.IntrospectionWrap(
+			command.PubSubWrap(b, command.Storage(s)), specs...,
+		), specs...),
+		command.Transactions(s, command.WithSpecs(specs...)),
+	)
+	r := newTestResponder()
+	testCommands(t, h, [][2]any{
+		{[]string{"MULTI"}, resp.OK},
+		{[]string{"PUBLISH", "ch", "m"}, command.Queued},
+		{[]string{"SET", "k", "v"}, command.Queued},
+		{[]string{"PING"}, command.Queued},
+		{[]string{"ACL", "WHOAMI"}, command.Queued},
+		{[]string{"EXEC"}, resp.MakeArray(
+			resp.Integer(0), resp.OK, resp.SimpleString("PONG"),
+			resp.BulkString("default"),
+		)},
+	})
+
+	as.Nil(h(r, makeCommand("MULTI")...))
+	as.Equal(resp.OK, <-r.output)
+	as.Nil(h(r, makeCommand("SUBSCRIBE", "news")...))
+	as.Equal(command.Queued, <-r.output)
+	as.Nil(h(r, makeCommand("EXEC")...))
+	as.Equal(resp.MakeArray(resp.MakeArray(
+		resp.BulkString("subscribe"), resp.BulkString("news"), resp.Integer(1),
+	)), <-r.output)
+
+	as.Equal(1, b.Publish("news", "hello"))
+	as.Equal(resp.MakeArray(
+		resp.BulkString("message"), resp.BulkString("news"),
+		resp.BulkString("hello"),
+	), <-r.output)
+}
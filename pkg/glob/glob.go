@@ -0,0 +1,81 @@
+// Package glob implements the glob-style pattern matching used by Redis for
+// commands such as KEYS, SCAN and PSUBSCRIBE. Patterns support '*' for any
+// sequence of bytes, '?' for any single byte, '[...]' for a class of bytes
+// (including '^' negation and 'a-z' ranges), and '\' to escape the next byte
+package glob
+
+// Match reports whether the entire string is matched by the pattern
+func Match(pattern, s string) bool {
+	p, i := 0, 0
+	star, resume := -1, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			if pattern[p] == '*' {
+				star, resume = p, i
+				p++
+				continue
+			}
+			if next, ok := matchByte(pattern, p, s[i]); ok {
+				p = next
+				i++
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		resume++
+		p, i = star+1, resume
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchByte matches a single byte against the pattern element starting at
+// the provided position, returning the position of the next element
+func matchByte(pattern string, p int, c byte) (int, bool) {
+	switch pattern[p] {
+	case '?':
+		return p + 1, true
+	case '[':
+		return matchClass(pattern, p+1, c)
+	case '\\':
+		if p+1 < len(pattern) {
+			p++
+		}
+		return p + 1, pattern[p] == c
+	default:
+		return p + 1, pattern[p] == c
+	}
+}
+
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	not := p < len(pattern) && pattern[p] == '^'
+	if not {
+		p++
+	}
+	match := false
+	for p < len(pattern) && pattern[p] != ']' {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			match = match || pattern[p] == c
+		case p+2 < len(pattern) && pattern[p+1] == '-':
+			lo, hi := pattern[p], pattern[p+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			match = match || c >= lo && c <= hi
+			p += 2
+		default:
+			match = match || pattern[p] == c
+		}
+		p++
+	}
+	if p < len(pattern) {
+		p++
+	}
+	return p, match != not
+}
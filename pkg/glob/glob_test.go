@@ -0,0 +1,58 @@
+package glob_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/glob"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	as := assert.New(t)
+
+	testCases := []struct {
+		pattern  string
+		input    string
+		expected bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"", "", true},
+		{"", "a", false},
+		{"hello", "hello", true},
+		{"hello", "hell", false},
+		{"h?llo", "hallo", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "hllo", true},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "heeeellx", false},
+		{"*llo", "hello", true},
+		{"he*", "hello", true},
+		{"**o", "hello", true},
+		{"*l*l*", "hello", true},
+		{"*x*", "hello", false},
+		{"h[ae]llo", "hello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{"h[\\]]llo", "h]llo", true},
+		{"h[-]llo", "h-llo", true},
+		{"h[ab", "ha", true},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"hello\\", "hello\\", true},
+		{"user:*:name", "user:1234:name", true},
+		{"user:*:name", "user:1234:email", false},
+	}
+
+	for _, tc := range testCases {
+		as.Equal(
+			tc.expected, glob.Match(tc.pattern, tc.input),
+			"%q ~ %q", tc.pattern, tc.input,
+		)
+	}
+}
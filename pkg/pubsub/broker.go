@@ -0,0 +1,182 @@
+// Package pubsub implements a broker that delivers published messages to the
+// subscribers of a channel, and to the subscribers of any pattern that
+// matches the channel's name
+package pubsub
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/kode4food/respect/pkg/glob"
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// Broker tracks channel and pattern subscriptions, and delivers the
+	// messages published to channels
+	Broker struct {
+		channels subscriptions
+		patterns subscriptions
+		sync.RWMutex
+	}
+
+	// Subscriber receives the messages delivered by a Broker. Messages are
+	// delivered by the goroutine that published them, so a Subscriber
+	// shouldn't block while receiving them
+	Subscriber interface {
+		// Message delivers a message published to a subscribed channel
+		Message(channel, payload resp.BulkString)
+
+		// PMessage delivers a message published to a channel whose name is
+		// matched by a subscribed pattern
+		PMessage(pattern, channel, payload resp.BulkString)
+	}
+
+	subscriptions map[resp.BulkString]map[Subscriber]struct{}
+)
+
+// NewBroker creates a new Broker with no subscriptions
+func NewBroker() *Broker {
+	return &Broker{
+		channels: subscriptions{},
+		patterns: subscriptions{},
+	}
+}
+
+// Subscribe adds a Subscriber to a channel, returning whether it wasn't
+// already subscribed
+func (b *Broker) Subscribe(s Subscriber, channel resp.BulkString) bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.channels.add(s, channel)
+}
+
+// Unsubscribe removes a Subscriber from a channel, returning whether it was
+// subscribed
+func (b *Broker) Unsubscribe(s Subscriber, channel resp.BulkString) bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.channels.remove(s, channel)
+}
+
+// PSubscribe adds a Subscriber to the channels matched by a glob-style
+// pattern, returning whether it wasn't already subscribed
+func (b *Broker) PSubscribe(s Subscriber, pattern resp.BulkString) bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.patterns.add(s, pattern)
+}
+
+// PUnsubscribe removes a Subscriber from a pattern, returning whether it was
+// subscribed
+func (b *Broker) PUnsubscribe(s Subscriber, pattern resp.BulkString) bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.patterns.remove(s, pattern)
+}
+
+// UnsubscribeAll removes a Subscriber from all of its channels and patterns
+func (b *Broker) UnsubscribeAll(s Subscriber) {
+	b.Lock()
+	defer b.Unlock()
+	b.channels.removeAll(s)
+	b.patterns.removeAll(s)
+}
+
+// Publish delivers a message to the subscribers of a channel and to the
+// subscribers of every pattern that matches it, returning the number of
+// deliveries made
+func (b *Broker) Publish(channel, payload resp.BulkString) int {
+	type delivery struct {
+		Subscriber
+		pattern resp.BulkString
+	}
+
+	b.RLock()
+	var direct []Subscriber
+	for s := range b.channels[channel] {
+		direct = append(direct, s)
+	}
+	var matched []delivery
+	for p, subs := range b.patterns {
+		if !glob.Match(string(p), string(channel)) {
+			continue
+		}
+		for s := range subs {
+			matched = append(matched, delivery{s, p})
+		}
+	}
+	b.RUnlock()
+
+	for _, s := range direct {
+		s.Message(channel, payload)
+	}
+	for _, d := range matched {
+		d.PMessage(d.pattern, channel, payload)
+	}
+	return len(direct) + len(matched)
+}
+
+// Channels returns the sorted names of the channels that have at least one
+// subscriber and are matched by the provided glob-style pattern
+func (b *Broker) Channels(pattern string) []resp.BulkString {
+	b.RLock()
+	defer b.RUnlock()
+	var res []resp.BulkString
+	for c := range b.channels {
+		if glob.Match(pattern, string(c)) {
+			res = append(res, c)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// NumSub returns the number of subscribers to a channel, not counting those
+// subscribed to matching patterns
+func (b *Broker) NumSub(channel resp.BulkString) int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.channels[channel])
+}
+
+// NumPat returns the number of unique patterns that are subscribed to
+func (b *Broker) NumPat() int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.patterns)
+}
+
+func (s subscriptions) add(sub Subscriber, name resp.BulkString) bool {
+	subs, ok := s[name]
+	if !ok {
+		subs = map[Subscriber]struct{}{}
+		s[name] = subs
+	}
+	if _, ok := subs[sub]; ok {
+		return false
+	}
+	subs[sub] = struct{}{}
+	return true
+}
+
+func (s subscriptions) remove(sub Subscriber, name resp.BulkString) bool {
+	subs, ok := s[name]
+	if !ok {
+		return false
+	}
+	if _, ok := subs[sub]; !ok {
+		return false
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s, name)
+	}
+	return true
+}
+
+func (s subscriptions) removeAll(sub Subscriber) {
+	for name := range s {
+		s.remove(sub, name)
+	}
+}
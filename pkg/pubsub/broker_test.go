@@ -0,0 +1,95 @@
+package pubsub_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/pubsub"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSubscriber struct {
+	received []resp.Values
+	sync.Mutex
+}
+
+func (s *testSubscriber) Message(channel, payload resp.BulkString) {
+	s.Lock()
+	defer s.Unlock()
+	s.received = append(s.received, resp.Values{channel, payload})
+}
+
+func (s *testSubscriber) PMessage(pattern, channel, payload resp.BulkString) {
+	s.Lock()
+	defer s.Unlock()
+	s.received = append(s.received, resp.Values{pattern, channel, payload})
+}
+
+func TestBrokerPublish(t *testing.T) {
+	as := assert.New(t)
+	b := pubsub.NewBroker()
+	s1 := &testSubscriber{}
+	s2 := &testSubscriber{}
+
+	as.True(b.Subscribe(s1, "news"))
+	as.False(b.Subscribe(s1, "news"))
+	as.True(b.Subscribe(s2, "news"))
+	as.True(b.PSubscribe(s2, "n*"))
+	as.True(b.PSubscribe(s2, "w?ather"))
+
+	as.Equal(3, b.Publish("news", "hello"))
+	as.Equal(1, b.Publish("weather", "sunny"))
+	as.Equal(0, b.Publish("sports", "none"))
+
+	as.Equal([]resp.Values{{
+		resp.BulkString("news"), resp.BulkString("hello"),
+	}}, s1.received)
+	as.ElementsMatch([]resp.Values{
+		{resp.BulkString("news"), resp.BulkString("hello")},
+		{
+			resp.BulkString("n*"), resp.BulkString("news"),
+			resp.BulkString("hello"),
+		},
+		{
+			resp.BulkString("w?ather"), resp.BulkString("weather"),
+			resp.BulkString("sunny"),
+		},
+	}, s2.received)
+}
+
+func TestBrokerIntrospection(t *testing.T) {
+	as := assert.New(t)
+	b := pubsub.NewBroker()
+	s1 := &testSubscriber{}
+	s2 := &testSubscriber{}
+
+	b.Subscribe(s1, "b")
+	b.Subscribe(s1, "a")
+	b.Subscribe(s2, "a")
+	b.Subscribe(s2, "other")
+	b.PSubscribe(s1, "*")
+	b.PSubscribe(s2, "*")
+	b.PSubscribe(s2, "a*")
+
+	as.Equal([]resp.BulkString{"a", "b", "other"}, b.Channels("*"))
+	as.Equal([]resp.BulkString{"a", "b"}, b.Channels("?"))
+	as.Equal(2, b.NumSub("a"))
+	as.Equal(1, b.NumSub("b"))
+	as.Equal(0, b.NumSub("missing"))
+	as.Equal(2, b.NumPat())
+
+	as.True(b.Unsubscribe(s1, "b"))
+	as.False(b.Unsubscribe(s1, "b"))
+	as.False(b.PUnsubscribe(s1, "a*"))
+	as.Equal([]resp.BulkString{"a", "other"}, b.Channels("*"))
+
+	b.UnsubscribeAll(s2)
+	as.Equal([]resp.BulkString{"a"}, b.Channels("*"))
+	as.Equal(1, b.NumSub("a"))
+	as.Equal(1, b.NumPat())
+
+	b.UnsubscribeAll(s1)
+	as.Empty(b.Channels("*"))
+	as.Equal(0, b.NumPat())
+}
@@ -49,7 +49,7 @@ func (a *Attribute) Marshal(w io.Writer) error {
 }
 
 func (a *Attribute) Equal(v Value) bool {
-	if v, ok := v.(*Map); ok {
+	if v, ok := v.(*Attribute); ok {
 		return a.data.equal(v.data)
 	}
 	return false
@@ -0,0 +1,44 @@
+package resp
+
+import "io"
+
+// Attributed is a Value paired with the Attribute that preceded it. A Reader
+// only produces Attributed values if it was configured to PreserveAttributes,
+// and a Responder can emit one to send an Attribute ahead of its reply
+type Attributed struct {
+	Value
+	Attribute *Attribute
+}
+
+// compile-time check for interface implementation
+var _ Value = (*Attributed)(nil)
+
+// MakeAttributed pairs a Value with the Attribute that's sent ahead of it
+func MakeAttributed(attr *Attribute, v Value) *Attributed {
+	return &Attributed{
+		Value:     v,
+		Attribute: attr,
+	}
+}
+
+// Unwrap returns the Value without the Attribute that preceded it, if any
+func Unwrap(v Value) Value {
+	if a, ok := v.(*Attributed); ok {
+		return a.Value
+	}
+	return v
+}
+
+func (a *Attributed) Marshal(w io.Writer) error {
+	if err := a.Attribute.Marshal(w); err != nil {
+		return err
+	}
+	return a.Value.Marshal(w)
+}
+
+func (a *Attributed) Equal(v Value) bool {
+	if v, ok := v.(*Attributed); ok {
+		return a.Attribute.Equal(v.Attribute) && a.Value.Equal(v.Value)
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package resp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributed(t *testing.T) {
+	as := assert.New(t)
+	input := "|1\r\n+popularity\r\n:42\r\n$5\r\nhello\r\n"
+	attr := resp.MakeAttributeFromPairs(
+		[2]resp.Value{resp.SimpleString("popularity"), resp.Integer(42)},
+	)
+
+	v, err := resp.ReadString(input)
+	as.Nil(err)
+	as.Equal(resp.BulkString("hello"), v)
+
+	v, err = resp.ReadString(input, resp.PreserveAttributes)
+	as.Nil(err)
+	as.Equal(resp.BulkStringTag, v.Tag())
+	as.True(resp.MakeAttributed(attr, resp.BulkString("hello")).Equal(v))
+	as.False(resp.MakeAttributed(attr, resp.BulkString("world")).Equal(v))
+	as.False(resp.BulkString("hello").Equal(v))
+	as.Equal(resp.BulkString("hello"), resp.Unwrap(v))
+	as.Equal(resp.BulkString("hello"), resp.Unwrap(resp.BulkString("hello")))
+	as.Equal(input, resp.ToString(v))
+
+	var sb strings.Builder
+	as.Nil(resp.MarshalV2(v, &sb))
+	as.Equal("$5\r\nhello\r\n", sb.String())
+}
+
+func TestAttributedNested(t *testing.T) {
+	as := assert.New(t)
+
+	v, err := resp.ReadString(
+		"*2\r\n:1\r\n|1\r\n+ttl\r\n:3\r\n:2\r\n", resp.PreserveAttributes,
+	)
+	as.Nil(err)
+	elems := v.(*resp.Array).Values
+	as.Equal(resp.Integer(1), elems[0])
+	a, ok := elems[1].(*resp.Attributed)
+	as.True(ok)
+	as.Equal(resp.Integer(2), a.Value)
+	ttl, ok := a.Attribute.Get(resp.SimpleString("ttl"))
+	as.True(ok)
+	as.Equal(resp.Integer(3), ttl)
+
+	_, err = resp.ReadString("|1\r\n+ttl\r\n", resp.PreserveAttributes)
+	as.NotNil(err)
+}
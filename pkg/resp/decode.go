@@ -0,0 +1,403 @@
+package resp
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Error messages
+const (
+	ErrInvalidDecodeTarget = "ERR decode target must be a non-nil pointer: %T"
+	ErrCannotDecode        = "ERR cannot decode %s into %s"
+	ErrDecodeOverflow      = "ERR %s overflows %s"
+)
+
+// Decode populates the Go value that target points to from a Value, reversing
+// the conversions that Encode performs. Numbers and booleans are also parsed
+// from strings, Maps and Arrays of alternating keys and values can populate
+// both maps and structs, and Null leaves the target with its zero value.
+// Decoding into an empty interface produces strings, int64s, float64s,
+// bools, *big.Ints, slices and maps keyed by string. If the Value is an
+// Error, it's returned. Types can implement Decoder to populate themselves
+func Decode(v Value, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf(ErrInvalidDecodeTarget, target)
+	}
+	return decodeValue(v, rv.Elem())
+}
+
+func decodeValue(v Value, rv reflect.Value) error {
+	v = Unwrap(v)
+	if e, ok := v.(Error); ok {
+		return e
+	}
+	t := rv.Type()
+	if reflect.PointerTo(t).Implements(decoderType) {
+		return rv.Addr().Interface().(Decoder).DecodeRESP(v)
+	}
+	if t.Implements(valueType) && reflect.TypeOf(v).AssignableTo(t) {
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	}
+	if _, ok := v.(Null); ok {
+		rv.Set(reflect.Zero(t))
+		return nil
+	}
+	switch t {
+	case durationType:
+		return decodeDuration(v, rv)
+	case bigIntType:
+		return decodeBigInt(v, rv)
+	case bytesType:
+		if s, ok := asString(v); ok {
+			rv.SetBytes([]byte(s))
+			return nil
+		}
+		return cannotDecode(v, t)
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return decodeBool(v, rv)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return decodeInt(v, rv)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return decodeUint(v, rv)
+	case reflect.Float32, reflect.Float64:
+		return decodeFloat(v, rv)
+	case reflect.String:
+		if s, ok := asString(v); ok {
+			rv.SetString(s)
+			return nil
+		}
+		return cannotDecode(v, t)
+	case reflect.Slice:
+		return decodeSlice(v, rv)
+	case reflect.Array:
+		return decodeArray(v, rv)
+	case reflect.Map:
+		return decodeMap(v, rv)
+	case reflect.Struct:
+		return decodeStruct(v, rv)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(t.Elem()))
+		}
+		return decodeValue(v, rv.Elem())
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return cannotDecode(v, t)
+		}
+		res, err := decodeAny(v)
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			rv.Set(reflect.Zero(t))
+		} else {
+			rv.Set(reflect.ValueOf(res))
+		}
+		return nil
+	default:
+		return cannotDecode(v, t)
+	}
+}
+
+func decodeDuration(v Value, rv reflect.Value) error {
+	switch v := v.(type) {
+	case Integer:
+		rv.SetInt(int64(time.Duration(v) * time.Millisecond))
+		return nil
+	case String:
+		d, err := time.ParseDuration(v.String())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	default:
+		return cannotDecode(v, rv.Type())
+	}
+}
+
+func decodeBigInt(v Value, rv reflect.Value) error {
+	var b *big.Int
+	switch v := v.(type) {
+	case *BigNumber:
+		b = (*big.Int)(v)
+	case Integer:
+		b = big.NewInt(int64(v))
+	case String:
+		n, err := MakeBigNumber(v.String())
+		if err != nil {
+			return err
+		}
+		b = (*big.Int)(n)
+	default:
+		return cannotDecode(v, rv.Type())
+	}
+	rv.Set(reflect.ValueOf(new(big.Int).Set(b)).Elem())
+	return nil
+}
+
+func decodeBool(v Value, rv reflect.Value) error {
+	switch v := v.(type) {
+	case Boolean:
+		rv.SetBool(bool(v))
+		return nil
+	case Integer:
+		rv.SetBool(v != 0)
+		return nil
+	case String:
+		b, err := strconv.ParseBool(v.String())
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+		return nil
+	default:
+		return cannotDecode(v, rv.Type())
+	}
+}
+
+func decodeInt(v Value, rv reflect.Value) error {
+	var i int64
+	switch v := v.(type) {
+	case Integer:
+		i = int64(v)
+	case String:
+		p, err := strconv.ParseInt(v.String(), 10, 64)
+		if err != nil {
+			return err
+		}
+		i = p
+	default:
+		return cannotDecode(v, rv.Type())
+	}
+	if rv.OverflowInt(i) {
+		return fmt.Errorf(ErrDecodeOverflow, v, rv.Type())
+	}
+	rv.SetInt(i)
+	return nil
+}
+
+func decodeUint(v Value, rv reflect.Value) error {
+	var u uint64
+	switch v := v.(type) {
+	case Integer:
+		if v < 0 {
+			return fmt.Errorf(ErrDecodeOverflow, v, rv.Type())
+		}
+		u = uint64(v)
+	case String:
+		p, err := strconv.ParseUint(v.String(), 10, 64)
+		if err != nil {
+			return err
+		}
+		u = p
+	default:
+		return cannotDecode(v, rv.Type())
+	}
+	if rv.OverflowUint(u) {
+		return fmt.Errorf(ErrDecodeOverflow, v, rv.Type())
+	}
+	rv.SetUint(u)
+	return nil
+}
+
+func decodeFloat(v Value, rv reflect.Value) error {
+	var f float64
+	switch v := v.(type) {
+	case Double:
+		f = float64(v)
+	case Integer:
+		f = float64(v)
+	case String:
+		p, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return err
+		}
+		f = p
+	default:
+		return cannotDecode(v, rv.Type())
+	}
+	rv.SetFloat(f)
+	return nil
+}
+
+func decodeSlice(v Value, rv reflect.Value) error {
+	c, ok := v.(Collection)
+	if !ok {
+		return cannotDecode(v, rv.Type())
+	}
+	elems := c.Elements()
+	res := reflect.MakeSlice(rv.Type(), len(elems), len(elems))
+	for i, e := range elems {
+		if err := decodeValue(e, res.Index(i)); err != nil {
+			return err
+		}
+	}
+	rv.Set(res)
+	return nil
+}
+
+func decodeArray(v Value, rv reflect.Value) error {
+	c, ok := v.(Collection)
+	if !ok {
+		return cannotDecode(v, rv.Type())
+	}
+	elems := c.Elements()
+	for i := 0; i < rv.Len(); i++ {
+		if i >= len(elems) {
+			rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+			continue
+		}
+		if err := decodeValue(elems[i], rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeMap(v Value, rv reflect.Value) error {
+	t := rv.Type()
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(t))
+	}
+	return forEachPair(v, t, func(k, e Value) error {
+		key := reflect.New(t.Key()).Elem()
+		if err := decodeValue(k, key); err != nil {
+			return err
+		}
+		val := reflect.New(t.Elem()).Elem()
+		if err := decodeValue(e, val); err != nil {
+			return err
+		}
+		rv.SetMapIndex(key, val)
+		return nil
+	})
+}
+
+func decodeStruct(v Value, rv reflect.Value) error {
+	fields := fieldsOf(rv.Type())
+	return forEachPair(v, rv.Type(), func(k, e Value) error {
+		name, ok := asString(k)
+		if !ok {
+			return nil
+		}
+		for _, f := range fields {
+			if f.name != name {
+				continue
+			}
+			if fv, ok := fieldByIndexAlloc(rv, f.index); ok {
+				return decodeValue(e, fv)
+			}
+		}
+		return nil
+	})
+}
+
+// fieldByIndexAlloc retrieves a possibly promoted field, allocating any nil
+// embedded pointers that it's promoted through. It reports false if one of
+// those pointers is unexported, and so can't be allocated
+func fieldByIndexAlloc(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// forEachPair calls fn with the key/value pairs of a Mapped Value, or of an
+// Array that holds alternating keys and values, as RESP2 replies do
+func forEachPair(v Value, t reflect.Type, fn func(k, v Value) error) error {
+	switch v := v.(type) {
+	case Mapped:
+		return v.ForEach(fn)
+	case *Array:
+		if len(v.Values)%2 != 0 {
+			return cannotDecode(v, t)
+		}
+		for i := 0; i < len(v.Values); i += 2 {
+			if err := fn(v.Values[i], v.Values[i+1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return cannotDecode(v, t)
+	}
+}
+
+func decodeAny(v Value) (any, error) {
+	switch v := Unwrap(v).(type) {
+	case Error:
+		return nil, v
+	case Null:
+		return nil, nil
+	case Boolean:
+		return bool(v), nil
+	case Integer:
+		return int64(v), nil
+	case Double:
+		return float64(v), nil
+	case *BigNumber:
+		return new(big.Int).Set((*big.Int)(v)), nil
+	case String:
+		return v.String(), nil
+	case Mapped:
+		res := make(map[string]any, v.Count())
+		err := v.ForEach(func(k, e Value) error {
+			ks, ok := asString(k)
+			if !ok {
+				ks = ToString(k)
+			}
+			d, err := decodeAny(e)
+			res[ks] = d
+			return err
+		})
+		return res, err
+	case Collection:
+		elems := v.Elements()
+		res := make([]any, len(elems))
+		for i, e := range elems {
+			d, err := decodeAny(e)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = d
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
+
+// asString returns the string form of a String or numeric Value
+func asString(v Value) (string, bool) {
+	switch v := v.(type) {
+	case String:
+		return v.String(), true
+	case Integer, Double, *BigNumber:
+		return v.(fmt.Stringer).String(), true
+	default:
+		return "", false
+	}
+}
+
+func cannotDecode(v Value, t reflect.Type) error {
+	return fmt.Errorf(ErrCannotDecode, v.Tag(), t)
+}
@@ -0,0 +1,318 @@
+package resp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Encoder is implemented by types that provide their own Value
+	// representation to Encode
+	Encoder interface {
+		EncodeRESP() (Value, error)
+	}
+
+	// Decoder is implemented by types that populate themselves from a Value
+	// when passed to Decode
+	Decoder interface {
+		DecodeRESP(Value) error
+	}
+
+	// field is an exported struct field, as named by its resp tag
+	field struct {
+		name      string
+		index     []int
+		tagged    bool
+		omitEmpty bool
+	}
+
+	// embedded is a struct type whose fields are promoted through index
+	embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+)
+
+// Error messages
+const (
+	ErrUnsupportedType = "ERR unsupported type: %s"
+	ErrUintOverflow    = "ERR unsigned integer overflows Integer: %d"
+)
+
+const tagName = "resp"
+
+var (
+	valueType    = reflect.TypeOf((*Value)(nil)).Elem()
+	encoderType  = reflect.TypeOf((*Encoder)(nil)).Elem()
+	decoderType  = reflect.TypeOf((*Decoder)(nil)).Elem()
+	durationType = reflect.TypeOf(time.Duration(0))
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bytesType    = reflect.TypeOf([]byte(nil))
+
+	structFields sync.Map
+)
+
+// Encode converts a Go value into a Value. Strings and byte slices become
+// BulkStrings, integers become Integers, floats become Doubles, big.Ints
+// become BigNumbers and time.Durations become Integers of milliseconds.
+// Slices and arrays become Arrays, while maps and structs become Maps. Nil
+// pointers, slices, maps and interfaces become Null. Struct fields are keyed
+// by name unless a `resp:"name,omitempty"` tag says otherwise, and a field
+// tagged "-" is skipped. Fields promoted from embedded structs are shadowed
+// as they are by encoding/json. Types can implement Encoder to provide their
+// own representation
+func Encode(v any) (Value, error) {
+	if v == nil {
+		return NullValue, nil
+	}
+	return encodeValue(reflect.ValueOf(v))
+}
+
+func encodeValue(rv reflect.Value) (Value, error) {
+	t := rv.Type()
+	if isNilable(rv.Kind()) && rv.IsNil() {
+		return NullValue, nil
+	}
+	if t.Implements(encoderType) {
+		return rv.Interface().(Encoder).EncodeRESP()
+	}
+	if rv.CanAddr() && reflect.PointerTo(t).Implements(encoderType) {
+		return rv.Addr().Interface().(Encoder).EncodeRESP()
+	}
+	if t.Implements(valueType) {
+		return rv.Interface().(Value), nil
+	}
+	switch t {
+	case durationType:
+		return Integer(rv.Interface().(time.Duration).Milliseconds()), nil
+	case bigIntType:
+		b := rv.Interface().(big.Int)
+		return (*BigNumber)(&b), nil
+	case bytesType:
+		return BulkString(rv.Bytes()), nil
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return Boolean(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return Integer(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf(ErrUintOverflow, u)
+		}
+		return Integer(u), nil
+	case reflect.Float32, reflect.Float64:
+		return Double(rv.Float()), nil
+	case reflect.String:
+		return BulkString(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		return encodeArray(rv)
+	case reflect.Map:
+		return encodeMap(rv)
+	case reflect.Struct:
+		return encodeStruct(rv)
+	case reflect.Pointer, reflect.Interface:
+		return encodeValue(rv.Elem())
+	default:
+		return nil, fmt.Errorf(ErrUnsupportedType, t)
+	}
+}
+
+func encodeArray(rv reflect.Value) (Value, error) {
+	res := make(Values, rv.Len())
+	for i := range res {
+		v, err := encodeValue(rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return MakeArray(res...), nil
+}
+
+func encodeMap(rv reflect.Value) (Value, error) {
+	pairs := make([][2]Value, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k, err := encodeValue(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		v, err := encodeValue(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]Value{k, v})
+	}
+	return MakeMapFromPairs(pairs...), nil
+}
+
+func encodeStruct(rv reflect.Value) (Value, error) {
+	fields := fieldsOf(rv.Type())
+	pairs := make([][2]Value, 0, len(fields))
+	for _, f := range fields {
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok || f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		v, err := encodeValue(fv)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]Value{BulkString(f.name), v})
+	}
+	return MakeMapFromPairs(pairs...), nil
+}
+
+// fieldByIndex retrieves a possibly promoted field, reporting false if it's
+// promoted through a nil embedded pointer
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// fieldsOf returns the encoded fields of a struct type, including those that
+// are promoted from untagged embedded structs. Fields that share a name are
+// resolved as encoding/json resolves them
+func fieldsOf(t reflect.Type) []field {
+	if f, ok := structFields.Load(t); ok {
+		return f.([]field)
+	}
+	res := dominantFields(collectFields(t))
+	structFields.Store(t, res)
+	return res
+}
+
+// collectFields gathers the fields of a struct type breadth-first, so that
+// those of each embedded struct follow the ones they might be shadowed by. A
+// struct that's embedded more than once at the same depth contributes its
+// fields once for each path, but one that's already been seen at a shallower
+// depth is skipped
+func collectFields(t reflect.Type) []field {
+	var res []field
+	visited := map[reflect.Type]bool{}
+	for next := []embedded{{typ: t}}; len(next) != 0; {
+		current := next
+		next = nil
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			res = append(res, structFieldsOf(e, &next)...)
+		}
+		for _, e := range current {
+			visited[e.typ] = true
+		}
+	}
+	return res
+}
+
+// structFieldsOf returns the fields declared by an embedded struct, adding
+// any untagged structs that it embeds in turn to next
+func structFieldsOf(e embedded, next *[]embedded) []field {
+	var res []field
+	for i := 0; i < e.typ.NumField(); i++ {
+		sf := e.typ.Field(i)
+		tag, tagged := sf.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+		idx := append(append([]int{}, e.index...), i)
+		if sf.Anonymous && !tagged {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				*next = append(*next, embedded{typ: ft, index: idx})
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		f := field{
+			name:      name,
+			index:     idx,
+			tagged:    name != "",
+			omitEmpty: opts == "omitempty",
+		}
+		if !f.tagged {
+			f.name = sf.Name
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+// dominantFields keeps one field for each name, in the order that they're
+// declared. Of the fields that share a name, the shallowest wins, and then
+// the one with a tagged name. If that still leaves more than one, the name
+// is ambiguous and none of them are kept
+func dominantFields(fields []field) []field {
+	byName := map[string][]field{}
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	res := make([]field, 0, len(byName))
+	for _, named := range byName {
+		if f, ok := dominantField(named); ok {
+			res = append(res, f)
+		}
+	}
+	slices.SortFunc(res, func(l, r field) int {
+		return slices.Compare(l.index, r.index)
+	})
+	return res
+}
+
+func dominantField(fields []field) (field, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields[1:] {
+		if len(f.index) < depth {
+			depth = len(f.index)
+		}
+	}
+	var res []field
+	for _, f := range fields {
+		if len(f.index) == depth {
+			res = append(res, f)
+		}
+	}
+	if len(res) > 1 {
+		res = slices.DeleteFunc(res, func(f field) bool {
+			return !f.tagged
+		})
+	}
+	if len(res) != 1 {
+		return field{}, false
+	}
+	return res[0], true
+}
+
+func isNilable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
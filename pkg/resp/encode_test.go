@@ -0,0 +1,256 @@
+package resp_test
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	base struct {
+		ID int64 `resp:"id"`
+	}
+
+	profile struct {
+		base
+		Name     string            `resp:"name"`
+		Email    string            `resp:"email,omitempty"`
+		Tags     []string          `resp:"tags"`
+		TTL      time.Duration     `resp:"ttl"`
+		Score    float64           `resp:"score"`
+		Balance  *big.Int          `resp:"balance"`
+		Avatar   []byte            `resp:"avatar"`
+		Limits   map[string]uint16 `resp:"limits"`
+		Manager  *profile          `resp:"manager"`
+		Color    color             `resp:"color"`
+		Internal string            `resp:"-"`
+		hidden   string
+	}
+
+	color struct {
+		name string
+	}
+
+	named struct {
+		Name string
+		Code int
+	}
+
+	left struct {
+		Shared string
+		Label  string `resp:"Tagged"`
+	}
+
+	right struct {
+		Shared string
+		Tagged string
+	}
+
+	shadowing struct {
+		named
+		left
+		right
+		Name string
+	}
+)
+
+func (c color) EncodeRESP() (resp.Value, error) {
+	return resp.SimpleString(strings.ToUpper(c.name)), nil
+}
+
+func (c *color) DecodeRESP(v resp.Value) error {
+	s, ok := v.(resp.SimpleString)
+	if !ok {
+		return errors.New("expected a simple string")
+	}
+	c.name = strings.ToLower(string(s))
+	return nil
+}
+
+func TestEncodeDecode(t *testing.T) {
+	as := assert.New(t)
+
+	p := &profile{
+		base:     base{ID: 7},
+		Name:     "alice",
+		Tags:     []string{"admin", "ops"},
+		TTL:      1500 * time.Millisecond,
+		Score:    2.5,
+		Balance:  new(big.Int).Lsh(big.NewInt(1), 100),
+		Avatar:   []byte{1, 2, 3},
+		Limits:   map[string]uint16{"conns": 10},
+		Color:    color{name: "red"},
+		Internal: "internal",
+		hidden:   "hidden",
+	}
+	v, err := resp.Encode(p)
+	as.Nil(err)
+	m := v.(*resp.Map)
+	as.Equal(10, m.Count())
+
+	get := func(k string) resp.Value {
+		res, ok := m.Get(resp.BulkString(k))
+		as.True(ok)
+		return res
+	}
+	as.Equal(resp.Integer(7), get("id"))
+	as.Equal(resp.BulkString("alice"), get("name"))
+	as.Equal(resp.Integer(1500), get("ttl"))
+	as.Equal(resp.Double(2.5), get("score"))
+	as.Equal(resp.BigNumberTag, get("balance").Tag())
+	as.Equal(resp.BulkString("\x01\x02\x03"), get("avatar"))
+	as.Equal(resp.NullValue, get("manager"))
+	as.Equal(resp.SimpleString("RED"), get("color"))
+	_, ok := m.Get(resp.BulkString("email"))
+	as.False(ok)
+
+	// Round trip through the wire
+	v, err = resp.ReadString(resp.ToString(v))
+	as.Nil(err)
+	var res profile
+	as.Nil(resp.Decode(v, &res))
+	p.Internal = ""
+	p.hidden = ""
+	as.Equal(*p, res)
+}
+
+func TestEncodeShadowing(t *testing.T) {
+	as := assert.New(t)
+
+	s := shadowing{
+		named: named{Name: "inner", Code: 7},
+		left:  left{Shared: "left", Label: "tagged"},
+		right: right{Shared: "right", Tagged: "untagged"},
+		Name:  "outer",
+	}
+	v, err := resp.Encode(s)
+	as.Nil(err)
+	m := v.(*resp.Map)
+	as.Equal(3, m.Count())
+
+	get := func(k string) resp.Value {
+		res, _ := m.Get(resp.BulkString(k))
+		return res
+	}
+	as.Equal(resp.BulkString("outer"), get("Name"))
+	as.Equal(resp.Integer(7), get("Code"))
+	as.Equal(resp.BulkString("tagged"), get("Tagged"))
+	_, ok := m.Get(resp.BulkString("Shared"))
+	as.False(ok)
+
+	// Round trip through the wire
+	v, err = resp.ReadString(resp.ToString(v))
+	as.Nil(err)
+	var res shadowing
+	as.Nil(resp.Decode(v, &res))
+	as.Equal(shadowing{
+		named: named{Code: 7},
+		left:  left{Label: "tagged"},
+		Name:  "outer",
+	}, res)
+}
+
+func TestEncode(t *testing.T) {
+	as := assert.New(t)
+
+	testCases := []struct {
+		value    any
+		expected resp.Value
+	}{
+		{nil, resp.NullValue},
+		{true, resp.True},
+		{uint8(8), resp.Integer(8)},
+		{float32(0.5), resp.Double(0.5)},
+		{[]int(nil), resp.NullValue},
+		{[2]bool{true, false}, resp.MakeArray(resp.True, resp.False)},
+		{resp.SimpleString("OK"), resp.OK},
+		{
+			[]any{"a", 1, nil},
+			resp.MakeArray(
+				resp.BulkString("a"), resp.Integer(1), resp.NullValue,
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		v, err := resp.Encode(tc.value)
+		as.Nil(err)
+		as.True(tc.expected.Equal(v), "%v", tc.value)
+	}
+
+	_, err := resp.Encode(uint64(1 << 63))
+	as.EqualError(err, fmt.Sprintf(resp.ErrUintOverflow, uint64(1<<63)))
+	_, err = resp.Encode(make(chan int))
+	as.EqualError(err, "ERR unsupported type: chan int")
+}
+
+func TestDecode(t *testing.T) {
+	as := assert.New(t)
+
+	var i int8
+	as.Nil(resp.Decode(resp.BulkString("42"), &i))
+	as.Equal(int8(42), i)
+	as.EqualError(resp.Decode(resp.Integer(300), &i), "ERR 300 overflows int8")
+
+	var u uint
+	as.EqualError(resp.Decode(resp.Integer(-1), &u), "ERR -1 overflows uint")
+
+	var b bool
+	as.Nil(resp.Decode(resp.Integer(1), &b))
+	as.True(b)
+
+	var d time.Duration
+	as.Nil(resp.Decode(resp.SimpleString("2s"), &d))
+	as.Equal(2*time.Second, d)
+
+	var f float64
+	as.Nil(resp.Decode(resp.BulkString("inf"), &f))
+	as.True(f > 0)
+
+	// RESP2 replies flatten maps into Arrays of alternating pairs
+	var hash map[string]int
+	as.Nil(resp.Decode(resp.MakeArray(
+		resp.BulkString("a"), resp.BulkString("1"),
+		resp.BulkString("b"), resp.BulkString("2"),
+	), &hash))
+	as.Equal(map[string]int{"a": 1, "b": 2}, hash)
+	as.EqualError(
+		resp.Decode(resp.MakeArray(resp.BulkString("a")), &hash),
+		"ERR cannot decode array into map[string]int",
+	)
+
+	var s *string
+	as.Nil(resp.Decode(resp.BulkString("value"), &s))
+	as.Equal("value", *s)
+	as.Nil(resp.Decode(resp.NullValue, &s))
+	as.Nil(s)
+
+	var anything any
+	as.Nil(resp.Decode(resp.MakeMapFromPairs(
+		[2]resp.Value{
+			resp.SimpleString("list"),
+			resp.MakeSet(resp.Integer(1)),
+		},
+	), &anything))
+	as.Equal(map[string]any{"list": []any{int64(1)}}, anything)
+
+	var value resp.Value
+	as.Nil(resp.Decode(resp.Integer(1), &value))
+	as.Equal(resp.Integer(1), value)
+
+	as.EqualError(resp.Decode(resp.MakeError("ERR failed"), &s), "ERR failed")
+	as.EqualError(
+		resp.Decode(resp.Integer(1), s),
+		"ERR decode target must be a non-nil pointer: *string",
+	)
+	as.EqualError(
+		resp.Decode(resp.Integer(1), &hash),
+		"ERR cannot decode integer into map[string]int",
+	)
+}
@@ -55,7 +55,7 @@ func (a hashedArray[T]) forEach(fn func(T) error) error {
 }
 
 func (a hashedArray[T]) marshal(w io.Writer) error {
-	if err := writeInt(len(a), w); err != nil {
+	if err := writeInt(a.count(), w); err != nil {
 		return err
 	}
 	return a.forEach(func(v T) error {
@@ -0,0 +1,141 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// readerLimits bound what a Reader accepts, so that hostile input can't
+// exhaust memory. A limit of zero means that it isn't enforced
+type readerLimits struct {
+	maxBulkLength     int
+	maxAggregateCount int
+	maxNesting        int
+	maxLineLength     int
+	maxRequestSize    int
+}
+
+// Default limits, as applied by DefaultLimits
+const (
+	DefaultMaxBulkLength     = 512 * 1024 * 1024
+	DefaultMaxAggregateCount = 1024 * 1024
+	DefaultMaxNesting        = 32
+	DefaultMaxLineLength     = 64 * 1024
+	DefaultMaxRequestSize    = 1024 * 1024 * 1024
+)
+
+// ErrLimitExceeded is wrapped by the errors that a Reader returns when its
+// input exceeds one of its limits
+var ErrLimitExceeded = errors.New("ERR Protocol error")
+
+// Error messages
+const (
+	ErrBulkTooLong      = "%w: bulk length %d exceeds limit of %d"
+	ErrAggregateTooLong = "%w: aggregate count %d exceeds limit of %d"
+	ErrNestingTooDeep   = "%w: nesting exceeds limit of %d"
+	ErrLineTooLong      = "%w: line exceeds limit of %d bytes"
+	ErrRequestTooLarge  = "%w: request exceeds limit of %d bytes"
+)
+
+// The most elements or bytes that are allocated ahead of their arrival, so
+// that a declared length can't by itself exhaust memory
+const (
+	preallocElements = 1024
+	preallocBytes    = 64 * 1024
+)
+
+// DefaultLimits applies limits that are suited to reading requests from
+// untrusted clients
+func DefaultLimits(c *ReaderConfig) {
+	c.limits = readerLimits{
+		maxBulkLength:     DefaultMaxBulkLength,
+		maxAggregateCount: DefaultMaxAggregateCount,
+		maxNesting:        DefaultMaxNesting,
+		maxLineLength:     DefaultMaxLineLength,
+		maxRequestSize:    DefaultMaxRequestSize,
+	}
+}
+
+// WithMaxBulkLength limits the length of bulk strings, including those that
+// are streamed
+func WithMaxBulkLength(n int) ReaderOption {
+	return func(c *ReaderConfig) {
+		c.limits.maxBulkLength = n
+	}
+}
+
+// WithMaxAggregateCount limits the number of elements in an Array, Set or
+// Push, and the number of pairs in a Map or Attribute
+func WithMaxAggregateCount(n int) ReaderOption {
+	return func(c *ReaderConfig) {
+		c.limits.maxAggregateCount = n
+	}
+}
+
+// WithMaxNesting limits the number of aggregates that may enclose a value
+func WithMaxNesting(n int) ReaderOption {
+	return func(c *ReaderConfig) {
+		c.limits.maxNesting = n
+	}
+}
+
+// WithMaxLineLength limits the length of a line, such as a simple string or
+// the length that precedes a bulk string, excluding its terminator
+func WithMaxLineLength(n int) ReaderOption {
+	return func(c *ReaderConfig) {
+		c.limits.maxLineLength = n
+	}
+}
+
+// WithMaxRequestSize limits the number of bytes that a single top-level
+// value may span
+func WithMaxRequestSize(n int) ReaderOption {
+	return func(c *ReaderConfig) {
+		c.limits.maxRequestSize = n
+	}
+}
+
+// consume counts bytes toward the size of the top-level value being read
+func (r *Reader) consume(n int) error {
+	r.size += n
+	if limit := r.limits.maxRequestSize; limit != 0 && r.size > limit {
+		return fmt.Errorf(ErrRequestTooLarge, ErrLimitExceeded, limit)
+	}
+	return nil
+}
+
+func (r *Reader) checkBulkLength(l int) error {
+	if limit := r.limits.maxBulkLength; limit != 0 && l > limit {
+		return fmt.Errorf(ErrBulkTooLong, ErrLimitExceeded, l, limit)
+	}
+	return nil
+}
+
+func (r *Reader) checkAggregateCount(l int) error {
+	if limit := r.limits.maxAggregateCount; limit != 0 && l > limit {
+		return fmt.Errorf(ErrAggregateTooLong, ErrLimitExceeded, l, limit)
+	}
+	return nil
+}
+
+func (r *Reader) checkNesting() error {
+	if limit := r.limits.maxNesting; limit != 0 && r.nesting > limit {
+		return fmt.Errorf(ErrNestingTooDeep, ErrLimitExceeded, limit)
+	}
+	return nil
+}
+
+func (r *Reader) checkLineLength(l int) error {
+	if limit := r.limits.maxLineLength; limit != 0 && l > limit {
+		return fmt.Errorf(ErrLineTooLong, ErrLimitExceeded, limit)
+	}
+	return nil
+}
+
+// preallocSize returns the capacity to allocate for a declared length
+func preallocSize(l int) int {
+	if l > preallocElements {
+		return preallocElements
+	}
+	return l
+}
@@ -0,0 +1,88 @@
+package resp_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderLimits(t *testing.T) {
+	as := assert.New(t)
+
+	testCases := []struct {
+		input string
+		opt   resp.ReaderOption
+		err   string
+	}{
+		{
+			"$2147483647\r\n", resp.WithMaxBulkLength(16),
+			"bulk length 2147483647 exceeds limit of 16",
+		},
+		{
+			"$?\r\n;10\r\n0123456789\r\n;10\r\n0123456789\r\n;0\r\n",
+			resp.WithMaxBulkLength(16),
+			"bulk length 20 exceeds limit of 16",
+		},
+		{
+			"*2147483647\r\n", resp.WithMaxAggregateCount(4),
+			"aggregate count 2147483647 exceeds limit of 4",
+		},
+		{
+			"%5\r\n", resp.WithMaxAggregateCount(4),
+			"aggregate count 5 exceeds limit of 4",
+		},
+		{
+			"~?\r\n:1\r\n:2\r\n:3\r\n.\r\n", resp.WithMaxAggregateCount(2),
+			"aggregate count 3 exceeds limit of 2",
+		},
+		{
+			"*1\r\n*1\r\n*1\r\n:1\r\n", resp.WithMaxNesting(2),
+			"nesting exceeds limit of 2",
+		},
+		{
+			"+" + strings.Repeat("a", 8192) + "\r\n",
+			resp.WithMaxLineLength(16),
+			"line exceeds limit of 16 bytes",
+		},
+		{
+			"*3\r\n$4\r\nabcd\r\n$4\r\nabcd\r\n$4\r\nabcd\r\n",
+			resp.WithMaxRequestSize(24),
+			"request exceeds limit of 24 bytes",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := resp.ReadString(tc.input, tc.opt)
+		as.ErrorIs(err, resp.ErrLimitExceeded, tc.input)
+		as.ErrorContains(err, tc.err)
+		as.True(strings.HasPrefix(err.Error(), "ERR Protocol error: "))
+	}
+}
+
+func TestReaderWithinLimits(t *testing.T) {
+	as := assert.New(t)
+	input := "*2\r\n$4\r\nabcd\r\n*1\r\n+ok\r\n"
+
+	r := resp.NewReader(
+		bufio.NewReader(strings.NewReader(strings.Repeat(input, 3))),
+		resp.DefaultLimits,
+		resp.WithMaxNesting(2),
+		resp.WithMaxRequestSize(len(input)),
+	)
+	for i := 0; i < 3; i++ {
+		v, err := r.Next()
+		as.Nil(err)
+		as.Equal(input, resp.ToString(v))
+	}
+
+	// Large bulk strings are read as they arrive
+	large := strings.Repeat("x", 1024*1024)
+	v, err := resp.ReadString(
+		resp.ToString(resp.BulkString(large)), resp.DefaultLimits,
+	)
+	as.Nil(err)
+	as.Equal(resp.BulkString(large), v)
+}
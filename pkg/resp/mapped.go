@@ -29,25 +29,32 @@ func newMapped(size int) mapped {
 }
 
 func readMapped(r *Reader) (*mapped, error) {
-	resLen, err := r.readLen()
+	count, err := r.readAggregateCount()
 	if err != nil {
 		return nil, err
 	}
-	res := &mapped{
-		data: makeHashedArray[*mappedPair](resLen),
+	var pairs [][2]Value
+	if count != streamed {
+		pairs = make([][2]Value, 0, preallocSize(count))
 	}
-	for i := 0; i < resLen; i++ {
+	err = r.readElements(count, func() error {
 		key, err := r.Next()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		val, err := r.Next()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		res.put(key, val)
+		pairs = append(pairs, [2]Value{key, val})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return res, nil
+	res := newMapped(len(pairs))
+	makeFromPairs(&res, pairs...)
+	return &res, nil
 }
 
 func makeFromMap[K MakeMappedKey, V Value](m *mapped, p map[K]V) {
@@ -14,11 +14,14 @@ type (
 		input *bufio.Reader
 		ReaderConfig
 		nesting int
+		size    int
 	}
 
 	ReaderConfig struct {
 		readers      map[Tag]ReaderFunc
 		v2Compatible bool
+		attributes   bool
+		limits       readerLimits
 	}
 
 	ReaderOption func(*ReaderConfig)
@@ -29,15 +32,25 @@ type (
 const (
 	CR byte = 13
 	LF byte = 10
+
+	// StreamedLen replaces the length of a RESP3 streamed value, whose
+	// chunks or elements follow until a StreamEnd or an empty StreamChunk
+	StreamedLen byte = '?'
+	StreamChunk byte = ';'
+	StreamEnd   byte = '.'
 )
 
+// streamed is the count reported for a streamed value
+const streamed = -1
+
 // Error messages
 const (
-	ErrEmptyInput        = "ERR empty input: %e"
+	ErrEmptyInput        = "ERR empty input: %w"
 	ErrUnknownTag        = "ERR unknown tag: %s"
 	ErrInvalidNesting    = "ERR invalid nesting: %s"
 	ErrInvalidLength     = "ERR invalid length: %d"
 	ErrInvalidTerminator = "ERR invalid terminator: %v"
+	ErrInvalidChunk      = "ERR invalid streamed string chunk: %q"
 )
 
 var (
@@ -81,8 +94,14 @@ var DefaultReaders = WithReaderFuncs(map[Tag]ReaderFunc{
 })
 
 // V2Compatible enables V2 compatibility mode
-func V2Compatible(r *Reader) {
-	r.v2Compatible = true
+func V2Compatible(c *ReaderConfig) {
+	c.v2Compatible = true
+}
+
+// PreserveAttributes causes the Reader to return the values that follow an
+// Attribute as Attributed values, rather than discarding the Attribute
+func PreserveAttributes(c *ReaderConfig) {
+	c.attributes = true
 }
 
 func WithReaderFuncs(m map[Tag]ReaderFunc) ReaderOption {
@@ -100,10 +119,23 @@ func initConfig(c *ReaderConfig) {
 
 // Next returns the next parsed value from the RESP ReaderFunc, or an error
 func (r *Reader) Next() (Value, error) {
+	if r.nesting == 0 {
+		r.size = 0
+	}
+	return r.next()
+}
+
+func (r *Reader) next() (Value, error) {
+	if err := r.checkNesting(); err != nil {
+		return nil, err
+	}
 	t, err := r.input.ReadByte()
 	if err != nil {
 		return nil, fmt.Errorf(ErrEmptyInput, err)
 	}
+	if err := r.consume(1); err != nil {
+		return nil, err
+	}
 	tag := Tag(t)
 	if r.isV2Null(tag) {
 		return NullValue, nil
@@ -118,43 +150,65 @@ func (r *Reader) Next() (Value, error) {
 				return nil, fmt.Errorf(ErrInvalidNesting, tag)
 			}
 		}
-		if tag == AttributeTag {
-			return r.Next()
+		if a, ok := res.(*Attribute); ok && err == nil {
+			return r.nextAttributed(a)
 		}
 		return res, err
 	}
 	return nil, fmt.Errorf(ErrUnknownTag, tag)
 }
 
+// nextAttributed reads the Value that follows an Attribute, pairing the two
+// if the Reader preserves Attributes
+func (r *Reader) nextAttributed(attr *Attribute) (Value, error) {
+	res, err := r.next()
+	if err != nil || !r.attributes {
+		return res, err
+	}
+	return MakeAttributed(attr, res), nil
+}
+
+// readSimple reads a line, excluding its terminator. The line is read a
+// buffer at a time so that its length can be limited as it arrives
 func (r *Reader) readSimple() ([]byte, error) {
-	var buf bytes.Buffer
+	var res []byte
 	for {
-		data, err := r.input.ReadBytes(LF)
-		if err != nil {
+		data, err := r.input.ReadSlice(LF)
+		if err != nil && err != bufio.ErrBufferFull {
 			return nil, err
 		}
-		ld := len(data) - 2
-		if ld >= 0 && data[ld] == CR {
-			pfx := data[:ld]
-			if buf.Len() == 0 {
-				return pfx, nil
-			}
-			return append(buf.Bytes(), pfx...), nil
+		if err := r.consume(len(data)); err != nil {
+			return nil, err
+		}
+		res = append(res, data...)
+		ld := len(res) - 2
+		if err := r.checkLineLength(ld); err != nil {
+			return nil, err
+		}
+		if err == nil && ld >= 0 && res[ld] == CR {
+			return res[:ld], nil
 		}
-		buf.Write(data)
 	}
 }
 
 func (r *Reader) readBulk() ([]byte, error) {
-	l, err := r.readLen()
+	l, err := r.readCount()
 	if err != nil {
 		if l == -1 {
 			return nil, nil
 		}
 		return nil, err
 	}
-	data := make([]byte, l)
-	_, err = io.ReadFull(r.input, data)
+	if l == streamed {
+		return r.readChunks()
+	}
+	if err := r.checkBulkLength(l); err != nil {
+		return nil, err
+	}
+	if err := r.consume(l); err != nil {
+		return nil, err
+	}
+	data, err := r.readN(l)
 	if err != nil {
 		return nil, err
 	}
@@ -164,24 +218,157 @@ func (r *Reader) readBulk() ([]byte, error) {
 	return data, nil
 }
 
+// readN reads l bytes. Larger lengths are read into a buffer that grows as
+// the bytes arrive, rather than being allocated up front
+func (r *Reader) readN(l int) ([]byte, error) {
+	if l <= preallocBytes {
+		res := make([]byte, l)
+		if _, err := io.ReadFull(r.input, res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(preallocBytes)
+	if _, err := io.CopyN(&buf, r.input, int64(l)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readChunks assembles the chunks of a streamed string, which end with a
+// chunk of zero length
+func (r *Reader) readChunks() ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		t, err := r.input.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if t != StreamChunk {
+			return nil, fmt.Errorf(ErrInvalidChunk, t)
+		}
+		l, err := r.readLen()
+		if err != nil {
+			return nil, err
+		}
+		if l == 0 {
+			return buf.Bytes(), nil
+		}
+		if err := r.checkBulkLength(buf.Len() + l); err != nil {
+			return nil, err
+		}
+		if err := r.consume(1 + l); err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(&buf, r.input, int64(l)); err != nil {
+			return nil, err
+		}
+		if err := r.readNewline(); err != nil {
+			return nil, err
+		}
+	}
+}
+
 func (r *Reader) readValues() (Values, error) {
-	s, err := r.readLen()
+	s, err := r.readAggregateCount()
 	if err != nil {
 		return nil, err
 	}
-	res := make(Values, s)
-	for i := 0; i < s; i++ {
+	res := Values{}
+	if s != streamed {
+		res = make(Values, 0, preallocSize(s))
+	}
+	err = r.readElements(s, func() error {
 		val, err := r.Next()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		res[i] = val
+		res = append(res, val)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readElements calls fn once for each element of an aggregate. If the
+// aggregate is streamed, fn is called until the StreamEnd is reached
+func (r *Reader) readElements(count int, fn func() error) error {
+	if count != streamed {
+		for i := 0; i < count; i++ {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i := 1; ; i++ {
+		end, err := r.atStreamEnd()
+		if err != nil || end {
+			return err
+		}
+		if err := r.checkAggregateCount(i); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Reader) atStreamEnd() (bool, error) {
+	b, err := r.input.Peek(1)
+	if err != nil {
+		return false, err
+	}
+	if b[0] != StreamEnd {
+		return false, nil
+	}
+	_, _ = r.input.Discard(1)
+	if err := r.consume(1); err != nil {
+		return false, err
+	}
+	return true, r.readNewline()
+}
+
+// readAggregateCount reads the count of an aggregate's elements, which may
+// be streamed
+func (r *Reader) readAggregateCount() (int, error) {
+	res, err := r.readCount()
+	if err != nil {
+		return 0, err
+	}
+	if err := r.checkAggregateCount(res); err != nil {
+		return 0, err
 	}
 	return res, nil
 }
 
+// readCount reads the length of a value that may be streamed, returning
+// streamed in its place if it is
+func (r *Reader) readCount() (int, error) {
+	data, err := r.readSimple()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 1 && data[0] == StreamedLen {
+		return streamed, nil
+	}
+	return parseLen(data)
+}
+
 func (r *Reader) readLen() (int, error) {
-	i, err := r.readInt64()
+	data, err := r.readSimple()
+	if err != nil {
+		return 0, err
+	}
+	return parseLen(data)
+}
+
+func parseLen(data []byte) (int, error) {
+	i, err := strconv.ParseInt(string(data), 10, 64)
 	if err != nil {
 		return 0, err
 	}
@@ -205,6 +392,9 @@ func (r *Reader) readInt64() (int64, error) {
 }
 
 func (r *Reader) readNewline() error {
+	if err := r.consume(len(NewLine)); err != nil {
+		return err
+	}
 	data := make([]byte, 2)
 	if _, err := io.ReadFull(r.input, data); err != nil {
 		return err
@@ -219,6 +409,11 @@ func (r *Reader) isV2Null(tag Tag) bool {
 	if !r.v2Compatible || !v2Nullable(tag) {
 		return false
 	}
+	// Only peek past the sign once it's present, as a shorter value may be
+	// the last thing the peer has sent
+	if sign, err := r.input.Peek(1); err != nil || sign[0] != v2Null[0] {
+		return false
+	}
 	data, err := r.input.Peek(v2NullLen)
 	if err == nil && bytes.Equal(data, v2Null) {
 		_, _ = r.input.Discard(v2NullLen)
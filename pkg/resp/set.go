@@ -28,21 +28,11 @@ var _ interface {
 } = (*Set)(nil)
 
 func readSet(r *Reader) (*Set, error) {
-	resLen, err := r.readLen()
+	res, err := r.readValues()
 	if err != nil {
 		return nil, err
 	}
-	res := &Set{
-		data: makeHashedArray[Value](resLen),
-	}
-	for i := 0; i < resLen; i++ {
-		v, err := r.Next()
-		if err != nil {
-			return nil, err
-		}
-		res.data.put(v)
-	}
-	return res, nil
+	return MakeSet(res...), nil
 }
 
 func MakeSet(v ...Value) *Set {
@@ -68,14 +58,10 @@ func (s *Set) Marshal(w io.Writer) error {
 
 func (s *Set) Elements() Values {
 	res := make(Values, 0, len(s.data))
-	for _, e := range s.data {
-	next:
-		res = append(res, e.ref)
-		if e.next != nil {
-			e = e.next
-			goto next
-		}
-	}
+	_ = s.data.forEach(func(v Value) error {
+		res = append(res, v)
+		return nil
+	})
 	return res
 }
 
@@ -42,3 +42,18 @@ func TestSet(t *testing.T) {
 		}
 	}
 }
+
+func TestSetCollisions(t *testing.T) {
+	as := assert.New(t)
+	elems := make([]resp.Value, 100)
+	for i := range elems {
+		elems[i] = resp.Integer(i)
+	}
+	s := resp.MakeSet(append(elems, elems[:10]...)...)
+	as.Equal(100, s.Count())
+	as.ElementsMatch(elems, s.Elements())
+
+	v, err := resp.ReadString(resp.ToString(s))
+	as.Nil(err)
+	as.ElementsMatch(elems, v.(*resp.Set).Elements())
+}
@@ -0,0 +1,116 @@
+package resp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadStreamed(t *testing.T) {
+	as := assert.New(t)
+
+	testCases := []struct {
+		input    string
+		expected resp.Value
+	}{
+		{"$?\r\n;0\r\n", resp.BulkString("")},
+		{
+			"$?\r\n;4\r\nHell\r\n;5\r\no wor\r\n;1\r\nd\r\n;0\r\n",
+			resp.BulkString("Hello word"),
+		},
+		{"*?\r\n.\r\n", resp.EmptyArray},
+		{
+			"*?\r\n:1\r\n*?\r\n+a\r\n.\r\n$?\r\n;1\r\nb\r\n;0\r\n.\r\n",
+			resp.MakeArray(
+				resp.Integer(1),
+				resp.MakeArray(resp.SimpleString("a")),
+				resp.BulkString("b"),
+			),
+		},
+		{
+			"%?\r\n+a\r\n:1\r\n+b\r\n:2\r\n.\r\n",
+			resp.MakeMapFromPairs(
+				[2]resp.Value{resp.SimpleString("a"), resp.Integer(1)},
+				[2]resp.Value{resp.SimpleString("b"), resp.Integer(2)},
+			),
+		},
+		{"~?\r\n:1\r\n:2\r\n.\r\n", resp.MakeSet(
+			resp.Integer(1), resp.Integer(2),
+		)},
+		{
+			">?\r\n+message\r\n.\r\n",
+			resp.MakePush(resp.SimpleString("message")),
+		},
+	}
+
+	for _, tc := range testCases {
+		v, err := resp.ReadString(tc.input)
+		as.Nil(err)
+		as.True(tc.expected.Equal(v), "%q", tc.input)
+	}
+
+	_, err := resp.ReadString("$?\r\n:4\r\nHell\r\n")
+	as.EqualError(err, `ERR invalid streamed string chunk: ':'`)
+	_, err = resp.ReadString("*?\r\n:1\r\n")
+	as.NotNil(err)
+	_, err = resp.ReadString("*?\r\n.\n")
+	as.NotNil(err)
+}
+
+func TestWriteStreamed(t *testing.T) {
+	as := assert.New(t)
+	var sb strings.Builder
+	w := resp.NewWriter(&sb)
+
+	as.Nil(w.WriteArrayHeader(2))
+	as.Nil(w.WriteStreamedMapHeader())
+	as.Nil(w.WriteString("key"))
+	as.EqualError(w.WriteStreamEnd(), resp.ErrMissingMapValue)
+	as.Nil(w.WriteStreamedStringHeader())
+	as.EqualError(w.WriteInteger(1), resp.ErrExpectedChunk)
+	as.Nil(w.WriteChunk([]byte("val")))
+	as.Nil(w.WriteChunk(nil))
+	as.Nil(w.WriteChunk([]byte("ue")))
+	as.Nil(w.WriteStreamEnd())
+	as.EqualError(w.Finish(), resp.ErrUnterminatedStream)
+	as.Nil(w.WriteStreamEnd())
+	as.Nil(w.WriteStreamedSetHeader())
+	as.EqualError(w.WriteChunk([]byte("x")), resp.ErrNotChunked)
+	as.Nil(w.WriteStreamEnd())
+	as.EqualError(w.WriteStreamEnd(), resp.ErrNotStreaming)
+	as.Nil(w.Finish())
+
+	as.Equal(
+		"*2\r\n%?\r\n$3\r\nkey\r\n$?\r\n;3\r\nval\r\n;2\r\nue\r\n;0\r\n"+
+			".\r\n~?\r\n.\r\n",
+		sb.String(),
+	)
+	v, err := resp.ReadString(sb.String())
+	as.Nil(err)
+	as.Equal(2, v.(*resp.Array).Count())
+}
+
+func TestWriteStreamedV2(t *testing.T) {
+	as := assert.New(t)
+	var sb strings.Builder
+	w := resp.NewWriter(&sb, resp.V2Encoding)
+
+	as.Nil(w.WriteStreamedArrayHeader())
+	as.Nil(w.WriteStreamedStringHeader())
+	as.Nil(w.WriteChunk([]byte("a")))
+	as.Nil(w.WriteChunk([]byte("b")))
+	as.Nil(w.WriteStreamEnd())
+	as.Nil(w.WriteStreamedMapHeader())
+	as.Nil(w.WriteString("key"))
+	as.Nil(w.WriteBoolean(true))
+	as.Nil(w.WriteStreamEnd())
+	as.Nil(w.WriteStreamEnd())
+	as.Nil(w.Finish())
+
+	as.Equal(
+		"*2\r\n$2\r\nab\r\n*2\r\n$3\r\nkey\r\n:1\r\n",
+		sb.String(),
+	)
+}
@@ -0,0 +1,98 @@
+package resp
+
+import (
+	"io"
+	"math"
+	"strings"
+)
+
+var (
+	v2NullBulk = []byte{byte(BulkStringTag), '-', '1', CR, LF}
+	v2True     = Integer(1)
+	v2False    = Integer(0)
+)
+
+// MarshalV2 writes the RESP2 representation of a Value to the provided
+// io.Writer. RESP3 types are downgraded to the encodings that RESP2 clients
+// expect in their place: Maps are flattened into Arrays of alternating keys
+// and values, Sets and Pushes become Arrays, Booleans become Integers, Null
+// becomes a null bulk string, and the remaining scalar types become bulk
+// strings. Attributes have no RESP2 representation, and are dropped
+func MarshalV2(v Value, w io.Writer) error {
+	switch v := v.(type) {
+	case *Attribute:
+		return nil
+	case *Attributed:
+		return MarshalV2(v.Value, w)
+	case Null:
+		_, err := w.Write(v2NullBulk)
+		return err
+	case Boolean:
+		if v {
+			return v2True.Marshal(w)
+		}
+		return v2False.Marshal(w)
+	case Double:
+		return BulkString(formatV2Double(v)).Marshal(w)
+	case *BigNumber:
+		return BulkString(v.String()).Marshal(w)
+	case *VerbatimString:
+		return BulkString(v.String()).Marshal(w)
+	case *BulkError:
+		return marshalV2Error(v, w)
+	case Mapped:
+		return marshalV2Mapped(v, w)
+	case *Array, *Set, *Push:
+		return marshalV2Values(v.(Collection).Elements(), w)
+	default:
+		return v.Marshal(w)
+	}
+}
+
+func marshalV2Error(e *BulkError, w io.Writer) error {
+	msg := strings.NewReplacer("\r", " ", "\n", " ").Replace(e.Error())
+	return MakeSimpleError(msg).Marshal(w)
+}
+
+func marshalV2Mapped(m Mapped, w io.Writer) error {
+	if _, err := w.Write([]byte{byte(ArrayTag)}); err != nil {
+		return err
+	}
+	if err := writeInt(m.Count()*2, w); err != nil {
+		return err
+	}
+	return m.ForEach(func(k, v Value) error {
+		if err := MarshalV2(k, w); err != nil {
+			return err
+		}
+		return MarshalV2(v, w)
+	})
+}
+
+func marshalV2Values(arr Values, w io.Writer) error {
+	if _, err := w.Write([]byte{byte(ArrayTag)}); err != nil {
+		return err
+	}
+	if err := writeLen(arr, w); err != nil {
+		return err
+	}
+	for _, v := range arr {
+		if err := MarshalV2(v, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatV2Double(d Double) string {
+	switch f := float64(d); {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	default:
+		return d.String()
+	}
+}
@@ -0,0 +1,79 @@
+package resp_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalV2(t *testing.T) {
+	as := assert.New(t)
+
+	big, _ := resp.MakeBigNumber("12345678901234567890")
+	verbatim, _ := resp.MakeVerbatimString("txt", "hello")
+
+	testCases := []struct {
+		value    resp.Value
+		expected string
+	}{
+		{resp.NullValue, "$-1\r\n"},
+		{resp.Boolean(true), ":1\r\n"},
+		{resp.Boolean(false), ":0\r\n"},
+		{resp.Double(1.5), "$3\r\n1.5\r\n"},
+		{resp.Double(math.Inf(1)), "$3\r\ninf\r\n"},
+		{resp.Double(math.Inf(-1)), "$4\r\n-inf\r\n"},
+		{big, "$20\r\n12345678901234567890\r\n"},
+		{verbatim, "$5\r\nhello\r\n"},
+		{resp.MakeBulkError("ERR bad\nthing"), "-ERR bad thing\r\n"},
+		{resp.MakeSimpleError("ERR bad"), "-ERR bad\r\n"},
+		{resp.BulkString("hello"), "$5\r\nhello\r\n"},
+		{resp.SimpleString("OK"), "+OK\r\n"},
+		{resp.Integer(42), ":42\r\n"},
+		{
+			resp.MakeArray(resp.NullValue, resp.Boolean(true)),
+			"*2\r\n$-1\r\n:1\r\n",
+		},
+		{
+			resp.MakePush(resp.BulkString("message"), resp.Double(2)),
+			"*2\r\n$7\r\nmessage\r\n$1\r\n2\r\n",
+		},
+		{resp.MakeSet(resp.Integer(1)), "*1\r\n:1\r\n"},
+		{
+			resp.MakeMapFromPairs(
+				[2]resp.Value{resp.BulkString("key"), resp.Boolean(false)},
+			),
+			"*2\r\n$3\r\nkey\r\n:0\r\n",
+		},
+		{
+			resp.MakeAttributeFromPairs(
+				[2]resp.Value{resp.BulkString("key"), resp.Integer(1)},
+			),
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		var sb strings.Builder
+		as.Nil(resp.MarshalV2(tc.value, &sb))
+		as.Equal(tc.expected, sb.String())
+	}
+}
+
+func TestMarshalV2Readable(t *testing.T) {
+	as := assert.New(t)
+
+	m := resp.MakeMapFromPairs(
+		[2]resp.Value{resp.BulkString("a"), resp.NullValue},
+		[2]resp.Value{resp.BulkString("b"), resp.MakeSet(resp.Double(1))},
+	)
+	var sb strings.Builder
+	as.Nil(resp.MarshalV2(m, &sb))
+
+	v, err := resp.ReadString(sb.String(), resp.V2Compatible)
+	as.Nil(err)
+	as.Equal(resp.ArrayTag, v.Tag())
+	as.Equal(4, v.(resp.Counted).Count())
+}
@@ -0,0 +1,370 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+type (
+	// Writer streams RESP values to an io.Writer without first building them
+	// in memory. Aggregates are written as a header that promises a count of
+	// elements, followed by those elements, and the Writer checks that each
+	// aggregate receives exactly the elements it promised. RESP3 streamed
+	// strings and aggregates, whose size needn't be known up front, are
+	// instead written until they're ended
+	Writer struct {
+		out io.Writer
+		WriterConfig
+
+		// pending holds the aggregates that are still open, innermost last
+		pending []*aggregate
+		values  int
+	}
+
+	// aggregate tracks the elements written to an open aggregate. RESP2 has
+	// no streamed values, so when encoding for it, a streamed value's output
+	// is buffered until its size is known
+	aggregate struct {
+		remaining int
+		count     int
+		streamed  bool
+		chunked   bool
+		pairs     bool
+		out       io.Writer
+		buf       *bytes.Buffer
+	}
+
+	WriterConfig struct {
+		v2    bool
+		limit int
+	}
+
+	WriterOption func(*WriterConfig)
+)
+
+// Error messages
+const (
+	ErrInvalidCount        = "ERR invalid aggregate count: %d"
+	ErrTooManyValues       = "ERR writer is limited to %d value(s)"
+	ErrMissingValues       = "ERR writer expected %d value(s), got %d"
+	ErrIncompleteAggregate = "ERR incomplete aggregate: %d element(s) missing"
+	ErrUnterminatedStream  = "ERR streamed value hasn't been ended"
+	ErrNotStreaming        = "ERR no streamed value to end"
+	ErrNotChunked          = "ERR chunks require a streamed string"
+	ErrExpectedChunk       = "ERR streamed strings only accept chunks"
+	ErrMissingMapValue     = "ERR streamed map is missing a value"
+)
+
+// NewWriter configures a new RESP Writer
+func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
+	res := &Writer{out: w}
+	for _, opt := range opts {
+		opt(&res.WriterConfig)
+	}
+	return res
+}
+
+// V2Encoding causes the Writer to downgrade RESP3 values to the encodings
+// that RESP2 clients expect in their place, as MarshalV2 does
+func V2Encoding(c *WriterConfig) {
+	c.v2 = true
+}
+
+// SingleValue limits the Writer to writing exactly one top-level value
+func SingleValue(c *WriterConfig) {
+	c.limit = 1
+}
+
+// WriteArrayHeader begins an Array of n elements
+func (w *Writer) WriteArrayHeader(n int) error {
+	return w.writeHeader(ArrayTag, n, n)
+}
+
+// WriteSetHeader begins a Set of n elements
+func (w *Writer) WriteSetHeader(n int) error {
+	if w.v2 {
+		return w.writeHeader(ArrayTag, n, n)
+	}
+	return w.writeHeader(SetTag, n, n)
+}
+
+// WritePushHeader begins a Push of n elements
+func (w *Writer) WritePushHeader(n int) error {
+	if w.v2 {
+		return w.writeHeader(ArrayTag, n, n)
+	}
+	return w.writeHeader(PushTag, n, n)
+}
+
+// WriteMapHeader begins a Map of n pairs, which must be followed by a key
+// and a value for each pair
+func (w *Writer) WriteMapHeader(n int) error {
+	if w.v2 {
+		return w.writeHeader(ArrayTag, n*2, n*2)
+	}
+	return w.writeHeader(MapTag, n, n*2)
+}
+
+// WriteStreamedArrayHeader begins an Array of unknown length, which must be
+// ended using WriteStreamEnd once its elements have been written
+func (w *Writer) WriteStreamedArrayHeader() error {
+	return w.writeStreamedHeader(ArrayTag, false)
+}
+
+// WriteStreamedSetHeader begins a Set of unknown length
+func (w *Writer) WriteStreamedSetHeader() error {
+	return w.writeStreamedHeader(SetTag, false)
+}
+
+// WriteStreamedMapHeader begins a Map of unknown length, which must be
+// followed by a key and a value for each pair
+func (w *Writer) WriteStreamedMapHeader() error {
+	return w.writeStreamedHeader(MapTag, true)
+}
+
+// WriteStreamedStringHeader begins a BulkString of unknown length, which
+// must be followed by its chunks and then ended using WriteStreamEnd
+func (w *Writer) WriteStreamedStringHeader() error {
+	return w.write(func() error {
+		w.beginStream(&aggregate{
+			streamed: true,
+			chunked:  true,
+		})
+		return w.writeStreamedTag(BulkStringTag)
+	})
+}
+
+// WriteChunk writes a chunk of a streamed string. Empty chunks are skipped,
+// as one would otherwise end the string
+func (w *Writer) WriteChunk(data []byte) error {
+	a := w.top()
+	if a == nil || !a.chunked {
+		return fmt.Errorf(ErrNotChunked)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if w.v2 {
+		_, err := w.out.Write(data)
+		return err
+	}
+	return writeBulk(Tag(StreamChunk), data, w.out)
+}
+
+// WriteStreamEnd ends the innermost streamed string or aggregate
+func (w *Writer) WriteStreamEnd() error {
+	a := w.top()
+	if a == nil || !a.streamed {
+		return fmt.Errorf(ErrNotStreaming)
+	}
+	if a.pairs && a.count%2 != 0 {
+		return fmt.Errorf(ErrMissingMapValue)
+	}
+	w.pending = w.pending[:len(w.pending)-1]
+	if err := w.endStream(a); err != nil {
+		return err
+	}
+	w.closeCompleted()
+	return nil
+}
+
+// WriteBulkString writes a BulkString
+func (w *Writer) WriteBulkString(data []byte) error {
+	return w.write(func() error {
+		return writeBulk(BulkStringTag, data, w.out)
+	})
+}
+
+// WriteString writes a string as a BulkString
+func (w *Writer) WriteString(s string) error {
+	return w.WriteBulkString([]byte(s))
+}
+
+// WriteSimpleString writes a SimpleString
+func (w *Writer) WriteSimpleString(s string) error {
+	return w.write(func() error {
+		return writeSimple(SimpleStringTag, []byte(s), w.out)
+	})
+}
+
+// WriteInteger writes an Integer
+func (w *Writer) WriteInteger(i int64) error {
+	return w.write(func() error {
+		return Integer(i).Marshal(w.out)
+	})
+}
+
+// WriteDouble writes a Double
+func (w *Writer) WriteDouble(f float64) error {
+	return w.write(func() error {
+		return w.marshal(Double(f))
+	})
+}
+
+// WriteBoolean writes a Boolean
+func (w *Writer) WriteBoolean(b bool) error {
+	return w.write(func() error {
+		return w.marshal(Boolean(b))
+	})
+}
+
+// WriteNull writes Null
+func (w *Writer) WriteNull() error {
+	return w.write(func() error {
+		return w.marshal(NullValue)
+	})
+}
+
+// WriteValue writes a complete Value as a single element
+func (w *Writer) WriteValue(v Value) error {
+	if _, ok := v.(TopLevelOnly); ok && len(w.pending) != 0 {
+		return fmt.Errorf(ErrInvalidNesting, v.Tag())
+	}
+	return w.write(func() error {
+		return w.marshal(v)
+	})
+}
+
+// Started reports whether the Writer has begun writing a value
+func (w *Writer) Started() bool {
+	return w.values != 0
+}
+
+// Finish checks that every aggregate has received the elements that it
+// promised, and that the expected number of values has been written
+func (w *Writer) Finish() error {
+	if len(w.pending) != 0 {
+		missing := 0
+		for _, a := range w.pending {
+			if a.streamed {
+				return fmt.Errorf(ErrUnterminatedStream)
+			}
+			missing += a.remaining
+		}
+		return fmt.Errorf(ErrIncompleteAggregate, missing)
+	}
+	if w.limit != 0 && w.values != w.limit {
+		return fmt.Errorf(ErrMissingValues, w.limit, w.values)
+	}
+	return nil
+}
+
+func (w *Writer) writeHeader(t Tag, n, elements int) error {
+	if n < 0 {
+		return fmt.Errorf(ErrInvalidCount, n)
+	}
+	return w.write(func() error {
+		if _, err := w.out.Write([]byte{byte(t)}); err != nil {
+			return err
+		}
+		if err := writeInt(n, w.out); err != nil {
+			return err
+		}
+		if elements != 0 {
+			w.pending = append(w.pending, &aggregate{remaining: elements})
+		}
+		return nil
+	})
+}
+
+func (w *Writer) writeStreamedHeader(t Tag, pairs bool) error {
+	return w.write(func() error {
+		w.beginStream(&aggregate{
+			streamed: true,
+			pairs:    pairs,
+		})
+		return w.writeStreamedTag(t)
+	})
+}
+
+// beginStream opens a streamed value, redirecting output to a buffer if
+// encoding for RESP2
+func (w *Writer) beginStream(a *aggregate) {
+	if w.v2 {
+		a.out = w.out
+		a.buf = new(bytes.Buffer)
+		w.out = a.buf
+	}
+	w.pending = append(w.pending, a)
+}
+
+func (w *Writer) writeStreamedTag(t Tag) error {
+	if w.v2 {
+		return nil
+	}
+	_, err := w.out.Write([]byte{byte(t), StreamedLen, CR, LF})
+	return err
+}
+
+// endStream terminates a streamed value. If encoding for RESP2, the value
+// is written from its buffer now that its size is known
+func (w *Writer) endStream(a *aggregate) error {
+	if !w.v2 {
+		if a.chunked {
+			return writeSimple(Tag(StreamChunk), []byte{'0'}, w.out)
+		}
+		return writeSimple(Tag(StreamEnd), nil, w.out)
+	}
+	w.out = a.out
+	if a.chunked {
+		return writeBulk(BulkStringTag, a.buf.Bytes(), w.out)
+	}
+	if _, err := w.out.Write([]byte{byte(ArrayTag)}); err != nil {
+		return err
+	}
+	if err := writeInt(a.count, w.out); err != nil {
+		return err
+	}
+	_, err := w.out.Write(a.buf.Bytes())
+	return err
+}
+
+// write counts an element toward the innermost open aggregate, or as a
+// top-level value, and then closes any aggregates that it completes
+func (w *Writer) write(fn func() error) error {
+	switch a := w.top(); {
+	case a == nil:
+		if w.limit != 0 && w.values >= w.limit {
+			return fmt.Errorf(ErrTooManyValues, w.limit)
+		}
+		w.values++
+	case a.chunked:
+		return fmt.Errorf(ErrExpectedChunk)
+	case a.streamed:
+		a.count++
+	default:
+		a.remaining--
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	w.closeCompleted()
+	return nil
+}
+
+func (w *Writer) top() *aggregate {
+	if l := len(w.pending); l != 0 {
+		return w.pending[l-1]
+	}
+	return nil
+}
+
+// closeCompleted closes the aggregates that have received every element
+// that they promised
+func (w *Writer) closeCompleted() {
+	for {
+		a := w.top()
+		if a == nil || a.streamed || a.remaining != 0 {
+			return
+		}
+		w.pending = w.pending[:len(w.pending)-1]
+	}
+}
+
+func (w *Writer) marshal(v Value) error {
+	if w.v2 {
+		return MarshalV2(v, w.out)
+	}
+	return v.Marshal(w.out)
+}
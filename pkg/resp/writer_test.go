@@ -0,0 +1,81 @@
+package resp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	as := assert.New(t)
+	var sb strings.Builder
+	w := resp.NewWriter(&sb)
+
+	as.Nil(w.WriteMapHeader(2))
+	as.Nil(w.WriteString("list"))
+	as.Nil(w.WriteArrayHeader(3))
+	as.Nil(w.WriteInteger(1))
+	as.Nil(w.WriteArrayHeader(0))
+	as.Nil(w.WriteSetHeader(1))
+	as.Nil(w.WriteBoolean(true))
+	as.Nil(w.WriteSimpleString("double"))
+	as.Nil(w.WriteDouble(1.5))
+	as.Nil(w.WriteNull())
+	as.Nil(w.Finish())
+
+	v, err := resp.ReadString(sb.String())
+	as.Nil(err)
+	m := v.(*resp.Map)
+	list, ok := m.Get(resp.BulkString("list"))
+	as.True(ok)
+	as.True(resp.MakeArray(
+		resp.Integer(1), resp.EmptyArray, resp.MakeSet(resp.True),
+	).Equal(list))
+	d, ok := m.Get(resp.SimpleString("double"))
+	as.True(ok)
+	as.Equal(resp.Double(1.5), d)
+	as.True(strings.HasSuffix(sb.String(), "_\r\n"))
+}
+
+func TestWriterV2(t *testing.T) {
+	as := assert.New(t)
+	var sb strings.Builder
+	w := resp.NewWriter(&sb, resp.V2Encoding)
+
+	as.Nil(w.WriteMapHeader(1))
+	as.Nil(w.WriteBulkString([]byte("key")))
+	as.Nil(w.WriteBoolean(false))
+	as.Nil(w.WritePushHeader(2))
+	as.Nil(w.WriteDouble(2))
+	as.Nil(w.WriteValue(resp.MakeSet(resp.NullValue)))
+	as.Nil(w.Finish())
+	as.Equal(
+		"*2\r\n$3\r\nkey\r\n:0\r\n*2\r\n$1\r\n2\r\n*1\r\n$-1\r\n",
+		sb.String(),
+	)
+}
+
+func TestWriterCounts(t *testing.T) {
+	as := assert.New(t)
+	var sb strings.Builder
+
+	w := resp.NewWriter(&sb, resp.SingleValue)
+	as.EqualError(w.WriteArrayHeader(-1), "ERR invalid aggregate count: -1")
+	as.False(w.Started())
+	as.EqualError(w.Finish(), "ERR writer expected 1 value(s), got 0")
+	as.Nil(w.WriteArrayHeader(2))
+	as.True(w.Started())
+	as.Nil(w.WriteMapHeader(1))
+	as.Nil(w.WriteInteger(1))
+	as.EqualError(
+		w.WriteValue(resp.MakeError("ERR nested")),
+		"ERR invalid nesting: simple error",
+	)
+	as.EqualError(w.Finish(), "ERR incomplete aggregate: 2 element(s) missing")
+	as.Nil(w.WriteInteger(2))
+	as.Nil(w.WriteInteger(3))
+	as.Nil(w.Finish())
+	as.EqualError(w.WriteInteger(4), "ERR writer is limited to 1 value(s)")
+}
@@ -0,0 +1,308 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type socketContext struct {
+	*Server
+
+	conn     net.Conn
+	input    *bufio.Reader
+	reader   *resp.Reader
+	writer   *bufio.Writer
+	session  *command.Session
+	handler  command.Handler
+	id       int64
+	name     string
+	protocol int32
+	state    int32
+
+	// wire is the protocol version that output is encoded for, and batching
+	// is set while a batch of pipelined commands is being processed. Both
+	// are guarded by writeLock, along with the writer
+	wire      int
+	batching  bool
+	writeLock sync.Mutex
+
+	output chan resp.Value
+	closed chan struct{}
+	done   chan struct{}
+
+	close   sync.Once
+	forward sync.Once
+}
+
+// Connection states, as tracked for graceful shutdown
+const (
+	stateIdle int32 = iota
+	stateBusy
+	stateClosing
+)
+
+// compile-time checks for interface implementation
+var (
+	_ command.ValueWriter  = (*socketContext)(nil)
+	_ command.StreamWriter = (*socketContext)(nil)
+	_ command.Described    = (*socketContext)(nil)
+)
+
+func (s *Server) makeContext(conn net.Conn) *socketContext {
+	input := bufio.NewReader(conn)
+	c := &socketContext{
+		Server: s,
+
+		conn:     conn,
+		input:    input,
+		reader:   s.MakeReader(input, s.ReaderOptions...),
+		writer:   bufio.NewWriter(conn),
+		session:  command.NewSession(),
+		id:       int64(atomic.AddUint64(&connectionIDs, 1)),
+		protocol: command.RESP2,
+		wire:     command.RESP2,
+
+		output: make(chan resp.Value),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	h := command.Wrap(command.Handlers{
+		"HELLO": c.hello,
+	}, s.Handler, helloSpec)
+	c.handler = c.tracked(h)
+	return c
+}
+
+// handleLoop reads and processes commands in the order that they arrive.
+// Replies are buffered until every command that the client has already sent
+// has been processed, so that a pipelined batch is flushed all at once. A
+// request that can't be read, such as one that exceeds the Reader's limits,
+// is answered with an error and the connection is then closed
+func (c *socketContext) handleLoop() {
+	for {
+		value, err := c.reader.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !command.IsClosed(c) {
+				c.writeError(err)
+			}
+			return
+		}
+		c.beginBatch()
+		if err := c.handle(value); err != nil {
+			c.writeError(err)
+		}
+		c.idle()
+		if c.input.Buffered() == 0 {
+			if err := c.flush(); err != nil {
+				return
+			}
+		}
+		if command.IsClosed(c) {
+			return
+		}
+	}
+}
+
+func (c *socketContext) handle(value resp.Value) error {
+	if value.Tag() != resp.ArrayTag {
+		return resp.MakeError(command.ErrExpectedArray)
+	}
+	return c.handler(c, value.(resp.Collection).Elements()...)
+}
+
+// WriteValue implements command.ValueWriter. Values written while a batch of
+// commands is being processed are flushed once the batch completes, while
+// those written at any other time, such as Pub/Sub messages, are flushed
+// immediately
+func (c *socketContext) WriteValue(value resp.Value) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if command.IsClosed(c) {
+		return errors.New(command.ErrContextClosed)
+	}
+	if pc, ok := value.(protocolChange); ok {
+		c.wire = pc.protocol
+		value = pc.Value
+	}
+	err := marshal(value, c.wire, c.writer)
+	if err == nil && !c.batching {
+		err = c.writer.Flush()
+	}
+	if err != nil {
+		_ = c.Close()
+	}
+	return err
+}
+
+// Stream implements command.StreamWriter, writing a reply to the client as
+// it's produced. If the reply fails once it has been started, the client
+// can't make sense of what follows, so the connection is closed
+func (c *socketContext) Stream(fn func(*resp.Writer) error) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if command.IsClosed(c) {
+		return errors.New(command.ErrContextClosed)
+	}
+	opts := []resp.WriterOption{resp.SingleValue}
+	if c.wire == command.RESP2 {
+		opts = append(opts, resp.V2Encoding)
+	}
+	w := resp.NewWriter(c.writer, opts...)
+	err := fn(w)
+	if err == nil {
+		err = w.Finish()
+	}
+	if err == nil && !c.batching {
+		err = c.writer.Flush()
+	}
+	if err != nil && w.Started() {
+		_ = c.Close()
+	}
+	return err
+}
+
+func (c *socketContext) beginBatch() {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.batching = true
+}
+
+// flush writes the output of a batch of commands to the client
+func (c *socketContext) flush() error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.batching = false
+	if err := c.writer.Flush(); err != nil {
+		_ = c.Close()
+		return err
+	}
+	return nil
+}
+
+// marshal writes a Value using the encoding of the provided protocol version,
+// downgrading RESP3 values for RESP2 clients
+func marshal(v resp.Value, protocol int, w io.Writer) error {
+	if protocol == command.RESP2 {
+		return resp.MarshalV2(v, w)
+	}
+	return v.Marshal(w)
+}
+
+func (c *socketContext) writeError(err error) {
+	respErr, ok := err.(resp.Value)
+	if !ok {
+		respErr = resp.MakeError(err.Error())
+	}
+	_ = c.WriteValue(respErr)
+}
+
+// tracked wraps a Handler so that the connection is marked as busy while it
+// processes a command. Commands that arrive once the connection has begun
+// closing are refused
+func (c *socketContext) tracked(h command.Handler) command.Handler {
+	return func(r command.Responder, args ...resp.Value) error {
+		if !atomic.CompareAndSwapInt32(&c.state, stateIdle, stateBusy) {
+			return errors.New(command.ErrContextClosed)
+		}
+		return h(r, args...)
+	}
+}
+
+// idle marks the connection as idle once a command has been processed,
+// closing it if the Server is shutting down
+func (c *socketContext) idle() {
+	atomic.CompareAndSwapInt32(&c.state, stateBusy, stateIdle)
+	if c.isClosing() {
+		c.closeIfIdle()
+	}
+}
+
+func (c *socketContext) closeIfIdle() {
+	if atomic.CompareAndSwapInt32(&c.state, stateIdle, stateClosing) {
+		_ = c.Close()
+	}
+}
+
+// abort closes the connection without waiting for pending output
+func (c *socketContext) abort() {
+	atomic.StoreInt32(&c.state, stateClosing)
+	_ = c.Close()
+	_ = c.conn.Close()
+}
+
+// Close signals the connection's loop to stop, interrupting any read that
+// it's waiting on. The network connection itself is closed once pending
+// output has been written
+func (c *socketContext) Close() error {
+	c.close.Do(func() {
+		close(c.closed)
+		_ = c.conn.SetReadDeadline(time.Now())
+	})
+	return nil
+}
+
+// Emit returns a channel that writes the values it receives to the client.
+// Values emitted using command.Emit are written directly instead, so the
+// goroutine that serves this channel is only started if it's requested.
+// Once the connection has closed, values sent to the channel are discarded
+func (c *socketContext) Emit() chan<- resp.Value {
+	if command.IsClosed(c) {
+		return make(chan resp.Value, 1)
+	}
+	c.forward.Do(func() {
+		go c.forwardLoop()
+	})
+	return c.output
+}
+
+// forwardLoop writes the values sent to the output channel. Once the
+// connection has closed, it discards them instead until the connection's
+// commands have all been processed, so that a Handler that sends to the
+// channel it was given can't block
+func (c *socketContext) forwardLoop() {
+	for {
+		select {
+		case <-c.closed:
+			c.discardOutput()
+			return
+		case value := <-c.output:
+			_ = c.WriteValue(value)
+		}
+	}
+}
+
+func (c *socketContext) discardOutput() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.output:
+		}
+	}
+}
+
+func (c *socketContext) Closed() <-chan struct{} {
+	return c.closed
+}
+
+func (c *socketContext) Session() *command.Session {
+	return c.session
+}
+
+// CommandSpecs describes the commands that the server processes itself,
+// rather than its Handler
+func (c *socketContext) CommandSpecs() []*command.CommandSpec {
+	return []*command.CommandSpec{helloSpec}
+}
+
+func (c *socketContext) Protocol() int {
+	return int(atomic.LoadInt32(&c.protocol))
+}
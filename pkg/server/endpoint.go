@@ -0,0 +1,217 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+type (
+	// Endpoint describes a network address that a Server listens on
+	Endpoint struct {
+		// Network is one of "tcp", "tcp4", "tcp6", "unix" or "unixpacket"
+		Network string
+		Address string
+
+		// SocketMode, if set, is applied to the file of a Unix socket once
+		// it has been created
+		SocketMode os.FileMode
+
+		// RemoveStale causes a Unix socket file left behind by a previous
+		// process to be removed before listening. A socket file that still
+		// accepts connections is never removed
+		RemoveStale bool
+	}
+
+	// EndpointOption configures an Endpoint
+	EndpointOption func(*Endpoint)
+
+	// packetListener accepts the connections of a "unixpacket" Endpoint
+	packetListener struct {
+		net.Listener
+	}
+
+	// packetConn reads a "unixpacket" connection a message at a time. A
+	// read that's shorter than a message discards the rest of it, so each
+	// message is read whole and then consumed from the buffer
+	packetConn struct {
+		*net.UnixConn
+		buf     []byte
+		pending []byte
+	}
+)
+
+// MaxPacketSize is the largest message that a "unixpacket" Endpoint
+// accepts. A client that sends a larger one is disconnected
+const MaxPacketSize = 1024 * 1024
+
+// ErrPacketTooLarge is returned when reading a message that exceeds
+// MaxPacketSize from a "unixpacket" connection
+var ErrPacketTooLarge = errors.New("packet exceeds maximum size")
+
+// WithEndpoint adds an Endpoint for the Server to listen on. Once any
+// Endpoints have been added, the configured Address is no longer listened on
+// unless it's also added as an Endpoint
+func WithEndpoint(network, address string, opts ...EndpointOption) Option {
+	e := Endpoint{
+		Network: network,
+		Address: address,
+	}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	return func(c *Config) {
+		c.Endpoints = append(c.Endpoints, e)
+	}
+}
+
+// WithUnixSocket adds a Unix socket Endpoint whose file is created with the
+// provided permissions, replacing any stale socket file at the same path
+func WithUnixSocket(path string, mode os.FileMode) Option {
+	return WithEndpoint("unix", path, WithSocketMode(mode), WithRemoveStale())
+}
+
+// WithSocketMode sets the permissions of a Unix socket Endpoint's file
+func WithSocketMode(mode os.FileMode) EndpointOption {
+	return func(e *Endpoint) {
+		e.SocketMode = mode
+	}
+}
+
+// WithRemoveStale causes a Unix socket Endpoint to remove a stale socket
+// file before listening
+func WithRemoveStale() EndpointOption {
+	return func(e *Endpoint) {
+		e.RemoveStale = true
+	}
+}
+
+// endpoints returns the Endpoints that the Server listens on
+func (s *Server) endpoints() []Endpoint {
+	if len(s.Endpoints) > 0 {
+		return s.Endpoints
+	}
+	return []Endpoint{{Network: "tcp", Address: s.Address}}
+}
+
+// listenAll listens on all of the Server's Endpoints, closing any listeners
+// already created if one of them fails
+func (s *Server) listenAll() ([]net.Listener, error) {
+	var res []net.Listener
+	for _, e := range s.endpoints() {
+		l, err := s.listen(e)
+		if err != nil {
+			for _, l := range res {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		res = append(res, l)
+	}
+	return res, nil
+}
+
+// listen creates a listener for an Endpoint. TCP listeners are wrapped with
+// the Server's TLS configuration, if one has been provided
+func (s *Server) listen(e Endpoint) (net.Listener, error) {
+	switch e.Network {
+	case "tcp", "tcp4", "tcp6":
+		l, err := net.Listen(e.Network, e.Address)
+		if err != nil {
+			return nil, err
+		}
+		if s.TLSConfig != nil {
+			return tls.NewListener(l, s.TLSConfig), nil
+		}
+		return l, nil
+	case "unix":
+		return listenUnix(e)
+	case "unixpacket":
+		l, err := listenUnix(e)
+		if err != nil {
+			return nil, err
+		}
+		return packetListener{l}, nil
+	default:
+		return nil, &net.OpError{
+			Op:  "listen",
+			Net: e.Network,
+			Err: net.UnknownNetworkError(e.Network),
+		}
+	}
+}
+
+func listenUnix(e Endpoint) (net.Listener, error) {
+	abstract := strings.HasPrefix(e.Address, "@")
+	if e.RemoveStale && !abstract {
+		if err := removeStale(e); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen(e.Network, e.Address)
+	if err != nil {
+		return nil, err
+	}
+	if e.SocketMode != 0 && !abstract {
+		if err := os.Chmod(e.Address, e.SocketMode); err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// removeStale removes a Unix socket file if nothing is accepting connections
+// on it. Files that aren't sockets are left alone
+func removeStale(e Endpoint) error {
+	info, err := os.Lstat(e.Address)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	if conn, err := net.Dial(e.Network, e.Address); err == nil {
+		_ = conn.Close()
+		return nil
+	}
+	if err := os.Remove(e.Address); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l packetListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &packetConn{UnixConn: conn.(*net.UnixConn)}, nil
+}
+
+func (c *packetConn) Read(b []byte) (int, error) {
+	if len(c.pending) == 0 {
+		if c.buf == nil {
+			c.buf = make([]byte, MaxPacketSize+1)
+		}
+		n, _, _, _, err := c.ReadMsgUnix(c.buf, nil)
+		switch {
+		case err != nil:
+			return 0, err
+		case n == 0:
+			return 0, io.EOF
+		case n > MaxPacketSize:
+			return 0, ErrPacketTooLarge
+		}
+		c.pending = c.buf[:n]
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func start(s *Server) (context.CancelFunc, chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	res := make(chan error, 1)
+	go func() {
+		res <- s.Start(ctx)
+	}()
+	return cancel, res
+}
+
+func dialPath(t *testing.T, network, path string) *testClient {
+	var conn net.Conn
+	assert.Eventually(t, func() bool {
+		c, err := net.Dial(network, path)
+		conn = c
+		return err == nil
+	}, time.Second, time.Millisecond)
+	return newTestClient(t, conn)
+}
+
+func staleSocket(t *testing.T, path string) {
+	l, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.Nil(t, l.Close())
+}
+
+func TestUnixEndpoints(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	stream := filepath.Join(dir, "stream.sock")
+	packet := filepath.Join(dir, "packet.sock")
+	staleSocket(t, stream)
+
+	s, _, _ := blockingServer()
+	WithUnixSocket(stream, 0o600)(&s.Config)
+	WithEndpoint("unixpacket", packet, WithSocketMode(0o660))(&s.Config)
+	cancel, started := start(s)
+
+	c1 := dialPath(t, "unix", stream)
+	c2 := dialPath(t, "unixpacket", packet)
+	as.Equal(resp.SimpleString("PONG"), c1.do(t, "PING"))
+	as.Equal(resp.SimpleString("PONG"), c2.do(t, "PING"))
+
+	info, err := os.Stat(stream)
+	as.Nil(err)
+	as.Equal(os.FileMode(0o600), info.Mode().Perm())
+	info, err = os.Stat(packet)
+	as.Nil(err)
+	as.Equal(os.FileMode(0o660), info.Mode().Perm())
+
+	cancel()
+	as.ErrorIs(<-started, ErrServerClosed)
+	as.Nil(s.Shutdown(context.Background()))
+
+	_, err = os.Stat(stream)
+	as.ErrorIs(err, os.ErrNotExist)
+	_, err = os.Stat(packet)
+	as.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestPacketEndpoint(t *testing.T) {
+	as := assert.New(t)
+	path := filepath.Join(t.TempDir(), "packet.sock")
+	s := NewServer(
+		WithHandler(command.Storage(storage.NewMemory())),
+		WithEndpoint("unixpacket", path),
+	)
+	cancel, started := start(s)
+	c := dialPath(t, "unixpacket", path)
+
+	// A message larger than the server's read buffer arrives whole
+	value := strings.Repeat("x", 64*1024)
+	var buf bytes.Buffer
+	as.Nil(resp.MakeArray(
+		resp.BulkString("SET"), resp.BulkString("key"), resp.BulkString(value),
+	).Marshal(&buf))
+	_, err := c.conn.Write(buf.Bytes())
+	as.Nil(err)
+	v, err := c.reader.Next()
+	as.Nil(err)
+	as.Equal(resp.OK, v)
+
+	// As does a request that's split across messages
+	as.Equal(resp.Integer(len(value)+1), c.do(t, "APPEND", "key", "y"))
+
+	cancel()
+	as.ErrorIs(<-started, ErrServerClosed)
+	as.Nil(s.Shutdown(context.Background()))
+}
+
+func TestStaleSocket(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stale.sock")
+
+	staleSocket(t, path)
+	s := NewServer(WithEndpoint("unix", path))
+	as.NotNil(s.Start(context.Background()))
+
+	active, err := net.Listen("unix", path)
+	as.ErrorContains(err, "address already in use")
+	as.Nil(active)
+	as.Nil(os.Remove(path))
+
+	active, err = net.Listen("unix", path)
+	as.Nil(err)
+	defer func() { _ = active.Close() }()
+	s = NewServer(WithUnixSocket(path, 0o600))
+	as.NotNil(s.Start(context.Background()))
+	_, err = os.Stat(path)
+	as.Nil(err)
+
+	file := filepath.Join(dir, "file")
+	as.Nil(os.WriteFile(file, []byte("data"), 0o600))
+	s = NewServer(WithUnixSocket(file, 0o600))
+	as.NotNil(s.Start(context.Background()))
+	data, err := os.ReadFile(file)
+	as.Nil(err)
+	as.Equal("data", string(data))
+}
+
+func TestListenFailure(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "first.sock")
+
+	s := NewServer(
+		WithEndpoint("unix", path),
+		WithEndpoint("bogus", "address"),
+	)
+	err := s.Start(context.Background())
+	as.ErrorContains(err, "unknown network bogus")
+
+	_, err = os.Stat(path)
+	as.ErrorIs(err, os.ErrNotExist)
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// protocolChange is emitted in place of HELLO's reply when a client
+	// changes its protocol version. WriteValue switches the encoding as it
+	// writes the reply into the batch that's being flushed, so the reply
+	// and any values that follow it are encoded for the new version
+	protocolChange struct {
+		resp.Value
+		protocol int
+	}
+
+	// helloAuth captures the reply of the AUTH command that HELLO issues on
+	// a client's behalf, so that it isn't written to the client
+	helloAuth struct {
+		*socketContext
+		output chan resp.Value
+	}
+)
+
+// Error messages
+const (
+	ErrProtocolNotInteger = "ERR Protocol version is not an integer or out " +
+		"of range"
+	ErrUnsupportedProtocol = "NOPROTO unsupported protocol version"
+	ErrHelloSyntax         = "ERR Syntax error in HELLO option '%s'"
+	ErrHelloAuthInMulti    = "ERR HELLO AUTH inside MULTI is not allowed"
+	ErrInvalidClientName   = "ERR Client names cannot contain spaces, " +
+		"newlines or special characters."
+)
+
+// Server identification, as reported by HELLO
+const (
+	ServerName    = "respect"
+	ServerVersion = "0.1.0"
+)
+
+var connectionIDs uint64
+
+var helloSpec = &command.CommandSpec{
+	Name: "hello", Group: command.GroupConnection, Arity: -1,
+	Flags:   command.FlagFast | command.FlagNoAuth,
+	Summary: "Handshakes with the server",
+	Args: []command.ArgSpec{
+		{Name: "arguments", Type: command.ArgBlock, Optional: true,
+			Args: []command.ArgSpec{
+				{Name: "protover", Type: command.ArgInteger},
+				{Name: "auth", Type: command.ArgBlock, Token: "AUTH",
+					Optional: true, Args: []command.ArgSpec{
+						{Name: "username", Type: command.ArgString},
+						{Name: "password", Type: command.ArgString},
+					}},
+				{Name: "clientname", Type: command.ArgString,
+					Token: "SETNAME", Optional: true},
+			}},
+	},
+}
+
+// hello negotiates the protocol version spoken by the client, and replies
+// with a Map describing the server and the connection
+func (c *socketContext) hello(_ command.Responder, args ...resp.Value) error {
+	protocol := c.Protocol()
+	if len(args) > 0 {
+		p, err := parseProtocol(args[0])
+		if err != nil {
+			return err
+		}
+		protocol = p
+		args = args[1:]
+	}
+
+	var name *string
+	for len(args) > 0 {
+		opt, _ := args[0].(resp.BulkString)
+		switch strings.ToUpper(string(opt)) {
+		case "AUTH":
+			if len(args) < 3 {
+				return resp.MakeError(ErrHelloSyntax, opt)
+			}
+			if err := c.auth(args[1], args[2]); err != nil {
+				return err
+			}
+			args = args[3:]
+		case "SETNAME":
+			if len(args) < 2 {
+				return resp.MakeError(ErrHelloSyntax, opt)
+			}
+			n, ok := args[1].(resp.BulkString)
+			if !ok || !validClientName(string(n)) {
+				return resp.MakeError(ErrInvalidClientName)
+			}
+			s := string(n)
+			name = &s
+			args = args[2:]
+		default:
+			return resp.MakeError(ErrHelloSyntax, args[0])
+		}
+	}
+
+	if name != nil {
+		c.name = *name
+	}
+	atomic.StoreInt32(&c.protocol, int32(protocol))
+	return command.Emit(c, protocolChange{
+		Value:    c.helloReply(protocol),
+		protocol: protocol,
+	})
+}
+
+// auth authenticates the client by issuing an AUTH command to the Server's
+// Handler, returning the error that it replies with, if any. It's refused
+// within a transaction, where the AUTH command would be queued instead
+func (c *socketContext) auth(user, password resp.Value) error {
+	if c.session.InTransaction() {
+		return resp.MakeError(ErrHelloAuthInMulti)
+	}
+	r := &helloAuth{
+		socketContext: c,
+		output:        make(chan resp.Value, 1),
+	}
+	err := c.Handler(r, resp.BulkString("AUTH"), user, password)
+	if err != nil {
+		return err
+	}
+	select {
+	case v := <-r.output:
+		if e, ok := v.(resp.Error); ok {
+			return e
+		}
+	default:
+	}
+	return nil
+}
+
+func (c *socketContext) helloReply(protocol int) resp.Value {
+	return resp.MakeMapFromPairs(
+		[2]resp.Value{resp.BulkString("server"), resp.BulkString(ServerName)},
+		[2]resp.Value{
+			resp.BulkString("version"), resp.BulkString(ServerVersion),
+		},
+		[2]resp.Value{resp.BulkString("proto"), resp.Integer(protocol)},
+		[2]resp.Value{resp.BulkString("id"), resp.Integer(c.id)},
+		[2]resp.Value{resp.BulkString("mode"), resp.BulkString("standalone")},
+		[2]resp.Value{resp.BulkString("role"), resp.BulkString("master")},
+		[2]resp.Value{resp.BulkString("modules"), resp.EmptyArray},
+	)
+}
+
+func parseProtocol(v resp.Value) (int, error) {
+	s, ok := v.(resp.BulkString)
+	if !ok {
+		return 0, resp.MakeError(ErrProtocolNotInteger)
+	}
+	p, err := strconv.Atoi(string(s))
+	switch {
+	case err != nil:
+		return 0, resp.MakeError(ErrProtocolNotInteger)
+	case p != command.RESP2 && p != command.RESP3:
+		return 0, resp.MakeError(ErrUnsupportedProtocol)
+	default:
+		return p, nil
+	}
+}
+
+func validClientName(name string) bool {
+	for _, ch := range name {
+		if ch < '!' || ch > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *helloAuth) Emit() chan<- resp.Value {
+	return a.output
+}
+
+// Stream overrides the socketContext's implementation so that a streamed
+// reply to AUTH is also captured
+func (a *helloAuth) Stream(fn func(*resp.Writer) error) error {
+	v, err := command.Collect(fn)
+	if err != nil {
+		return err
+	}
+	return a.WriteValue(v)
+}
+
+// WriteValue overrides the socketContext's implementation so that the reply
+// to AUTH is captured rather than written
+func (a *helloAuth) WriteValue(v resp.Value) error {
+	select {
+	case <-a.closed:
+		return errors.New(command.ErrContextClosed)
+	case a.output <- v:
+		return nil
+	}
+}
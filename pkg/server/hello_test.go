@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bufio"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/acl"
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHello(t *testing.T) {
+	as := assert.New(t)
+	s := NewServer(WithHandler(
+		command.ACLWrap(
+			acl.NewACL(), command.Storage(storage.NewMemory()),
+			command.StorageSpecs()...,
+		),
+	))
+	c := newPipeClient(t, s)
+
+	as.Equal(resp.Integer(1), c.do(t, "HSET", "hash", "field", "value"))
+	as.Equal(
+		resp.MakeArray(resp.BulkString("field"), resp.BulkString("value")),
+		c.do(t, "HGETALL", "hash"),
+	)
+
+	v2 := c.do(t, "HELLO")
+	as.Equal(resp.ArrayTag, v2.Tag())
+	as.Equal(14, v2.(resp.Counted).Count())
+
+	v3 := c.do(t, "HELLO", "3", "SETNAME", "client")
+	as.Equal(resp.MapTag, v3.Tag())
+	proto, ok := v3.(resp.Mapped).Get(resp.BulkString("proto"))
+	as.True(ok)
+	as.Equal(resp.Integer(3), proto)
+
+	as.Equal(resp.MapTag, c.do(t, "HGETALL", "hash").Tag())
+	as.Equal(resp.NullValue, c.do(t, "GET", "missing"))
+
+	as.Equal(
+		resp.MakeError(ErrUnsupportedProtocol), c.do(t, "HELLO", "4"),
+	)
+	as.Equal(
+		resp.MakeError(ErrProtocolNotInteger), c.do(t, "HELLO", "three"),
+	)
+	as.Equal(
+		resp.MakeError(ErrHelloSyntax, "BOGUS"),
+		c.do(t, "HELLO", "3", "BOGUS"),
+	)
+	as.Equal(
+		resp.MakeError(ErrInvalidClientName),
+		c.do(t, "HELLO", "3", "SETNAME", "bad name"),
+	)
+
+	v2 = c.do(t, "HELLO", "2", "AUTH", "default", "secret")
+	as.Equal(resp.ArrayTag, v2.Tag())
+	as.Equal(resp.NullValue, c.do(t, "GET", "missing"))
+	as.Equal(resp.ArrayTag, c.do(t, "HGETALL", "hash").Tag())
+}
+
+func TestHelloDescribed(t *testing.T) {
+	as := assert.New(t)
+	s := NewServer(WithHandler(command.Use(
+		command.IntrospectionWrap(
+			command.Storage(storage.NewMemory()),
+			command.TransactionSpecs()...,
+		),
+		command.Transactions(storage.NewMemory()),
+	)))
+	c := newPipeClient(t, s)
+
+	info := c.do(t, "COMMAND", "INFO", "hello", "multi", "subscribe")
+	values := info.(*resp.Array).Values
+	as.Equal(resp.BulkString("hello"), values[0].(*resp.Array).Values[0])
+	as.Equal(resp.BulkString("multi"), values[1].(*resp.Array).Values[0])
+	as.Equal(resp.NullValue, values[2])
+}
+
+func TestHelloAuth(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+	as.Nil(a.SetUser("alice", "on", ">secret", "~*", "+@all"))
+	as.Nil(a.SetUser(acl.DefaultUser, "resetpass", ">hidden"))
+	s := NewServer(WithHandler(
+		command.ACLWrap(
+			a, command.Storage(storage.NewMemory()), command.StorageSpecs()...,
+		),
+	))
+	c := newPipeClient(t, s)
+
+	as.Equal(resp.MakeError(command.ErrNoAuth), c.do(t, "GET", "key"))
+	as.Equal(
+		resp.MakeError(command.ErrWrongPass),
+		c.do(t, "HELLO", "3", "AUTH", "alice", "wrong"),
+	)
+	as.Equal(resp.MakeError(command.ErrNoAuth), c.do(t, "GET", "key"))
+
+	v3 := c.do(t, "HELLO", "3", "AUTH", "alice", "secret")
+	as.Equal(resp.MapTag, v3.Tag())
+	as.Equal(resp.NullValue, c.do(t, "GET", "key"))
+	as.Equal(resp.BulkString("alice"), c.do(t, "ACL", "WHOAMI"))
+}
+
+func TestHelloAuthInMulti(t *testing.T) {
+	as := assert.New(t)
+	a := acl.NewACL()
+	as.Nil(a.SetUser("alice", "on", ">secret", "~*", "+@all"))
+	as.Nil(a.SetUser(acl.DefaultUser, "resetpass", ">hidden"))
+	s := storage.NewMemory()
+	specs := command.Specs()
+	srv := NewServer(WithHandler(command.Use(
+		command.ACLWrap(a, command.Storage(s), specs...),
+		command.Transactions(s, command.WithSpecs(specs...)),
+	)))
+	c := newPipeClient(t, srv)
+
+	as.Equal(resp.OK, c.do(t, "AUTH", "alice", "secret"))
+	as.Equal(resp.OK, c.do(t, "MULTI"))
+	as.Equal(
+		resp.MakeError(ErrHelloAuthInMulti),
+		c.do(t, "HELLO", "2", "AUTH", "default", "wrong"),
+	)
+	as.Equal(resp.OK, c.do(t, "DISCARD"))
+	as.Equal(resp.BulkString("alice"), c.do(t, "ACL", "WHOAMI"))
+}
+
+func TestEmitAttribute(t *testing.T) {
+	as := assert.New(t)
+	attr := resp.MakeAttributeFromPairs(
+		[2]resp.Value{resp.BulkString("popularity"), resp.Double(0.5)},
+	)
+	s := NewServer(WithHandler(command.Wrap(command.Handlers{
+		"POPULAR": func(r command.Responder, _ ...resp.Value) error {
+			v := resp.MakeAttributed(attr, resp.BulkString("value"))
+			return command.Emit(r, v)
+		},
+	}, command.Storage(storage.NewMemory()))))
+	c := newPipeClient(t, s)
+	c.reader = resp.NewReader(
+		bufio.NewReader(c.conn), resp.V2Compatible, resp.PreserveAttributes,
+	)
+
+	// RESP2 has no Attributes, so only the reply is sent
+	as.Equal(resp.BulkString("value"), c.do(t, "POPULAR"))
+
+	as.Equal(resp.MapTag, c.do(t, "HELLO", "3").Tag())
+	v := c.do(t, "POPULAR")
+	as.True(resp.MakeAttributed(attr, resp.BulkString("value")).Equal(v))
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeCommands(cmds ...[]string) []byte {
+	var buf bytes.Buffer
+	for _, args := range cmds {
+		cmd := make([]resp.Value, len(args))
+		for i, a := range args {
+			cmd[i] = resp.BulkString(a)
+		}
+		_ = resp.MakeArray(cmd...).Marshal(&buf)
+	}
+	return buf.Bytes()
+}
+
+func TestPipeline(t *testing.T) {
+	as := assert.New(t)
+	s := NewServer(WithHandler(command.Storage(storage.NewMemory())))
+	l := listen(t)
+	served := serve(s, l)
+	c := dialTestClient(t, l)
+
+	var cmds [][]string
+	for i := 0; i < 100; i++ {
+		cmds = append(cmds, []string{"INCR", "counter"})
+	}
+	cmds = append(cmds, []string{"BOGUS"}, []string{"GET", "counter"})
+	_, err := c.conn.Write(encodeCommands(cmds...))
+	as.Nil(err)
+
+	for i := 1; i <= 100; i++ {
+		v, err := c.reader.Next()
+		as.Nil(err)
+		as.Equal(resp.Integer(i), v)
+	}
+	v, err := c.reader.Next()
+	as.Nil(err)
+	as.Equal(resp.MakeError(command.ErrUnknownCommand, "BOGUS"), v)
+	v, err = c.reader.Next()
+	as.Nil(err)
+	as.Equal(resp.BulkString("100"), v)
+
+	as.Nil(s.Shutdown(context.Background()))
+	as.ErrorIs(<-served, ErrServerClosed)
+}
+
+func TestPipelinePush(t *testing.T) {
+	as := assert.New(t)
+	s := NewServer(WithHandler(command.NewHandler(command.Handlers{
+		"PUSH": func(r command.Responder, _ ...resp.Value) error {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				_ = command.Emit(r, resp.BulkString("pushed"))
+			}()
+			return command.Emit(r, resp.OK)
+		},
+	})))
+	c := newPipeClient(t, s)
+
+	as.Equal(resp.OK, c.do(t, "PUSH"))
+	v, err := c.reader.Next()
+	as.Nil(err)
+	as.Equal(resp.BulkString("pushed"), v)
+}
+
+func TestStream(t *testing.T) {
+	as := assert.New(t)
+	s := NewServer(WithHandler(command.NewHandler(command.Handlers{
+		"RANGE": func(r command.Responder, _ ...resp.Value) error {
+			return command.Stream(r, func(w *resp.Writer) error {
+				if err := w.WriteMapHeader(2); err != nil {
+					return err
+				}
+				for i := int64(0); i < 2; i++ {
+					if err := w.WriteInteger(i); err != nil {
+						return err
+					}
+					if err := w.WriteBoolean(i == 0); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+		"BROKEN": func(r command.Responder, _ ...resp.Value) error {
+			return command.Stream(r, func(w *resp.Writer) error {
+				return w.WriteArrayHeader(2)
+			})
+		},
+	})))
+	c := newPipeClient(t, s)
+
+	as.Equal(resp.MakeArray(
+		resp.Integer(0), resp.Integer(1), resp.Integer(1), resp.Integer(0),
+	), c.do(t, "RANGE"))
+
+	c.send("HELLO", "3")
+	_, err := c.reader.Next()
+	as.Nil(err)
+	v := c.do(t, "RANGE")
+	as.Equal(resp.MapTag, v.Tag())
+	b, ok := v.(*resp.Map).Get(resp.Integer(0))
+	as.True(ok)
+	as.Equal(resp.True, b)
+
+	// A reply that fails part way through closes the connection
+	c.send("BROKEN")
+	_, err = c.reader.Next()
+	as.NotNil(err)
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, depth := range []int{1, 16, 128} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			benchmarkPipeline(b, depth)
+		})
+	}
+}
+
+func benchmarkPipeline(b *testing.B, depth int) {
+	s := NewServer(WithHandler(command.Storage(storage.NewMemory())))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	go func() { _ = s.Serve(context.Background(), l) }()
+	defer func() { _ = s.Shutdown(context.Background()) }()
+
+	conn, err := net.Dial(l.Addr().Network(), l.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	r := resp.NewReader(bufio.NewReader(conn), resp.V2Compatible)
+	cmd := encodeCommands([]string{"GET", "key"})
+	batch := bytes.Repeat(cmd, depth)
+
+	b.ResetTimer()
+	start := time.Now()
+	for n := 0; n < b.N; n += depth {
+		count := depth
+		if rem := b.N - n; rem < depth {
+			count = rem
+		}
+		if _, err := conn.Write(batch[:count*len(cmd)]); err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < count; i++ {
+			if _, err := r.Next(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "ops/s")
+}
@@ -2,13 +2,16 @@ package server
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/kode4food/respect/pkg/command"
 	"github.com/kode4food/respect/pkg/resp"
@@ -17,43 +20,51 @@ import (
 type (
 	Server struct {
 		Config
+
+		listeners map[net.Listener]struct{}
+		conns     map[*socketContext]struct{}
+		closing   int32
+		sync.Mutex
 	}
 
 	Config struct {
-		MakeReader ReaderMaker
-		Handler    command.Handler
-		Address    string
+		MakeReader      ReaderMaker
+		ReaderOptions   []resp.ReaderOption
+		Handler         command.Handler
+		Address         string
+		Endpoints       []Endpoint
+		TLSConfig       *tls.Config
+		CertificateUser CertificateUser
 	}
 
 	Option func(*Config)
 
 	ReaderMaker func(*bufio.Reader, ...resp.ReaderOption) *resp.Reader
-
-	socketContext struct {
-		*Server
-
-		conn   net.Conn
-		reader *resp.Reader
-		writer *bufio.Writer
-
-		input  chan resp.Value
-		output chan resp.Value
-		closed chan struct{}
-
-		close sync.Once
-	}
 )
 
 const DefaultPort = 6379
 
+// ErrServerClosed is returned by Start and Serve once the Server has begun
+// shutting down
+var ErrServerClosed = errors.New("server closed")
+
+// shutdownPollInterval is how often Shutdown checks for connections that
+// have become idle
+const shutdownPollInterval = 10 * time.Millisecond
+
 var defaultOptions = []Option{
 	WithReaderMaker(resp.NewReader),
+	WithReaderOptions(resp.DefaultLimits),
 	WithHandler(command.NewHandler(command.Handlers{})),
 	WithPort(DefaultPort),
+	WithCertificateUser(CommonNameUser),
 }
 
 func NewServer(opts ...Option) *Server {
-	res := &Server{}
+	res := &Server{
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[*socketContext]struct{}{},
+	}
 	for _, opt := range append(defaultOptions, opts...) {
 		opt(&res.Config)
 	}
@@ -76,6 +87,15 @@ func WithReaderMaker(m ReaderMaker) Option {
 	}
 }
 
+// WithReaderOptions adds options to the Readers that client requests are
+// read with, such as limits that replace resp.DefaultLimits. Requests that
+// exceed a limit are rejected, and their connections closed
+func WithReaderOptions(opts ...resp.ReaderOption) Option {
+	return func(c *Config) {
+		c.ReaderOptions = append(c.ReaderOptions, opts...)
+	}
+}
+
 func WithHandler(h command.Handler) Option {
 	return func(c *Config) {
 		c.Handler = h
@@ -93,127 +113,164 @@ func WithEnvPort() Option {
 	return func(*Config) {}
 }
 
-func (s *Server) Start() error {
-	listener, err := net.Listen("tcp", s.Address)
+// Start listens on the configured Endpoints, or on the configured Address if
+// there are none, and serves connections until the Context is canceled or
+// the Server is shut down. If a TLS configuration has been provided, only TLS
+// connections are accepted on TCP Endpoints. Should any listener fail, the
+// Server begins shutting down and the failure is returned
+func (s *Server) Start(ctx context.Context) error {
+	listeners, err := s.listenAll()
 	if err != nil {
 		return err
 	}
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- s.Serve(ctx, l)
+		}(l)
+	}
+	res := ErrServerClosed
+	for range listeners {
+		if err := <-errs; !errors.Is(err, ErrServerClosed) {
+			go func() { _ = s.Shutdown(context.Background()) }()
+			res = err
+		}
+	}
+	return res
+}
 
-	defer func() {
-		_ = listener.Close()
-	}()
+// Serve accepts connections on the provided net.Listener until the Context
+// is canceled or the Server is shut down, and then closes the listener.
+// Canceling the Context begins a graceful shutdown of the whole Server, but
+// doesn't wait for it to complete. Call Shutdown to do so
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	defer func() { _ = l.Close() }()
+	if !s.trackListener(l) {
+		return ErrServerClosed
+	}
+	defer s.untrackListener(l)
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = s.Shutdown(context.Background())
+	})
+	defer stop()
 
 	for {
-		conn, err := listener.Accept()
+		conn, err := l.Accept()
 		if err != nil {
+			if s.isClosing() {
+				return ErrServerClosed
+			}
 			return err
 		}
 		go s.handleConnection(conn)
 	}
 }
 
-func (s *Server) handleConnection(conn net.Conn) {
-	ctx := s.makeContext(conn)
-	defer func() { _ = ctx.Close() }()
-	go ctx.readLoop()
-	go ctx.writeLoop()
-	ctx.handleLoop()
-}
-
-func (s *Server) makeContext(conn net.Conn) *socketContext {
-	return &socketContext{
-		Server: s,
-
-		conn:   conn,
-		reader: s.MakeReader(bufio.NewReader(conn)),
-		writer: bufio.NewWriter(conn),
-
-		input:  make(chan resp.Value),
-		output: make(chan resp.Value),
-		closed: make(chan struct{}),
-	}
-}
+// Shutdown gracefully stops the Server. It closes every listener, waits for
+// in-flight commands to complete, and closes connections as they become
+// idle, returning once all of them are closed. If the Context ends first,
+// the remaining connections are closed forcefully and its error is returned
+func (s *Server) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&s.closing, 1)
+	s.closeListeners()
 
-func (c *socketContext) handleLoop() {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
 	for {
+		if s.closeIdle() {
+			return nil
+		}
 		select {
-		case <-c.closed:
-			return
-		default:
-			if err := command.HandleNext(c, c.Handler); err != nil {
-				c.forwardError(err)
-			}
+		case <-ctx.Done():
+			s.closeAll()
+			return ctx.Err()
+		case <-ticker.C:
 		}
 	}
 }
 
-func (c *socketContext) readLoop() {
-	for {
-		select {
-		case <-c.closed:
-			return
-		default:
-			value, err := c.reader.Next()
-			if err == nil {
-				c.input <- value
-				continue
-			}
-			if errors.Is(err, io.EOF) {
-				_ = c.Close()
-			} else {
-				c.forwardError(err)
-			}
-			return
-		}
-	}
+func (s *Server) isClosing() bool {
+	return atomic.LoadInt32(&s.closing) != 0
 }
 
-func (c *socketContext) writeLoop() {
-	for {
-		select {
-		case <-c.closed:
-			return
-		case value := <-c.output:
-			err := value.Marshal(c.writer)
-			if err == nil {
-				err = c.writer.Flush()
-			}
-			if err != nil {
-				c.forwardError(err)
-			}
-		}
+func (s *Server) trackListener(l net.Listener) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.isClosing() {
+		return false
 	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+func (s *Server) untrackListener(l net.Listener) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.listeners, l)
 }
 
-func (c *socketContext) forwardError(err error) {
-	respErr, ok := err.(resp.Value)
-	if !ok {
-		respErr = resp.MakeError(err.Error())
+func (s *Server) closeListeners() {
+	s.Lock()
+	defer s.Unlock()
+	for l := range s.listeners {
+		_ = l.Close()
 	}
-	select {
-	case <-c.closed:
-	case c.output <- respErr:
+}
+
+func (s *Server) trackConn(c *socketContext) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.isClosing() {
+		return false
 	}
+	s.conns[c] = struct{}{}
+	return true
 }
 
-func (c *socketContext) Close() error {
-	c.close.Do(func() {
-		_ = c.conn.Close()
-		close(c.closed)
-		close(c.input)
-		close(c.output)
-	})
-	return nil
+func (s *Server) untrackConn(c *socketContext) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.conns, c)
 }
 
-func (c *socketContext) Accept() <-chan resp.Value {
-	return c.input
+// closeIdle closes the connections that aren't processing a command,
+// returning whether none remain
+func (s *Server) closeIdle() bool {
+	s.Lock()
+	defer s.Unlock()
+	for c := range s.conns {
+		c.closeIfIdle()
+	}
+	return len(s.conns) == 0
 }
 
-func (c *socketContext) Emit() chan<- resp.Value {
-	return c.output
+func (s *Server) closeAll() {
+	s.Lock()
+	defer s.Unlock()
+	for c := range s.conns {
+		c.abort()
+	}
 }
 
-func (c *socketContext) Closed() <-chan struct{} {
-	return c.closed
+func (s *Server) handleConnection(conn net.Conn) {
+	c := s.makeContext(conn)
+	if !s.trackConn(c) {
+		_ = conn.Close()
+		return
+	}
+	defer s.untrackConn(c)
+	if err := c.authenticate(); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	c.handleLoop()
+	close(c.done)
+
+	// Whatever has already been written is flushed before the connection
+	// itself is closed
+	_ = c.Close()
+	_ = c.flush()
+	_ = conn.Close()
 }
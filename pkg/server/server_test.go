@@ -0,0 +1,248 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClient struct {
+	conn   net.Conn
+	reader *resp.Reader
+}
+
+func newPipeClient(t *testing.T, s *Server) *testClient {
+	server, client := net.Pipe()
+	go s.handleConnection(server)
+	return newTestClient(t, client)
+}
+
+func dialTestClient(t *testing.T, l net.Listener) *testClient {
+	conn, err := net.Dial(l.Addr().Network(), l.Addr().String())
+	assert.Nil(t, err)
+	return newTestClient(t, conn)
+}
+
+func newTestClient(t *testing.T, conn net.Conn) *testClient {
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testClient{
+		conn:   conn,
+		reader: resp.NewReader(bufio.NewReader(conn), resp.V2Compatible),
+	}
+}
+
+func (c *testClient) send(args ...string) {
+	cmd := make([]resp.Value, len(args))
+	for i, a := range args {
+		cmd[i] = resp.BulkString(a)
+	}
+	go func() {
+		_ = resp.MakeArray(cmd...).Marshal(c.conn)
+	}()
+}
+
+func (c *testClient) do(t *testing.T, args ...string) resp.Value {
+	c.send(args...)
+	v, err := c.reader.Next()
+	assert.Nil(t, err)
+	return v
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	return l
+}
+
+// blockingServer creates a Server whose BLOCK command doesn't complete until
+// the returned channel is closed
+func blockingServer() (*Server, chan struct{}, chan struct{}) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	s := NewServer(WithHandler(command.NewHandler(command.Handlers{
+		"PING": func(r command.Responder, _ ...resp.Value) error {
+			return command.Emit(r, resp.SimpleString("PONG"))
+		},
+		"BLOCK": func(r command.Responder, _ ...resp.Value) error {
+			started <- struct{}{}
+			<-release
+			return command.Emit(r, resp.OK)
+		},
+	})))
+	return s, started, release
+}
+
+func serve(s *Server, l net.Listener) chan error {
+	res := make(chan error, 1)
+	go func() {
+		res <- s.Serve(context.Background(), l)
+	}()
+	return res
+}
+
+func TestShutdown(t *testing.T) {
+	as := assert.New(t)
+	s, started, release := blockingServer()
+	l := listen(t)
+	served := serve(s, l)
+
+	idle := dialTestClient(t, l)
+	busy := dialTestClient(t, l)
+	as.Equal(resp.SimpleString("PONG"), idle.do(t, "PING"))
+	busy.send("BLOCK")
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	as.ErrorIs(<-served, ErrServerClosed)
+
+	_, err := idle.reader.Next()
+	as.ErrorIs(err, io.EOF)
+
+	select {
+	case <-shutdown:
+		as.Fail("shutdown completed with a command in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	v, err := busy.reader.Next()
+	as.Nil(err)
+	as.Equal(resp.OK, v)
+	as.Nil(<-shutdown)
+
+	_, err = busy.reader.Next()
+	as.ErrorIs(err, io.EOF)
+
+	_, err = net.Dial(l.Addr().Network(), l.Addr().String())
+	as.NotNil(err)
+	as.ErrorIs(s.Serve(context.Background(), listen(t)), ErrServerClosed)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	as := assert.New(t)
+	s, started, release := blockingServer()
+	defer close(release)
+	l := listen(t)
+	served := serve(s, l)
+
+	busy := dialTestClient(t, l)
+	busy.send("BLOCK")
+	<-started
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 50*time.Millisecond,
+	)
+	defer cancel()
+	as.ErrorIs(s.Shutdown(ctx), context.DeadlineExceeded)
+	as.ErrorIs(<-served, ErrServerClosed)
+
+	_, err := busy.reader.Next()
+	as.NotNil(err)
+}
+
+func TestEmitAfterClose(t *testing.T) {
+	as := assert.New(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	s := NewServer(WithHandler(command.NewHandler(command.Handlers{
+		"EMIT": func(r command.Responder, _ ...resp.Value) error {
+			out := r.Emit()
+			out <- resp.SimpleString("first")
+			close(started)
+			<-release
+			out <- resp.SimpleString("second")
+			r.Emit() <- resp.SimpleString("third")
+			close(finished)
+			return nil
+		},
+	})))
+	l := listen(t)
+	served := serve(s, l)
+
+	c := dialTestClient(t, l)
+	c.send("EMIT")
+	<-started
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 50*time.Millisecond,
+	)
+	defer cancel()
+	as.ErrorIs(s.Shutdown(ctx), context.DeadlineExceeded)
+	as.ErrorIs(<-served, ErrServerClosed)
+
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		as.Fail("emitting to a closed connection blocked")
+	}
+}
+
+func TestCommandWhileClosing(t *testing.T) {
+	as := assert.New(t)
+	s, _, _ := blockingServer()
+	server, client := net.Pipe()
+	defer func() { _ = client.Close() }()
+
+	c := s.makeContext(server)
+	c.abort()
+	err := c.handler(c, resp.BulkString("PING"))
+	as.EqualError(err, command.ErrContextClosed)
+}
+
+func TestServeContext(t *testing.T) {
+	as := assert.New(t)
+	s, _, _ := blockingServer()
+	l := listen(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, l)
+	}()
+
+	c := dialTestClient(t, l)
+	as.Equal(resp.SimpleString("PONG"), c.do(t, "PING"))
+
+	cancel()
+	as.ErrorIs(<-served, ErrServerClosed)
+	as.Nil(s.Shutdown(context.Background()))
+
+	_, err := c.reader.Next()
+	as.ErrorIs(err, io.EOF)
+}
+
+func TestReaderLimits(t *testing.T) {
+	as := assert.New(t)
+	s := NewServer(
+		WithHandler(command.Storage(storage.NewMemory())),
+		WithReaderOptions(resp.WithMaxAggregateCount(8)),
+	)
+	c := newPipeClient(t, s)
+	as.Equal(resp.OK, c.do(t, "SET", "key", "value"))
+
+	go func() {
+		_, _ = c.conn.Write([]byte("*2147483647\r\n"))
+	}()
+	v, err := c.reader.Next()
+	as.Nil(err)
+	e, ok := v.(resp.Error)
+	as.True(ok)
+	as.Equal("ERR", e.Prefix())
+	as.Contains(e.Error(), "aggregate count 2147483647 exceeds limit of 8")
+
+	_, err = c.reader.Next()
+	as.ErrorIs(err, io.EOF)
+}
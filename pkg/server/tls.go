@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"time"
+)
+
+// CertificateUser maps a verified client certificate to the name of the user
+// that its connection is authenticated as
+type CertificateUser func(*x509.Certificate) (string, error)
+
+// ErrNoCertificateUser is returned by CommonNameUser when a certificate has
+// no Common Name to map
+var ErrNoCertificateUser = errors.New("certificate has no common name")
+
+// handshakeTimeout bounds how long a client can take to complete its TLS
+// handshake
+const handshakeTimeout = 10 * time.Second
+
+// WithTLSConfig causes Start to serve TLS connections on TCP Endpoints using
+// the provided configuration. Client certificates are requested and verified
+// according to the configuration's ClientAuth and ClientCAs
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Config) {
+		c.TLSConfig = cfg
+	}
+}
+
+// WithCertificateUser sets how verified client certificates are mapped to
+// the users that their connections are authenticated as
+func WithCertificateUser(fn CertificateUser) Option {
+	return func(c *Config) {
+		c.CertificateUser = fn
+	}
+}
+
+// CommonNameUser maps a client certificate to the user named by its
+// subject's Common Name
+func CommonNameUser(cert *x509.Certificate) (string, error) {
+	if cert.Subject.CommonName == "" {
+		return "", ErrNoCertificateUser
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// authenticate completes the TLS handshake of a connection, if it has one,
+// and authenticates the connection as the user that its client certificate
+// maps to
+func (c *socketContext) authenticate() error {
+	tc, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	certs := tc.ConnectionState().VerifiedChains
+	if len(certs) == 0 || c.CertificateUser == nil {
+		return nil
+	}
+	user, err := c.CertificateUser(certs[0][0])
+	if err != nil {
+		return err
+	}
+	c.session.SetUser(user)
+	return nil
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kode4food/respect/pkg/command"
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+var serial int64
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := certTemplate("Test CA")
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign
+	der, err := x509.CreateCertificate(
+		rand.Reader, tmpl, tmpl, &key.PublicKey, key,
+	)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(
+	t *testing.T, name string, usage x509.ExtKeyUsage,
+) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := certTemplate(name)
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	der, err := x509.CreateCertificate(
+		rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key,
+	)
+	assert.Nil(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func certTemplate(name string) *x509.Certificate {
+	serial++
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+}
+
+func whoAmIServer(opts ...Option) *Server {
+	return NewServer(append([]Option{
+		WithAddress("127.0.0.1:0"),
+		WithHandler(command.NewHandler(command.Handlers{
+			"WHOAMI": func(r command.Responder, _ ...resp.Value) error {
+				user := command.SessionOf(r).User()
+				return command.Emit(r, resp.BulkString(user))
+			},
+		})),
+	}, opts...)...)
+}
+
+func serveTLS(t *testing.T, s *Server) net.Listener {
+	l, err := s.listen(s.endpoints()[0])
+	assert.Nil(t, err)
+	go func() { _ = s.Serve(context.Background(), l) }()
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+	return l
+}
+
+func dialTLS(
+	t *testing.T, l net.Listener, cfg *tls.Config,
+) (*testClient, error) {
+	conn, err := tls.Dial(l.Addr().Network(), l.Addr().String(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newTestClient(t, conn), nil
+}
+
+func TestTLS(t *testing.T) {
+	as := assert.New(t)
+	ca := newTestCA(t)
+	s := whoAmIServer(WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{
+			ca.issue(t, "server", x509.ExtKeyUsageServerAuth),
+		},
+	}))
+	l := serveTLS(t, s)
+
+	c, err := dialTLS(t, l, &tls.Config{RootCAs: ca.pool})
+	as.Nil(err)
+	as.Equal(resp.BulkString(command.DefaultUser), c.do(t, "WHOAMI"))
+
+	plain := dialTestClient(t, l)
+	plain.send("WHOAMI")
+	_, err = plain.reader.Next()
+	as.NotNil(err)
+}
+
+func TestMutualTLS(t *testing.T) {
+	as := assert.New(t)
+	ca := newTestCA(t)
+	s := whoAmIServer(WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{
+			ca.issue(t, "server", x509.ExtKeyUsageServerAuth),
+		},
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  ca.pool,
+	}))
+	l := serveTLS(t, s)
+
+	c, err := dialTLS(t, l, &tls.Config{
+		RootCAs: ca.pool,
+		Certificates: []tls.Certificate{
+			ca.issue(t, "alice", x509.ExtKeyUsageClientAuth),
+		},
+	})
+	as.Nil(err)
+	as.Equal(resp.BulkString("alice"), c.do(t, "WHOAMI"))
+
+	other := newTestCA(t)
+	c, err = dialTLS(t, l, &tls.Config{
+		RootCAs: ca.pool,
+		Certificates: []tls.Certificate{
+			other.issue(t, "mallory", x509.ExtKeyUsageClientAuth),
+		},
+	})
+	if err == nil {
+		c.send("WHOAMI")
+		_, err = c.reader.Next()
+	}
+	as.NotNil(err)
+}
+
+func TestCertificateUser(t *testing.T) {
+	as := assert.New(t)
+	ca := newTestCA(t)
+	s := whoAmIServer(
+		WithTLSConfig(&tls.Config{
+			Certificates: []tls.Certificate{
+				ca.issue(t, "server", x509.ExtKeyUsageServerAuth),
+			},
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  ca.pool,
+		}),
+		WithCertificateUser(func(cert *x509.Certificate) (string, error) {
+			if cert.Subject.CommonName == "root" {
+				return "", ErrNoCertificateUser
+			}
+			return "user:" + cert.Subject.CommonName, nil
+		}),
+	)
+	l := serveTLS(t, s)
+
+	c, err := dialTLS(t, l, &tls.Config{
+		RootCAs: ca.pool,
+		Certificates: []tls.Certificate{
+			ca.issue(t, "bob", x509.ExtKeyUsageClientAuth),
+		},
+	})
+	as.Nil(err)
+	as.Equal(resp.BulkString("user:bob"), c.do(t, "WHOAMI"))
+
+	c, err = dialTLS(t, l, &tls.Config{RootCAs: ca.pool})
+	as.Nil(err)
+	as.Equal(resp.BulkString(command.DefaultUser), c.do(t, "WHOAMI"))
+
+	c, err = dialTLS(t, l, &tls.Config{
+		RootCAs: ca.pool,
+		Certificates: []tls.Certificate{
+			ca.issue(t, "root", x509.ExtKeyUsageClientAuth),
+		},
+	})
+	as.Nil(err)
+	c.send("WHOAMI")
+	_, err = c.reader.Next()
+	as.NotNil(err)
+
+	_, err = CommonNameUser(&x509.Certificate{})
+	as.ErrorIs(err, ErrNoCertificateUser)
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// lockedMemory serializes Atomic functions against the key-level operations
+// of a memory Storage. Key-level operations share the lock with one another,
+// relying on the memNode locks to keep them apart, whereas an Atomic function
+// holds it exclusively. Key iteration doesn't take the lock at all, because
+// iterators call back into the Storage while they're in progress
+type lockedMemory struct {
+	*memory
+	txn sync.RWMutex
+}
+
+// compile-time checks for interface implementation
+var _ Storage = (*lockedMemory)(nil)
+
+func (m *lockedMemory) Atomic(fn func(Storage) error) error {
+	m.txn.Lock()
+	defer m.txn.Unlock()
+	return fn(m.memory)
+}
+
+func (m *lockedMemory) Get(key Key) (resp.Value, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Get(key)
+}
+
+func (m *lockedMemory) Set(key Key, value resp.Value) (resp.Value, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Set(key, value)
+}
+
+func (m *lockedMemory) SetWith(
+	key Key, value resp.Value, opts SetOptions,
+) (resp.Value, bool, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.SetWith(key, value, opts)
+}
+
+func (m *lockedMemory) Delete(key Key) (resp.Value, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Delete(key)
+}
+
+func (m *lockedMemory) Exists(key Key) (bool, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Exists(key)
+}
+
+func (m *lockedMemory) Expire(key Key, deadline time.Time) error {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Expire(key, deadline)
+}
+
+func (m *lockedMemory) Persist(key Key) (bool, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Persist(key)
+}
+
+func (m *lockedMemory) Deadline(key Key) (time.Time, bool, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Deadline(key)
+}
+
+func (m *lockedMemory) Revision(key Key) (uint64, bool, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Revision(key)
+}
+
+func (m *lockedMemory) Kind(key Key) (Kind, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Kind(key)
+}
+
+func (m *lockedMemory) Update(key Key, fn Updater) (resp.Value, error) {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Update(key, fn)
+}
+
+func (m *lockedMemory) Mutate(key Key, fn Mutator) error {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Mutate(key, fn)
+}
+
+func (m *lockedMemory) Inspect(key Key, fn Inspector) error {
+	m.txn.RLock()
+	defer m.txn.RUnlock()
+	return m.memory.Inspect(key, fn)
+}
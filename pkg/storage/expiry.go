@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"container/heap"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// expiryQueue actively removes expired keys from a memNode tree. Values
+	// are already hidden once their deadline passes, so the queue exists only
+	// to reclaim their memory. Its sweeper runs only while deadlines remain
+	expiryQueue struct {
+		sync.Mutex
+		root    *memNode
+		entries expiryEntries
+		byKey   map[string]*expiryEntry
+		wake    chan struct{}
+		running bool
+	}
+
+	expiryEntry struct {
+		key      Key
+		deadline time.Time
+		index    int
+	}
+
+	expiryEntries []*expiryEntry
+)
+
+// compile-time checks for interface implementation
+var _ heap.Interface = (*expiryEntries)(nil)
+
+func newExpiryQueue(root *memNode) *expiryQueue {
+	return &expiryQueue{
+		root:  root,
+		byKey: map[string]*expiryEntry{},
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// schedule records a deadline for the provided Key, replacing any that was
+// already recorded. The queue's lock must be held
+func (q *expiryQueue) schedule(key Key, deadline time.Time) {
+	id := expiryID(key)
+	if e, ok := q.byKey[id]; ok {
+		e.deadline = deadline
+		heap.Fix(&q.entries, e.index)
+	} else {
+		e = &expiryEntry{
+			key:      slices.Clone(key),
+			deadline: deadline,
+		}
+		heap.Push(&q.entries, e)
+		q.byKey[id] = e
+	}
+	if !q.running {
+		q.running = true
+		go q.sweep()
+		return
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *expiryQueue) sweep() {
+	for {
+		now := time.Now()
+		due, next, ok := q.popDue(now)
+		for _, e := range due {
+			q.root.expire(e.key, now)
+		}
+		if !ok {
+			return
+		}
+		t := time.NewTimer(next.Sub(now))
+		select {
+		case <-t.C:
+		case <-q.wake:
+			t.Stop()
+		}
+	}
+}
+
+// popDue removes the entries whose deadlines have passed, returning them
+// along with the next pending deadline. If none are pending, the sweeper is
+// marked as stopped
+func (q *expiryQueue) popDue(now time.Time) ([]*expiryEntry, time.Time, bool) {
+	q.Lock()
+	defer q.Unlock()
+	var res []*expiryEntry
+	for len(q.entries) > 0 && !now.Before(q.entries[0].deadline) {
+		e := heap.Pop(&q.entries).(*expiryEntry)
+		delete(q.byKey, expiryID(e.key))
+		res = append(res, e)
+	}
+	if len(q.entries) == 0 {
+		q.running = false
+		return res, time.Time{}, false
+	}
+	return res, q.entries[0].deadline, true
+}
+
+// expiryID identifies a Key within the queue. Each of its parts is prefixed
+// with its length, as parts can contain any byte, including a separator
+func expiryID(k Key) string {
+	var buf []byte
+	for _, e := range k {
+		buf = strconv.AppendInt(buf, int64(len(e)), 10)
+		buf = append(buf, ':')
+		buf = append(buf, e...)
+	}
+	return string(buf)
+}
+
+func (e expiryEntries) Len() int {
+	return len(e)
+}
+
+func (e expiryEntries) Less(i, j int) bool {
+	return e[i].deadline.Before(e[j].deadline)
+}
+
+func (e expiryEntries) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+	e[i].index = i
+	e[j].index = j
+}
+
+func (e *expiryEntries) Push(x any) {
+	entry := x.(*expiryEntry)
+	entry.index = len(*e)
+	*e = append(*e, entry)
+}
+
+func (e *expiryEntries) Pop() any {
+	old := *e
+	n := len(old) - 1
+	res := old[n]
+	old[n] = nil
+	*e = old[:n]
+	return res
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"io"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// Hash is a Mutable mapping of field names to values
+type Hash struct {
+	fields table[resp.Value]
+}
+
+// compile-time checks for interface implementation
+var _ Mutable = (*Hash)(nil)
+
+// NewHash creates a new empty Hash
+func NewHash() *Hash {
+	return &Hash{}
+}
+
+// MutateHash applies a function to the Hash stored at a Key while the Key is
+// locked for writing. If the Key doesn't exist and create is true, a new Hash
+// is stored, otherwise a KeyNotFound error is returned
+func MutateHash(s Storage, k Key, create bool, fn func(*Hash) error) error {
+	var mk func() *Hash
+	if create {
+		mk = NewHash
+	}
+	return mutateAs(s, k, mk, fn)
+}
+
+// InspectHash applies a function to the Hash stored at a Key while the Key is
+// locked for reading
+func InspectHash(s Storage, k Key, fn func(*Hash) error) error {
+	return inspectAs(s, k, fn)
+}
+
+func (*Hash) Kind() Kind {
+	return HashKind
+}
+
+func (h *Hash) Len() int {
+	return h.fields.len()
+}
+
+// Get returns the value of a field
+func (h *Hash) Get(field resp.BulkString) (resp.Value, bool) {
+	return h.fields.get(field)
+}
+
+// Set stores the value of a field, returning whether the field is new
+func (h *Hash) Set(field resp.BulkString, value resp.Value) bool {
+	return h.fields.put(field, value)
+}
+
+// Delete removes a field, returning whether it was present
+func (h *Hash) Delete(field resp.BulkString) bool {
+	return h.fields.remove(field)
+}
+
+// ForEach calls a function for each field of the Hash until it returns an
+// error
+func (h *Hash) ForEach(fn func(resp.BulkString, resp.Value) error) error {
+	return h.fields.forEach(fn)
+}
+
+// Scan visits the fields of the Hash, resuming from a cursor returned by a
+// previous call, with the same guarantees as Storage's ScanKeys
+func (h *Hash) Scan(
+	cursor uint64, count int, fn func(resp.BulkString, resp.Value),
+) uint64 {
+	return h.fields.scan(cursor, count, fn)
+}
+
+func (h *Hash) Snapshot() resp.Value {
+	pairs := make([][2]resp.Value, 0, h.Len())
+	_ = h.ForEach(func(f resp.BulkString, v resp.Value) error {
+		pairs = append(pairs, [2]resp.Value{f, v})
+		return nil
+	})
+	return resp.MakeMapFromPairs(pairs...)
+}
+
+func (*Hash) Tag() resp.Tag {
+	return resp.MapTag
+}
+
+func (h *Hash) Marshal(w io.Writer) error {
+	return h.Snapshot().Marshal(w)
+}
+
+func (h *Hash) Equal(v resp.Value) bool {
+	if v, ok := v.(*Hash); ok {
+		return h == v || h.Snapshot().Equal(v.Snapshot())
+	}
+	return false
+}
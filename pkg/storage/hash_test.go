@@ -0,0 +1,98 @@
+package storage_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash(t *testing.T) {
+	as := assert.New(t)
+	h := storage.NewHash()
+	as.Equal(storage.HashKind, h.Kind())
+
+	as.True(h.Set("first", resp.Integer(1)))
+	as.True(h.Set("second", resp.Integer(2)))
+	as.False(h.Set("first", resp.Integer(10)))
+	as.Equal(2, h.Len())
+
+	v, ok := h.Get("first")
+	as.True(ok)
+	as.Equal(resp.Integer(10), v)
+	_, ok = h.Get("third")
+	as.False(ok)
+
+	as.True(h.Snapshot().Equal(resp.MakeMap(map[resp.BulkString]resp.Value{
+		"first":  resp.Integer(10),
+		"second": resp.Integer(2),
+	})))
+
+	as.True(h.Delete("first"))
+	as.False(h.Delete("first"))
+	as.Equal(1, h.Len())
+}
+
+func TestHashScan(t *testing.T) {
+	as := assert.New(t)
+	h := storage.NewHash()
+	for i := 0; i < 500; i++ {
+		h.Set(resp.BulkString(fmt.Sprintf("field-%d", i)), resp.Integer(i))
+	}
+
+	seen := map[resp.BulkString]int{}
+	var cursor uint64
+	for {
+		cursor = h.Scan(cursor, 10, func(f resp.BulkString, _ resp.Value) {
+			seen[f]++
+		})
+		if cursor == 0 {
+			break
+		}
+		for i := 0; i < 5; i++ {
+			h.Set(resp.BulkString(fmt.Sprintf("new-%d-%d", cursor, i)),
+				resp.Integer(i),
+			)
+		}
+	}
+	for i := 0; i < 500; i++ {
+		as.Equal(1, seen[resp.BulkString(fmt.Sprintf("field-%d", i))])
+	}
+}
+
+func TestMutateHash(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"hash"}
+
+	as.Nil(storage.MutateHash(s, key, true, func(h *storage.Hash) error {
+		h.Set("field", resp.BulkString("value"))
+		return nil
+	}))
+	k, err := s.Kind(key)
+	as.Nil(err)
+	as.Equal("hash", k.String())
+
+	as.ErrorIs(
+		storage.InspectList(s, key, func(*storage.List) error {
+			return nil
+		}),
+		storage.WrongType,
+	)
+
+	as.Nil(storage.InspectHash(s, key, func(h *storage.Hash) error {
+		v, ok := h.Get("field")
+		as.True(ok)
+		as.Equal(resp.BulkString("value"), v)
+		return nil
+	}))
+
+	as.Nil(storage.MutateHash(s, key, false, func(h *storage.Hash) error {
+		h.Delete("field")
+		return nil
+	}))
+	_, err = s.Kind(key)
+	as.ErrorIs(err, storage.KeyNotFound)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import "github.com/kode4food/respect/pkg/resp"
+
+type (
+	// Kind identifies the type of data stored at a Key
+	Kind uint8
+
+	// Mutable is implemented by the kinds of data that are modified in place
+	// while their Key is locked. They are only ever exposed outside the
+	// Storage lock as immutable snapshots
+	Mutable interface {
+		resp.Value
+
+		// Kind returns the Kind of data the Mutable holds
+		Kind() Kind
+
+		// Len returns the number of elements in the Mutable. A Mutable that
+		// is left empty by a mutation is removed from the Storage
+		Len() int
+
+		// Snapshot returns an immutable copy of the Mutable's contents
+		Snapshot() resp.Value
+	}
+
+	// Mutator is applied to the Mutable stored at a Key, or to nil if there
+	// is none, and returns the Mutable that should be stored in its place
+	Mutator func(Mutable) (Mutable, error)
+
+	// Inspector is applied to the Mutable stored at a Key
+	Inspector func(Mutable) error
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=Kind -linecomment
+const (
+	ValueKind     Kind = iota // string
+	ListKind                  // list
+	HashKind                  // hash
+	SortedSetKind             // zset
+	SetKind                   // set
+)
+
+// Error messages
+const (
+	ErrWrongType = "WRONGTYPE Operation against a key holding the wrong " +
+		"kind of value"
+)
+
+// WrongType is returned when a Key holds a different Kind of data than the
+// operation requires
+var WrongType = resp.MakeError(ErrWrongType)
+
+// mutateAs applies a function to the Mutable of type T stored at a Key. If
+// the Key doesn't exist, the Mutable is constructed using create. If create
+// is nil, a KeyNotFound error is returned instead
+func mutateAs[T Mutable](
+	s Storage, k Key, create func() T, fn func(T) error,
+) error {
+	return s.Mutate(k, func(m Mutable) (Mutable, error) {
+		if m == nil {
+			if create == nil {
+				return nil, keyNotFound(k)
+			}
+			m = create()
+		}
+		t, ok := m.(T)
+		if !ok {
+			return nil, WrongType
+		}
+		return t, fn(t)
+	})
+}
+
+// inspectAs applies a function to the Mutable of type T stored at a Key
+func inspectAs[T Mutable](s Storage, k Key, fn func(T) error) error {
+	return s.Inspect(k, func(m Mutable) error {
+		t, ok := m.(T)
+		if !ok {
+			return WrongType
+		}
+		return fn(t)
+	})
+}
+
+// normalizeRange converts a Redis-style inclusive range, where negative
+// indexes count back from the end, into a half-open range of valid indexes
+func normalizeRange(start, stop, length int) (int, int) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return 0, 0
+	}
+	return start, stop + 1
+}
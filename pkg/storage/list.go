@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"io"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// List is a Mutable sequence of values that can be efficiently pushed to and
+// popped from both ends
+type List struct {
+	items []resp.Value
+	head  int
+	size  int
+}
+
+const minListCapacity = 4
+
+// compile-time checks for interface implementation
+var _ Mutable = (*List)(nil)
+
+// NewList creates a new empty List
+func NewList() *List {
+	return &List{}
+}
+
+// MutateList applies a function to the List stored at a Key while the Key is
+// locked for writing. If the Key doesn't exist and create is true, a new List
+// is stored, otherwise a KeyNotFound error is returned
+func MutateList(s Storage, k Key, create bool, fn func(*List) error) error {
+	var mk func() *List
+	if create {
+		mk = NewList
+	}
+	return mutateAs(s, k, mk, fn)
+}
+
+// InspectList applies a function to the List stored at a Key while the Key is
+// locked for reading
+func InspectList(s Storage, k Key, fn func(*List) error) error {
+	return inspectAs(s, k, fn)
+}
+
+func (*List) Kind() Kind {
+	return ListKind
+}
+
+func (l *List) Len() int {
+	return l.size
+}
+
+// PushFront inserts values at the head of the List, one at a time, so that
+// the last value provided becomes the new head
+func (l *List) PushFront(v ...resp.Value) {
+	for _, e := range v {
+		l.grow()
+		l.head = (l.head - 1 + len(l.items)) % len(l.items)
+		l.items[l.head] = e
+		l.size++
+	}
+}
+
+// PushBack appends values to the tail of the List
+func (l *List) PushBack(v ...resp.Value) {
+	for _, e := range v {
+		l.grow()
+		l.items[l.pos(l.size)] = e
+		l.size++
+	}
+}
+
+// PopFront removes and returns up to count values from the head of the List
+func (l *List) PopFront(count int) resp.Values {
+	if count > l.size {
+		count = l.size
+	}
+	res := make(resp.Values, count)
+	for i := range res {
+		res[i] = l.items[l.head]
+		l.items[l.head] = nil
+		l.head = (l.head + 1) % len(l.items)
+		l.size--
+	}
+	return res
+}
+
+// PopBack removes and returns up to count values from the tail of the List
+func (l *List) PopBack(count int) resp.Values {
+	if count > l.size {
+		count = l.size
+	}
+	res := make(resp.Values, count)
+	for i := range res {
+		p := l.pos(l.size - 1)
+		res[i] = l.items[p]
+		l.items[p] = nil
+		l.size--
+	}
+	return res
+}
+
+// Index returns the value at the provided index. Negative indexes count back
+// from the tail of the List
+func (l *List) Index(i int) (resp.Value, bool) {
+	if i < 0 {
+		i += l.size
+	}
+	if i < 0 || i >= l.size {
+		return nil, false
+	}
+	return l.items[l.pos(i)], true
+}
+
+// Range returns the values between the start and stop indexes, inclusive.
+// Negative indexes count back from the tail of the List
+func (l *List) Range(start, stop int) resp.Values {
+	from, to := normalizeRange(start, stop, l.size)
+	res := make(resp.Values, to-from)
+	for i := range res {
+		res[i] = l.items[l.pos(from+i)]
+	}
+	return res
+}
+
+// Trim removes all values outside the start and stop indexes, inclusive.
+// Negative indexes count back from the tail of the List
+func (l *List) Trim(start, stop int) {
+	kept := l.Range(start, stop)
+	l.items = kept
+	l.head = 0
+	l.size = len(kept)
+}
+
+func (l *List) Snapshot() resp.Value {
+	return resp.MakeArray(l.Range(0, -1)...)
+}
+
+func (*List) Tag() resp.Tag {
+	return resp.ArrayTag
+}
+
+func (l *List) Marshal(w io.Writer) error {
+	return l.Snapshot().Marshal(w)
+}
+
+func (l *List) Equal(v resp.Value) bool {
+	if v, ok := v.(*List); ok {
+		return l == v || l.Snapshot().Equal(v.Snapshot())
+	}
+	return false
+}
+
+func (l *List) pos(i int) int {
+	return (l.head + i) % len(l.items)
+}
+
+func (l *List) grow() {
+	if l.size < len(l.items) {
+		return
+	}
+	size := len(l.items) * 2
+	if size < minListCapacity {
+		size = minListCapacity
+	}
+	items := make([]resp.Value, size)
+	for i := 0; i < l.size; i++ {
+		items[i] = l.items[l.pos(i)]
+	}
+	l.items = items
+	l.head = 0
+}
@@ -0,0 +1,111 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func values(ints ...int) resp.Values {
+	res := make(resp.Values, len(ints))
+	for i, e := range ints {
+		res[i] = resp.Integer(e)
+	}
+	return res
+}
+
+func TestList(t *testing.T) {
+	as := assert.New(t)
+	l := storage.NewList()
+	as.Equal(storage.ListKind, l.Kind())
+	as.Equal(0, l.Len())
+
+	l.PushBack(values(3, 4, 5)...)
+	l.PushFront(values(2, 1, 0)...)
+	as.Equal(6, l.Len())
+	as.Equal(values(0, 1, 2, 3, 4, 5), l.Range(0, -1))
+	as.Equal(values(4, 5), l.Range(-2, 100))
+	as.Equal(values(), l.Range(4, 2))
+	as.Equal(values(), l.Range(10, 20))
+
+	v, ok := l.Index(-1)
+	as.True(ok)
+	as.Equal(resp.Integer(5), v)
+	_, ok = l.Index(6)
+	as.False(ok)
+
+	as.Equal(values(0, 1), l.PopFront(2))
+	as.Equal(values(5), l.PopBack(1))
+	as.Equal(values(2, 3, 4), l.Range(0, -1))
+
+	for i := 10; i < 30; i++ {
+		l.PushFront(resp.Integer(i))
+		l.PushBack(resp.Integer(i))
+	}
+	as.Equal(43, l.Len())
+	l.Trim(19, 23)
+	as.Equal(values(10, 2, 3, 4, 10), l.Range(0, -1))
+	as.Equal(values(10, 4, 3, 2, 10), l.PopBack(10))
+	as.Equal(0, l.Len())
+
+	as.True(resp.EmptyArray.Equal(l.Snapshot()))
+}
+
+func TestMutateList(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"list"}
+
+	err := storage.MutateList(s, key, false, func(l *storage.List) error {
+		as.Fail("list should not be created")
+		return nil
+	})
+	as.ErrorIs(err, storage.KeyNotFound)
+
+	as.Nil(storage.MutateList(s, key, true, func(l *storage.List) error {
+		l.PushBack(values(1, 2, 3)...)
+		return nil
+	}))
+
+	k, err := s.Kind(key)
+	as.Nil(err)
+	as.Equal(storage.ListKind, k)
+	as.Equal("list", k.String())
+
+	_, err = s.Get(key)
+	as.Equal(storage.WrongType, err)
+
+	as.Nil(storage.InspectList(s, key, func(l *storage.List) error {
+		as.Equal(3, l.Len())
+		as.Equal(resp.MakeArray(values(1, 2, 3)...), l.Snapshot())
+		return nil
+	}))
+
+	as.Nil(storage.MutateList(s, key, false, func(l *storage.List) error {
+		l.PopFront(3)
+		return nil
+	}))
+	ok, err := s.Exists(key)
+	as.False(ok)
+	as.ErrorIs(err, storage.KeyNotFound)
+
+	_, err = s.Set(key, resp.BulkString("string"))
+	as.Nil(err)
+	k, err = s.Kind(key)
+	as.Nil(err)
+	as.Equal(storage.ValueKind, k)
+	as.ErrorIs(
+		storage.MutateList(s, key, true, func(*storage.List) error {
+			return nil
+		}),
+		storage.WrongType,
+	)
+	as.ErrorIs(
+		storage.InspectList(s, key, func(*storage.List) error {
+			return nil
+		}),
+		storage.WrongType,
+	)
+}
@@ -4,19 +4,33 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/kode4food/respect/pkg/resp"
 )
 
 type (
+	memory struct {
+		*memNode
+		expiry *expiryQueue
+	}
+
 	memNode struct {
-		children map[resp.BulkString]*memNode
+		children table[*memNode]
 		value    resp.Value
+		deadline time.Time
 		version  int
+		revision uint64
+		pruned   uint64
 		sync.RWMutex
 	}
 
 	keySeen struct{}
+
+	keyNotFoundError struct {
+		key Key
+	}
 )
 
 // Error messages
@@ -26,11 +40,53 @@ const (
 
 // compile-time checks for interface implementation
 var (
-	_ Storage = (*memNode)(nil)
+	_ Storage = (*memory)(nil)
 )
 
+// revisions is the source of memNode revisions. It's shared by every memory
+// Storage so that a Revision is never reused, even by a Key that is removed
+// and then stored again
+var revisions uint64
+
 func NewMemory() Storage {
-	return &memNode{}
+	root := &memNode{}
+	return &lockedMemory{
+		memory: &memory{
+			memNode: root,
+			expiry:  newExpiryQueue(root),
+		},
+	}
+}
+
+// Atomic calls the function with the memory itself. It's only reachable
+// through a lockedMemory, which already holds exclusive access
+func (m *memory) Atomic(fn func(Storage) error) error {
+	return fn(m)
+}
+
+func (m *memory) SetWith(
+	key Key, value resp.Value, opts SetOptions,
+) (resp.Value, bool, error) {
+	if opts.Deadline.IsZero() {
+		return m.memNode.SetWith(key, value, opts)
+	}
+	m.expiry.Lock()
+	defer m.expiry.Unlock()
+	old, ok, err := m.memNode.SetWith(key, value, opts)
+	if err == nil && ok {
+		m.expiry.schedule(key, opts.Deadline)
+	}
+	return old, ok, err
+}
+
+func (m *memory) Expire(key Key, deadline time.Time) error {
+	m.expiry.Lock()
+	defer m.expiry.Unlock()
+	if err := m.memNode.Expire(key, deadline); err != nil {
+		return err
+	}
+	m.expiry.schedule(key, deadline)
+	return nil
 }
 
 func (m *memNode) Get(key Key) (resp.Value, error) {
@@ -40,11 +96,14 @@ func (m *memNode) Get(key Key) (resp.Value, error) {
 	m.RLock()
 	if child := m.fetchNested(key); child != nil {
 		defer child.RUnlock()
-		if child.value != nil {
-			return child.value, nil
+		if v := child.liveValue(); v != nil {
+			if _, ok := v.(Mutable); ok {
+				return nil, WrongType
+			}
+			return v, nil
 		}
 	}
-	return nil, fmt.Errorf(ErrKeyNotFound, key)
+	return nil, keyNotFound(key)
 }
 
 func (m *memNode) fetchNested(key Key) *memNode {
@@ -61,42 +120,134 @@ func (m *memNode) fetchNested(key Key) *memNode {
 }
 
 func (m *memNode) getChild(comp resp.BulkString) (*memNode, bool) {
-	if m.children == nil {
-		return nil, false
-	}
-	child, ok := m.children[comp]
-	return child, ok
+	return m.children.get(comp)
 }
 
 func (m *memNode) Set(key Key, value resp.Value) (resp.Value, error) {
+	old, _, err := m.SetWith(key, value, SetOptions{})
+	return old, err
+}
+
+func (m *memNode) SetWith(
+	key Key, value resp.Value, opts SetOptions,
+) (resp.Value, bool, error) {
+	if len(key) == 0 {
+		return nil, false, fmt.Errorf(ErrEmptyKey)
+	}
+	var old resp.Value
+	var ok bool
+	var err error
+	m.Lock()
+	m.update(key, func(n *memNode) {
+		old = n.liveValue()
+		if _, mut := old.(Mutable); mut && opts.ValuesOnly {
+			old, err = nil, WrongType
+			return
+		}
+		if ok = opts.Condition.accepts(old != nil); !ok {
+			return
+		}
+		if !opts.KeepDeadline || old == nil {
+			n.deadline = opts.Deadline
+		}
+		n.value = value
+		n.touch()
+	})
+	return old, ok, err
+}
+
+func (m *memNode) Update(key Key, fn Updater) (resp.Value, error) {
 	if len(key) == 0 {
 		return nil, fmt.Errorf(ErrEmptyKey)
 	}
+	var res resp.Value
+	var err error
+	m.Lock()
+	m.update(key, func(n *memNode) {
+		res, err = n.apply(fn)
+	})
+	return res, err
+}
+
+func (m *memNode) apply(fn Updater) (resp.Value, error) {
+	cur := m.liveValue()
+	if _, ok := cur.(Mutable); ok {
+		return nil, WrongType
+	}
+	res, err := fn(cur)
+	switch {
+	case err != nil:
+		return nil, err
+	case res == nil:
+		m.clear()
+	default:
+		if cur == nil {
+			m.deadline = time.Time{}
+		}
+		m.value = res
+		m.touch()
+	}
+	return res, nil
+}
+
+func (m *memNode) Mutate(key Key, fn Mutator) error {
+	if len(key) == 0 {
+		return fmt.Errorf(ErrEmptyKey)
+	}
+	var err error
 	m.Lock()
-	return m.set(key, value), nil
+	m.update(key, func(n *memNode) {
+		err = n.mutate(fn)
+	})
+	return err
 }
 
-func (m *memNode) set(k Key, v resp.Value) resp.Value {
+func (m *memNode) mutate(fn Mutator) error {
+	var cur Mutable
+	if v := m.liveValue(); v != nil {
+		mv, ok := v.(Mutable)
+		if !ok {
+			return WrongType
+		}
+		cur = mv
+	}
+	res, err := fn(cur)
+	switch {
+	case err != nil:
+		return err
+	case res == nil || res.Len() == 0:
+		m.clear()
+	case res != cur:
+		m.value = res
+		m.deadline = time.Time{}
+		m.touch()
+	default:
+		m.touch()
+	}
+	return nil
+}
+
+// update applies a function to the write-locked node at the provided Key,
+// creating the node if necessary, and pruning it if it's been left empty
+func (m *memNode) update(k Key, fn func(*memNode)) {
 	if len(k) == 0 {
-		old := m.value
-		m.value = v
-		m.version++
-		m.Unlock()
-		return old
+		defer m.Unlock()
+		fn(m)
+		return
 	}
-	child := m.ensureNested(k[0])
+	comp := k[0]
+	child := m.ensureNested(comp)
 	m.transferLockTo(child)
-	return child.set(k[1:], v)
+	child.update(k[1:], fn)
+	m.attemptToPrune(comp)
 }
 
 func (m *memNode) ensureNested(comp resp.BulkString) *memNode {
-	if m.children == nil {
-		m.children = map[resp.BulkString]*memNode{}
-	}
-	child, ok := m.children[comp]
+	child, ok := m.children.get(comp)
 	if !ok {
-		child = &memNode{}
-		m.children[comp] = child
+		rev := nextRevision()
+		child = &memNode{revision: rev, pruned: rev}
+		m.children.put(comp, child)
 		m.version++
 	}
 	return child
@@ -107,28 +258,22 @@ func (m *memNode) Delete(key Key) (resp.Value, error) {
 		return nil, fmt.Errorf(ErrEmptyKey)
 	}
 	m.Lock()
-	res, ok := m.delete(key)
+	res, ok := m.modify(key, (*memNode).clear)
 	if !ok {
-		return nil, fmt.Errorf(ErrKeyNotFound, key)
+		return nil, keyNotFound(key)
 	}
 	return res, nil
 }
 
-func (m *memNode) delete(k Key) (resp.Value, bool) {
-	if len(k) > 0 {
-		return m.deleteNested(k)
-	}
-	defer m.Unlock()
-	old := m.value
-	if old == nil {
-		return nil, false
+// modify applies a function to the write-locked node at the provided Key,
+// pruning any nodes that have been left empty as a result
+func (m *memNode) modify(
+	k Key, fn func(*memNode) (resp.Value, bool),
+) (resp.Value, bool) {
+	if len(k) == 0 {
+		defer m.Unlock()
+		return fn(m)
 	}
-	m.value = nil
-	m.version++
-	return old, true
-}
-
-func (m *memNode) deleteNested(k Key) (resp.Value, bool) {
 	comp := k[0]
 	child, ok := m.getChild(comp)
 	if !ok {
@@ -136,25 +281,36 @@ func (m *memNode) deleteNested(k Key) (resp.Value, bool) {
 		return nil, false
 	}
 	m.transferLockTo(child)
-	v, ok := child.delete(k[1:])
-	if !ok {
-		return nil, false
-	}
-
+	v, ok := child.modify(k[1:], fn)
 	m.attemptToPrune(comp)
-	return v, true
+	return v, ok
+}
+
+func (m *memNode) clear() (resp.Value, bool) {
+	old := m.liveValue()
+	if m.value != nil {
+		m.value = nil
+		m.deadline = time.Time{}
+		m.touch()
+	}
+	return old, old != nil
 }
 
 func (m *memNode) attemptToPrune(comp resp.BulkString) {
 	m.Lock()
 	defer m.Unlock()
 	if child, ok := m.getChild(comp); ok && child.canBePruned() {
-		delete(m.children, comp)
+		m.children.remove(comp)
+		m.pruned = nextRevision()
 	}
 }
 
+// canBePruned locks the node because another operation may have passed
+// through its parent and be in the middle of storing a value in it
 func (m *memNode) canBePruned() bool {
-	return m.value == nil && len(m.children) == 0
+	m.Lock()
+	defer m.Unlock()
+	return m.value == nil && m.children.len() == 0
 }
 
 func (m *memNode) Exists(key Key) (bool, error) {
@@ -164,11 +320,135 @@ func (m *memNode) Exists(key Key) (bool, error) {
 	m.RLock()
 	if child := m.fetchNested(key); child != nil {
 		defer child.RUnlock()
-		if child.value != nil {
+		if child.liveValue() != nil {
 			return true, nil
 		}
 	}
-	return false, fmt.Errorf(ErrKeyNotFound, key)
+	return false, keyNotFound(key)
+}
+
+func (m *memNode) Expire(key Key, deadline time.Time) error {
+	if len(key) == 0 {
+		return fmt.Errorf(ErrEmptyKey)
+	}
+	m.Lock()
+	if _, ok := m.modify(key, func(n *memNode) (resp.Value, bool) {
+		v := n.liveValue()
+		if v != nil {
+			n.deadline = deadline
+			n.touch()
+		}
+		return v, v != nil
+	}); !ok {
+		return keyNotFound(key)
+	}
+	return nil
+}
+
+func (m *memNode) Persist(key Key) (bool, error) {
+	if len(key) == 0 {
+		return false, fmt.Errorf(ErrEmptyKey)
+	}
+	var persisted bool
+	m.Lock()
+	if _, ok := m.modify(key, func(n *memNode) (resp.Value, bool) {
+		v := n.liveValue()
+		if v != nil && !n.deadline.IsZero() {
+			n.deadline = time.Time{}
+			n.touch()
+			persisted = true
+		}
+		return v, v != nil
+	}); !ok {
+		return false, keyNotFound(key)
+	}
+	return persisted, nil
+}
+
+func (m *memNode) Deadline(key Key) (time.Time, bool, error) {
+	if len(key) == 0 {
+		return time.Time{}, false, fmt.Errorf(ErrEmptyKey)
+	}
+	m.RLock()
+	if child := m.fetchNested(key); child != nil {
+		defer child.RUnlock()
+		if child.liveValue() != nil {
+			return child.deadline, !child.deadline.IsZero(), nil
+		}
+	}
+	return time.Time{}, false, keyNotFound(key)
+}
+
+func (m *memNode) Revision(key Key) (uint64, bool, error) {
+	if len(key) == 0 {
+		return 0, false, fmt.Errorf(ErrEmptyKey)
+	}
+	m.RLock()
+	rev, ok := m.revisionOf(key)
+	return rev, ok, nil
+}
+
+// revisionOf returns the revision of the node at the provided Key, and
+// whether it holds a value. If there's no such node, the revision at which
+// the nearest node along the way last had a child pruned is returned, as
+// that's when the Key would have been removed
+func (m *memNode) revisionOf(key Key) (uint64, bool) {
+	if len(key) == 0 {
+		defer m.RUnlock()
+		return m.revision, m.liveValue() != nil
+	}
+	child, ok := m.getChild(key[0])
+	if !ok {
+		defer m.RUnlock()
+		return m.pruned, false
+	}
+	m.transferRLockTo(child)
+	return child.revisionOf(key[1:])
+}
+
+func (m *memNode) Kind(key Key) (Kind, error) {
+	if len(key) == 0 {
+		return ValueKind, fmt.Errorf(ErrEmptyKey)
+	}
+	m.RLock()
+	if child := m.fetchNested(key); child != nil {
+		defer child.RUnlock()
+		if v := child.liveValue(); v != nil {
+			if mv, ok := v.(Mutable); ok {
+				return mv.Kind(), nil
+			}
+			return ValueKind, nil
+		}
+	}
+	return ValueKind, keyNotFound(key)
+}
+
+func (m *memNode) Inspect(key Key, fn Inspector) error {
+	if len(key) == 0 {
+		return fmt.Errorf(ErrEmptyKey)
+	}
+	m.RLock()
+	if child := m.fetchNested(key); child != nil {
+		defer child.RUnlock()
+		if v := child.liveValue(); v != nil {
+			if mv, ok := v.(Mutable); ok {
+				return fn(mv)
+			}
+			return WrongType
+		}
+	}
+	return keyNotFound(key)
+}
+
+// expire removes the value at the provided Key if its deadline has passed
+func (m *memNode) expire(key Key, now time.Time) {
+	m.Lock()
+	m.modify(key, func(n *memNode) (resp.Value, bool) {
+		if n.value == nil || !n.isExpired(now) {
+			return nil, false
+		}
+		return n.clear()
+	})
 }
 
 func (m *memNode) IterateKeys(pfx Key, accept Accept[Key]) error {
@@ -180,7 +460,7 @@ func (m *memNode) IterateKeys(pfx Key, accept Accept[Key]) error {
 		}
 		return err
 	}
-	return fmt.Errorf(ErrKeyNotFound, pfx)
+	return keyNotFound(pfx)
 }
 
 func (m *memNode) forEach(pfx Key, accept Accept[Key]) error {
@@ -189,15 +469,15 @@ func (m *memNode) forEach(pfx Key, accept Accept[Key]) error {
 	ver := m.version
 	cur := 0
 
-	for cur < len(keys) {
+	for cur < len(keys) || m.version != ver {
 		if m.version != ver {
 			old := keys[:cur]
 			keys = append(old, m.getNewKeys(old)...)
 			ver = m.version
 			continue
 		}
-		if child, ok := m.children[keys[cur]]; ok {
-			ck := append(pfx, keys[cur])
+		if child, ok := m.children.get(keys[cur]); ok {
+			ck := append(pfx[:len(pfx):len(pfx)], keys[cur])
 			child.RLock()
 			if err := m.doRUnlocked(func() error {
 				return child.forEach(ck, accept)
@@ -208,7 +488,7 @@ func (m *memNode) forEach(pfx Key, accept Accept[Key]) error {
 		cur++
 	}
 
-	if m.value != nil {
+	if m.liveValue() != nil {
 		return m.doRUnlocked(func() error {
 			return accept(pfx)
 		})
@@ -216,12 +496,65 @@ func (m *memNode) forEach(pfx Key, accept Accept[Key]) error {
 	return nil
 }
 
-func (m *memNode) getKeys() []resp.BulkString {
-	res := make([]resp.BulkString, 0, len(m.children))
-	for k := range m.children {
-		res = append(res, k)
+func (m *memNode) ScanKeys(
+	pfx Key, cursor uint64, count int, accept Accept[Key],
+) (uint64, error) {
+	m.RLock()
+	if child := m.fetchNested(pfx); child != nil {
+		next, err := child.scan(pfx, cursor, count, accept)
+		if err == nil {
+			return next, nil
+		}
+		if errors.Is(err, StopIteration) {
+			return 0, nil
+		}
+		return 0, err
 	}
-	return res
+	return 0, keyNotFound(pfx)
+}
+
+// scan visits the keys of a node's children, one bucket at a time, until at
+// least the requested number of keys have been visited. A child's descendants
+// are always visited along with it
+func (m *memNode) scan(
+	pfx Key, cursor uint64, count int, accept Accept[Key],
+) (uint64, error) {
+	defer m.RUnlock()
+	seen := 0
+	counted := func(k Key) error {
+		seen++
+		return accept(k)
+	}
+
+	if cursor == 0 && m.liveValue() != nil {
+		if err := m.doRUnlocked(func() error {
+			return counted(pfx)
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	for {
+		entries, next := m.children.bucket(cursor)
+		for _, e := range entries {
+			ck := append(pfx[:len(pfx):len(pfx)], e.name)
+			child := e.value
+			child.RLock()
+			if err := m.doRUnlocked(func() error {
+				return child.forEach(ck, counted)
+			}); err != nil {
+				return 0, err
+			}
+		}
+		cursor = next
+		if cursor == 0 || seen >= count {
+			return cursor, nil
+		}
+	}
+}
+
+func (m *memNode) getKeys() []resp.BulkString {
+	return m.children.names()
 }
 
 func (m *memNode) getNewKeys(old []resp.BulkString) []resp.BulkString {
@@ -229,8 +562,8 @@ func (m *memNode) getNewKeys(old []resp.BulkString) []resp.BulkString {
 	for _, k := range old {
 		seen[k] = keySeen{}
 	}
-	res := make([]resp.BulkString, 0, len(m.children)-len(seen))
-	for k := range m.children {
+	res := make([]resp.BulkString, 0, m.children.len()-len(seen))
+	for _, k := range m.children.names() {
 		if _, ok := seen[k]; !ok {
 			res = append(res, k)
 		}
@@ -238,6 +571,24 @@ func (m *memNode) getNewKeys(old []resp.BulkString) []resp.BulkString {
 	return res
 }
 
+// liveValue returns the node's value, unless it is missing or has expired
+func (m *memNode) liveValue() resp.Value {
+	if m.value == nil || m.isExpired(time.Now()) {
+		return nil
+	}
+	return m.value
+}
+
+func (m *memNode) isExpired(now time.Time) bool {
+	return !m.deadline.IsZero() && !now.Before(m.deadline)
+}
+
+// touch records a change to the node's value or deadline
+func (m *memNode) touch() {
+	m.version++
+	m.revision = nextRevision()
+}
+
 func (m *memNode) transferLockTo(child *memNode) {
 	child.Lock()
 	m.Unlock()
@@ -253,3 +604,19 @@ func (m *memNode) doRUnlocked(fn func() error) error {
 	defer m.RLock()
 	return fn()
 }
+
+func nextRevision() uint64 {
+	return atomic.AddUint64(&revisions, 1)
+}
+
+func keyNotFound(k Key) error {
+	return &keyNotFoundError{key: k}
+}
+
+func (e *keyNotFoundError) Error() string {
+	return fmt.Sprintf(ErrKeyNotFound, e.key)
+}
+
+func (e *keyNotFoundError) Is(err error) bool {
+	return err == KeyNotFound
+}
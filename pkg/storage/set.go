@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"io"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+// Set is a Mutable collection of unique members
+type Set struct {
+	members table[struct{}]
+}
+
+// compile-time checks for interface implementation
+var _ Mutable = (*Set)(nil)
+
+// NewSet creates a new Set containing the provided members
+func NewSet(members ...resp.BulkString) *Set {
+	res := &Set{}
+	for _, m := range members {
+		res.Add(m)
+	}
+	return res
+}
+
+// MutateSet applies a function to the Set stored at a Key while the Key is
+// locked for writing. If the Key doesn't exist and create is true, a new Set
+// is stored, otherwise a KeyNotFound error is returned
+func MutateSet(s Storage, k Key, create bool, fn func(*Set) error) error {
+	var mk func() *Set
+	if create {
+		mk = func() *Set { return NewSet() }
+	}
+	return mutateAs(s, k, mk, fn)
+}
+
+// InspectSet applies a function to the Set stored at a Key while the Key is
+// locked for reading
+func InspectSet(s Storage, k Key, fn func(*Set) error) error {
+	return inspectAs(s, k, fn)
+}
+
+// Union returns a new Set containing the members of every provided Set
+func Union(sets ...*Set) *Set {
+	res := NewSet()
+	for _, s := range sets {
+		_ = s.ForEach(func(m resp.BulkString) error {
+			res.Add(m)
+			return nil
+		})
+	}
+	return res
+}
+
+// Intersect returns a new Set containing the members that every provided Set
+// has in common
+func Intersect(sets ...*Set) *Set {
+	res := NewSet()
+	if len(sets) == 0 {
+		return res
+	}
+	_ = sets[0].ForEach(func(m resp.BulkString) error {
+		for _, s := range sets[1:] {
+			if !s.Contains(m) {
+				return nil
+			}
+		}
+		res.Add(m)
+		return nil
+	})
+	return res
+}
+
+// Difference returns a new Set containing the members of the first Set that
+// none of the others contain
+func Difference(first *Set, rest ...*Set) *Set {
+	res := NewSet()
+	_ = first.ForEach(func(m resp.BulkString) error {
+		for _, s := range rest {
+			if s.Contains(m) {
+				return nil
+			}
+		}
+		res.Add(m)
+		return nil
+	})
+	return res
+}
+
+func (*Set) Kind() Kind {
+	return SetKind
+}
+
+func (s *Set) Len() int {
+	return s.members.len()
+}
+
+// Add adds a member, returning whether it is new
+func (s *Set) Add(member resp.BulkString) bool {
+	return s.members.put(member, struct{}{})
+}
+
+// Remove removes a member, returning whether it was present
+func (s *Set) Remove(member resp.BulkString) bool {
+	return s.members.remove(member)
+}
+
+// Contains returns whether the Set contains a member
+func (s *Set) Contains(member resp.BulkString) bool {
+	_, ok := s.members.get(member)
+	return ok
+}
+
+// Members returns the members of the Set, in no particular order
+func (s *Set) Members() []resp.BulkString {
+	return s.members.names()
+}
+
+// ForEach calls a function for each member of the Set until it returns an
+// error
+func (s *Set) ForEach(fn func(resp.BulkString) error) error {
+	return s.members.forEach(func(m resp.BulkString, _ struct{}) error {
+		return fn(m)
+	})
+}
+
+// Scan visits the members of the Set, resuming from a cursor returned by a
+// previous call, with the same guarantees as Storage's ScanKeys
+func (s *Set) Scan(
+	cursor uint64, count int, fn func(resp.BulkString),
+) uint64 {
+	return s.members.scan(cursor, count,
+		func(m resp.BulkString, _ struct{}) {
+			fn(m)
+		},
+	)
+}
+
+func (s *Set) Snapshot() resp.Value {
+	res := make([]resp.Value, 0, s.Len())
+	_ = s.ForEach(func(m resp.BulkString) error {
+		res = append(res, m)
+		return nil
+	})
+	return resp.MakeSet(res...)
+}
+
+func (*Set) Tag() resp.Tag {
+	return resp.SetTag
+}
+
+func (s *Set) Marshal(w io.Writer) error {
+	return s.Snapshot().Marshal(w)
+}
+
+func (s *Set) Equal(v resp.Value) bool {
+	if v, ok := v.(*Set); ok {
+		return s == v || s.Snapshot().Equal(v.Snapshot())
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package storage_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewSet("a", "b")
+	as.Equal(storage.SetKind, s.Kind())
+	as.Equal("set", s.Kind().String())
+
+	as.True(s.Add("c"))
+	as.False(s.Add("a"))
+	as.Equal(3, s.Len())
+	as.True(s.Contains("b"))
+	as.False(s.Contains("d"))
+	as.ElementsMatch([]resp.BulkString{"a", "b", "c"}, s.Members())
+	as.True(s.Snapshot().Equal(resp.MakeSet(
+		resp.BulkString("a"), resp.BulkString("b"), resp.BulkString("c"),
+	)))
+
+	as.True(s.Remove("a"))
+	as.False(s.Remove("a"))
+	as.Equal(2, s.Len())
+}
+
+func TestSetAlgebra(t *testing.T) {
+	as := assert.New(t)
+	a := storage.NewSet("a", "b", "c", "d")
+	b := storage.NewSet("c", "d", "e")
+	c := storage.NewSet("d", "f")
+
+	as.ElementsMatch(
+		[]resp.BulkString{"a", "b", "c", "d", "e", "f"},
+		storage.Union(a, b, c).Members(),
+	)
+	as.ElementsMatch(
+		[]resp.BulkString{"d"}, storage.Intersect(a, b, c).Members(),
+	)
+	as.ElementsMatch(
+		[]resp.BulkString{"a", "b"}, storage.Difference(a, b, c).Members(),
+	)
+	as.Equal(0, storage.Intersect().Len())
+	as.Equal(0, storage.Intersect(a, storage.NewSet()).Len())
+}
+
+func TestMutateSet(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"set"}
+
+	as.Nil(storage.MutateSet(s, key, true, func(set *storage.Set) error {
+		set.Add("member")
+		return nil
+	}))
+	k, err := s.Kind(key)
+	as.Nil(err)
+	as.Equal(storage.SetKind, k)
+
+	as.Nil(storage.InspectSet(s, key, func(set *storage.Set) error {
+		as.True(set.Contains("member"))
+		return nil
+	}))
+	as.ErrorIs(
+		storage.InspectSortedSet(s, key, func(*storage.SortedSet) error {
+			return nil
+		}),
+		storage.WrongType,
+	)
+
+	as.Nil(storage.MutateSet(s, key, false, func(set *storage.Set) error {
+		set.Remove("member")
+		return nil
+	}))
+	_, err = s.Exists(key)
+	as.ErrorIs(err, storage.KeyNotFound)
+}
+
+func TestMemoryAtomic(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	from := storage.Key{"from"}
+	to := storage.Key{"to"}
+	as.Nil(storage.MutateSet(s, from, true, func(set *storage.Set) error {
+		for i := 0; i < 100; i++ {
+			set.Add(resp.BulkString(resp.Integer(i).String()))
+		}
+		return nil
+	}))
+
+	move := func(tx storage.Storage) error {
+		var member resp.BulkString
+		err := storage.MutateSet(tx, from, false,
+			func(set *storage.Set) error {
+				member = set.Members()[0]
+				set.Remove(member)
+				return nil
+			},
+		)
+		if err != nil {
+			return err
+		}
+		runtime.Gosched()
+		return storage.MutateSet(tx, to, true, func(set *storage.Set) error {
+			set.Add(member)
+			return nil
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				as.Nil(s.Atomic(move))
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		as.Nil(s.Atomic(func(tx storage.Storage) error {
+			total := 0
+			for _, k := range []storage.Key{from, to} {
+				_ = storage.InspectSet(tx, k, func(set *storage.Set) error {
+					total += set.Len()
+					return nil
+				})
+				runtime.Gosched()
+			}
+			as.Equal(100, total)
+			return nil
+		}))
+	}
+	wg.Wait()
+
+	_, err := s.Exists(from)
+	as.ErrorIs(err, storage.KeyNotFound)
+	as.Nil(storage.InspectSet(s, to, func(set *storage.Set) error {
+		as.Equal(100, set.Len())
+		return nil
+	}))
+}
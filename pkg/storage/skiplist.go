@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"math/rand/v2"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// skiplist orders members by score, and then lexicographically. Each
+	// link records how many nodes it spans, so that ranks can be found with
+	// the same logarithmic cost as members
+	skiplist struct {
+		head   *skipNode
+		tail   *skipNode
+		length int
+		level  int
+	}
+
+	skipNode struct {
+		ScoredMember
+		back   *skipNode
+		levels []skipLevel
+	}
+
+	skipLevel struct {
+		next *skipNode
+		span int
+	}
+)
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 4 // one in skiplistP nodes is promoted to a new level
+)
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		head:  &skipNode{levels: make([]skipLevel, skiplistMaxLevel)},
+		level: 1,
+	}
+}
+
+func (l *skiplist) insert(score float64, member resp.BulkString) {
+	var update [skiplistMaxLevel]*skipNode
+	var rank [skiplistMaxLevel]int
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for n := x.levels[i].next; n != nil && n.before(score, member); {
+			rank[i] += x.levels[i].span
+			x, n = n, n.levels[i].next
+		}
+		update[i] = x
+	}
+
+	lvl := randomSkipLevel()
+	for ; l.level < lvl; l.level++ {
+		update[l.level] = l.head
+		l.head.levels[l.level].span = l.length
+	}
+
+	x = &skipNode{
+		ScoredMember: ScoredMember{Member: member, Score: score},
+		levels:       make([]skipLevel, lvl),
+	}
+	for i := 0; i < lvl; i++ {
+		prev := &update[i].levels[i]
+		x.levels[i].next = prev.next
+		x.levels[i].span = prev.span - (rank[0] - rank[i])
+		prev.next = x
+		prev.span = rank[0] - rank[i] + 1
+	}
+	for i := lvl; i < l.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != l.head {
+		x.back = update[0]
+	}
+	if next := x.levels[0].next; next != nil {
+		next.back = x
+	} else {
+		l.tail = x
+	}
+	l.length++
+}
+
+func (l *skiplist) remove(score float64, member resp.BulkString) bool {
+	var update [skiplistMaxLevel]*skipNode
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for n := x.levels[i].next; n != nil && n.before(score, member); {
+			x, n = n, n.levels[i].next
+		}
+		update[i] = x
+	}
+	x = x.levels[0].next
+	if x == nil || x.Score != score || x.Member != member {
+		return false
+	}
+
+	for i := 0; i < l.level; i++ {
+		prev := &update[i].levels[i]
+		if prev.next == x {
+			prev.span += x.levels[i].span - 1
+			prev.next = x.levels[i].next
+		} else {
+			prev.span--
+		}
+	}
+	if next := x.levels[0].next; next != nil {
+		next.back = x.back
+	} else {
+		l.tail = x.back
+	}
+	for l.level > 1 && l.head.levels[l.level-1].next == nil {
+		l.level--
+	}
+	l.length--
+	return true
+}
+
+// rank returns the zero-based position of a member with the provided score
+func (l *skiplist) rank(score float64, member resp.BulkString) int {
+	x, rank := l.lastWhere(func(n *skipNode) bool {
+		return !n.after(score, member)
+	})
+	if x == l.head || x.Member != member {
+		return -1
+	}
+	return rank - 1
+}
+
+// byRank returns the node at a zero-based position
+func (l *skiplist) byRank(rank int) *skipNode {
+	if rank < 0 || rank >= l.length {
+		return nil
+	}
+	x := l.head
+	traversed := 0
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && traversed+x.levels[i].span <= rank+1 {
+			traversed += x.levels[i].span
+			x = x.levels[i].next
+		}
+		if traversed == rank+1 {
+			return x
+		}
+	}
+	return nil
+}
+
+// lastWhere returns the last node for which the predicate holds, along with
+// its one-based rank. The predicate must hold for a prefix of the list. If it
+// holds for no nodes, the head is returned with a rank of zero
+func (l *skiplist) lastWhere(pred func(*skipNode) bool) (*skipNode, int) {
+	x := l.head
+	rank := 0
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].next != nil && pred(x.levels[i].next) {
+			rank += x.levels[i].span
+			x = x.levels[i].next
+		}
+	}
+	return x, rank
+}
+
+func (n *skipNode) before(score float64, member resp.BulkString) bool {
+	return n.Score < score || n.Score == score && n.Member < member
+}
+
+func (n *skipNode) after(score float64, member resp.BulkString) bool {
+	return n.Score > score || n.Score == score && n.Member > member
+}
+
+func randomSkipLevel() int {
+	lvl := 1
+	for lvl < skiplistMaxLevel && rand.N(skiplistP) == 0 {
+		lvl++
+	}
+	return lvl
+}
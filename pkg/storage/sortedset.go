@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"io"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// SortedSet is a Mutable set of unique members, each associated with a
+	// score. Members are ordered by score, and members with equal scores are
+	// ordered lexicographically
+	SortedSet struct {
+		scores table[float64]
+		list   *skiplist
+	}
+
+	// ScoredMember is a SortedSet member along with its score
+	ScoredMember struct {
+		Member resp.BulkString
+		Score  float64
+	}
+
+	// ScoreBound is the lower or upper limit of a range of scores
+	ScoreBound struct {
+		Score     float64
+		Exclusive bool
+	}
+
+	// LexBound is the lower or upper limit of a lexicographical range of
+	// members. If Infinity is negative or positive, the bound lies before or
+	// after every member, and Member is ignored
+	LexBound struct {
+		Member    resp.BulkString
+		Exclusive bool
+		Infinity  int
+	}
+
+	// Limit selects a window of the members that fall within a range. A
+	// negative Count includes every member that follows the Offset
+	Limit struct {
+		Offset int
+		Count  int
+	}
+)
+
+// NoLimit selects every member that falls within a range
+var NoLimit = Limit{Count: -1}
+
+// compile-time checks for interface implementation
+var _ Mutable = (*SortedSet)(nil)
+
+// NewSortedSet creates a new empty SortedSet
+func NewSortedSet() *SortedSet {
+	return &SortedSet{list: newSkiplist()}
+}
+
+// MutateSortedSet applies a function to the SortedSet stored at a Key while
+// the Key is locked for writing. If the Key doesn't exist and create is true,
+// a new SortedSet is stored, otherwise a KeyNotFound error is returned
+func MutateSortedSet(
+	s Storage, k Key, create bool, fn func(*SortedSet) error,
+) error {
+	var mk func() *SortedSet
+	if create {
+		mk = NewSortedSet
+	}
+	return mutateAs(s, k, mk, fn)
+}
+
+// InspectSortedSet applies a function to the SortedSet stored at a Key while
+// the Key is locked for reading
+func InspectSortedSet(s Storage, k Key, fn func(*SortedSet) error) error {
+	return inspectAs(s, k, fn)
+}
+
+func (*SortedSet) Kind() Kind {
+	return SortedSetKind
+}
+
+func (z *SortedSet) Len() int {
+	return z.list.length
+}
+
+// Score returns the score of a member
+func (z *SortedSet) Score(member resp.BulkString) (float64, bool) {
+	return z.scores.get(member)
+}
+
+// Add stores a member with the provided score, replacing its existing score
+// if it is already present. Returns whether the member is new
+func (z *SortedSet) Add(member resp.BulkString, score float64) bool {
+	if old, ok := z.scores.get(member); ok {
+		if old != score {
+			z.list.remove(old, member)
+			z.list.insert(score, member)
+			z.scores.put(member, score)
+		}
+		return false
+	}
+	z.list.insert(score, member)
+	z.scores.put(member, score)
+	return true
+}
+
+// Remove removes a member, returning whether it was present
+func (z *SortedSet) Remove(member resp.BulkString) bool {
+	score, ok := z.scores.get(member)
+	if !ok {
+		return false
+	}
+	z.list.remove(score, member)
+	z.scores.remove(member)
+	return true
+}
+
+// Rank returns the zero-based position of a member in ascending order
+func (z *SortedSet) Rank(member resp.BulkString) (int, bool) {
+	score, ok := z.scores.get(member)
+	if !ok {
+		return 0, false
+	}
+	return z.list.rank(score, member), true
+}
+
+// Range returns the members between two inclusive ranks, where negative ranks
+// count back from the end. If rev is true, ranks are counted from the highest
+// score and the members are returned in descending order
+func (z *SortedSet) Range(start, stop int, rev bool) []ScoredMember {
+	from, to := normalizeRange(start, stop, z.Len())
+	if from == to {
+		return []ScoredMember{}
+	}
+	count := to - from
+	if rev {
+		from = z.Len() - from - 1
+	}
+	x := z.list.byRank(from)
+	res := make([]ScoredMember, 0, count)
+	for i := count; i > 0; i-- {
+		res = append(res, x.ScoredMember)
+		x = x.step(rev)
+	}
+	return res
+}
+
+// RangeByScore returns the members whose scores fall between two bounds,
+// windowed by a Limit. If rev is true, the members are returned in descending
+// order, and the Limit is applied from the highest score
+func (z *SortedSet) RangeByScore(
+	min, max ScoreBound, rev bool, limit Limit,
+) []ScoredMember {
+	return z.rangeWhere(min.below, max.above, rev, limit)
+}
+
+// RangeByLex returns the members that fall lexicographically between two
+// bounds, windowed by a Limit. The result is only meaningful if every member
+// of the SortedSet has the same score
+func (z *SortedSet) RangeByLex(
+	min, max LexBound, rev bool, limit Limit,
+) []ScoredMember {
+	return z.rangeWhere(min.below, max.above, rev, limit)
+}
+
+// CountByScore returns the number of members whose scores fall between two
+// bounds
+func (z *SortedSet) CountByScore(min, max ScoreBound) int {
+	_, first := z.list.lastWhere(min.below)
+	_, last := z.list.lastWhere(func(n *skipNode) bool {
+		return !max.above(n)
+	})
+	if last < first {
+		return 0
+	}
+	return last - first
+}
+
+func (z *SortedSet) rangeWhere(
+	below, above func(*skipNode) bool, rev bool, limit Limit,
+) []ScoredMember {
+	var x *skipNode
+	if rev {
+		x, _ = z.list.lastWhere(func(n *skipNode) bool {
+			return !above(n)
+		})
+		if x == z.list.head {
+			x = nil
+		}
+	} else {
+		x, _ = z.list.lastWhere(below)
+		x = x.levels[0].next
+	}
+
+	for i := limit.Offset; x != nil && i > 0; i-- {
+		x = x.step(rev)
+	}
+	res := []ScoredMember{}
+	for i := limit.Count; x != nil && i != 0; i-- {
+		if below(x) || above(x) {
+			break
+		}
+		res = append(res, x.ScoredMember)
+		x = x.step(rev)
+	}
+	return res
+}
+
+// ForEach calls a function for each member of the SortedSet, in ascending
+// order, until it returns an error
+func (z *SortedSet) ForEach(fn func(ScoredMember) error) error {
+	for x := z.list.head.levels[0].next; x != nil; x = x.levels[0].next {
+		if err := fn(x.ScoredMember); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the members of the SortedSet in ascending order, each
+// followed by its score
+func (z *SortedSet) Snapshot() resp.Value {
+	res := make(resp.Values, 0, z.Len()*2)
+	_ = z.ForEach(func(m ScoredMember) error {
+		res = append(res, m.Member, resp.Double(m.Score))
+		return nil
+	})
+	return resp.MakeArray(res...)
+}
+
+func (*SortedSet) Tag() resp.Tag {
+	return resp.ArrayTag
+}
+
+func (z *SortedSet) Marshal(w io.Writer) error {
+	return z.Snapshot().Marshal(w)
+}
+
+func (z *SortedSet) Equal(v resp.Value) bool {
+	if v, ok := v.(*SortedSet); ok {
+		return z == v || z.Snapshot().Equal(v.Snapshot())
+	}
+	return false
+}
+
+func (n *skipNode) step(rev bool) *skipNode {
+	if rev {
+		return n.back
+	}
+	return n.levels[0].next
+}
+
+// below reports whether a node precedes the range bounded below by b
+func (b ScoreBound) below(n *skipNode) bool {
+	return n.Score < b.Score || b.Exclusive && n.Score == b.Score
+}
+
+// above reports whether a node follows the range bounded above by b
+func (b ScoreBound) above(n *skipNode) bool {
+	return n.Score > b.Score || b.Exclusive && n.Score == b.Score
+}
+
+// below reports whether a node precedes the range bounded below by b
+func (b LexBound) below(n *skipNode) bool {
+	switch {
+	case b.Infinity < 0:
+		return false
+	case b.Infinity > 0:
+		return true
+	default:
+		return n.Member < b.Member || b.Exclusive && n.Member == b.Member
+	}
+}
+
+// above reports whether a node follows the range bounded above by b
+func (b LexBound) above(n *skipNode) bool {
+	switch {
+	case b.Infinity > 0:
+		return false
+	case b.Infinity < 0:
+		return true
+	default:
+		return n.Member > b.Member || b.Exclusive && n.Member == b.Member
+	}
+}
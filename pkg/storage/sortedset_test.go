@@ -0,0 +1,201 @@
+package storage_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"testing"
+
+	"github.com/kode4food/respect/pkg/resp"
+	"github.com/kode4food/respect/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func members(z []storage.ScoredMember) []resp.BulkString {
+	res := make([]resp.BulkString, len(z))
+	for i, m := range z {
+		res[i] = m.Member
+	}
+	return res
+}
+
+func TestSortedSet(t *testing.T) {
+	as := assert.New(t)
+	z := storage.NewSortedSet()
+	as.Equal(storage.SortedSetKind, z.Kind())
+	as.Equal("zset", z.Kind().String())
+
+	as.True(z.Add("c", 3))
+	as.True(z.Add("a", 1))
+	as.True(z.Add("b", 2))
+	as.True(z.Add("bb", 2))
+	as.False(z.Add("c", 0))
+	as.Equal(4, z.Len())
+
+	s, ok := z.Score("c")
+	as.True(ok)
+	as.Equal(0.0, s)
+	_, ok = z.Score("d")
+	as.False(ok)
+
+	as.Equal(
+		[]resp.BulkString{"c", "a", "b", "bb"},
+		members(z.Range(0, -1, false)),
+	)
+	as.Equal(
+		[]resp.BulkString{"bb", "b"},
+		members(z.Range(0, 1, true)),
+	)
+	as.Empty(z.Range(5, 10, false))
+
+	r, ok := z.Rank("bb")
+	as.True(ok)
+	as.Equal(3, r)
+	_, ok = z.Rank("d")
+	as.False(ok)
+
+	as.True(z.Snapshot().Equal(resp.MakeArray(
+		resp.BulkString("c"), resp.Double(0),
+		resp.BulkString("a"), resp.Double(1),
+		resp.BulkString("b"), resp.Double(2),
+		resp.BulkString("bb"), resp.Double(2),
+	)))
+
+	as.True(z.Remove("a"))
+	as.False(z.Remove("a"))
+	as.Equal(3, z.Len())
+	r, _ = z.Rank("bb")
+	as.Equal(2, r)
+}
+
+func TestSortedSetRanges(t *testing.T) {
+	as := assert.New(t)
+	z := storage.NewSortedSet()
+	for i, m := range []resp.BulkString{"a", "b", "c", "d", "e"} {
+		z.Add(m, float64(i))
+	}
+
+	incl := func(f float64) storage.ScoreBound {
+		return storage.ScoreBound{Score: f}
+	}
+	excl := func(f float64) storage.ScoreBound {
+		return storage.ScoreBound{Score: f, Exclusive: true}
+	}
+
+	as.Equal(
+		[]resp.BulkString{"b", "c", "d"},
+		members(z.RangeByScore(incl(1), incl(3), false, storage.NoLimit)),
+	)
+	as.Equal(
+		[]resp.BulkString{"c"},
+		members(z.RangeByScore(excl(1), excl(3), false, storage.NoLimit)),
+	)
+	as.Equal(
+		[]resp.BulkString{"d", "c"},
+		members(z.RangeByScore(incl(1), incl(4), true, storage.Limit{
+			Offset: 1, Count: 2,
+		})),
+	)
+	as.Empty(z.RangeByScore(incl(10), incl(20), false, storage.NoLimit))
+	as.Empty(z.RangeByScore(incl(-2), excl(0), true, storage.NoLimit))
+	as.Equal(3, z.CountByScore(incl(1), incl(3)))
+	as.Equal(0, z.CountByScore(excl(3), excl(3)))
+
+	z = storage.NewSortedSet()
+	for _, m := range []resp.BulkString{"a", "b", "c", "d", "e"} {
+		z.Add(m, 0)
+	}
+	as.Equal(
+		[]resp.BulkString{"a", "b", "c"},
+		members(z.RangeByLex(
+			storage.LexBound{Infinity: -1},
+			storage.LexBound{Member: "c"},
+			false, storage.NoLimit,
+		)),
+	)
+	as.Equal(
+		[]resp.BulkString{"e", "d"},
+		members(z.RangeByLex(
+			storage.LexBound{Member: "c", Exclusive: true},
+			storage.LexBound{Infinity: 1},
+			true, storage.NoLimit,
+		)),
+	)
+}
+
+func TestSortedSetOrdering(t *testing.T) {
+	as := assert.New(t)
+	z := storage.NewSortedSet()
+	expected := map[resp.BulkString]float64{}
+	for i := 0; i < 2000; i++ {
+		m := resp.BulkString(fmt.Sprintf("member-%d", rand.N(500)))
+		s := float64(rand.N(50))
+		if rand.N(4) == 0 {
+			z.Remove(m)
+			delete(expected, m)
+			continue
+		}
+		z.Add(m, s)
+		expected[m] = s
+	}
+
+	sorted := make([]storage.ScoredMember, 0, len(expected))
+	for m, s := range expected {
+		sorted = append(sorted, storage.ScoredMember{Member: m, Score: s})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		l, r := sorted[i], sorted[j]
+		return l.Score < r.Score || l.Score == r.Score && l.Member < r.Member
+	})
+
+	as.Equal(len(sorted), z.Len())
+	as.Equal(sorted, z.Range(0, -1, false))
+	for i, m := range sorted {
+		r, ok := z.Rank(m.Member)
+		as.True(ok)
+		as.Equal(i, r)
+	}
+}
+
+func TestMutateSortedSet(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"zset"}
+
+	as.ErrorIs(
+		storage.MutateSortedSet(s, key, false, func(*storage.SortedSet) error {
+			return nil
+		}),
+		storage.KeyNotFound,
+	)
+	as.Nil(storage.MutateSortedSet(s, key, true,
+		func(z *storage.SortedSet) error {
+			z.Add("member", 1.5)
+			return nil
+		},
+	))
+	k, err := s.Kind(key)
+	as.Nil(err)
+	as.Equal(storage.SortedSetKind, k)
+
+	as.Nil(storage.InspectSortedSet(s, key, func(z *storage.SortedSet) error {
+		as.Equal(1, z.Len())
+		return nil
+	}))
+	as.ErrorIs(
+		storage.InspectHash(s, key, func(*storage.Hash) error {
+			return nil
+		}),
+		storage.WrongType,
+	)
+
+	as.Nil(storage.MutateSortedSet(s, key, false,
+		func(z *storage.SortedSet) error {
+			z.Remove("member")
+			return nil
+		},
+	))
+	ok, err := s.Exists(key)
+	as.False(ok)
+	as.ErrorIs(err, storage.KeyNotFound)
+}
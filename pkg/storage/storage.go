@@ -4,18 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/kode4food/respect/pkg/resp"
 )
 
 type (
 	Storage interface {
-		// Get retrieves a value from the storage
+		// Get retrieves a value from the storage. A WrongType error is
+		// returned if the Key holds a Mutable
 		Get(Key) (resp.Value, error)
 
-		// Set stores a value in the storage
+		// Set stores a value in the storage, clearing any deadline
 		Set(Key, resp.Value) (resp.Value, error)
 
+		// SetWith stores a value in the storage according to the provided
+		// SetOptions, returning the previous value and whether the new value
+		// was stored
+		SetWith(Key, resp.Value, SetOptions) (resp.Value, bool, error)
+
 		// Delete removes a value from the storage
 		Delete(Key) (resp.Value, error)
 
@@ -25,8 +32,83 @@ type (
 		// IterateKeys returns an iterator over all keys in the Storage
 		// starting at the provided Key acting as a prefix, inclusive
 		IterateKeys(Key, Accept[Key]) error
+
+		// ScanKeys visits the keys starting at the provided Key acting as a
+		// prefix, resuming from a cursor returned by a previous call. At
+		// least the requested number of keys are visited, if available,
+		// before returning the cursor of the next batch. Iteration starts and
+		// ends with a cursor of zero. Every key that exists for the duration
+		// of the whole iteration will be visited, though some keys may be
+		// visited more than once
+		ScanKeys(Key, uint64, int, Accept[Key]) (uint64, error)
+
+		// Expire sets the deadline after which the Key will no longer exist
+		Expire(Key, time.Time) error
+
+		// Persist removes the deadline from a Key, returning whether there
+		// was one to remove
+		Persist(Key) (bool, error)
+
+		// Deadline returns the deadline of a Key, and whether one is set
+		Deadline(Key) (time.Time, bool, error)
+
+		// Revision returns a number that changes whenever the value or
+		// deadline stored at a Key does, and whether the Key exists.
+		// Revisions are never reused, and a Key that doesn't exist has one
+		// too, which changes if the Key is stored and then removed again
+		Revision(Key) (uint64, bool, error)
+
+		// Kind returns the Kind of data stored at a Key
+		Kind(Key) (Kind, error)
+
+		// Update applies an Updater to the value stored at a Key while the
+		// Key is locked for writing, returning the value that replaced it.
+		// The Key's deadline is retained. A WrongType error is returned if
+		// the Key holds a Mutable
+		Update(Key, Updater) (resp.Value, error)
+
+		// Mutate applies a Mutator to the Mutable stored at a Key while the
+		// Key is locked for writing. If the Key doesn't exist, the Mutator
+		// receives nil, and may return a new Mutable to be stored. A WrongType
+		// error is returned if the Key holds a value that isn't Mutable
+		Mutate(Key, Mutator) error
+
+		// Inspect applies an Inspector to the Mutable stored at a Key while
+		// the Key is locked for reading
+		Inspect(Key, Inspector) error
+
+		// Atomic calls a function with exclusive access to the Storage. The
+		// operations performed through the Storage that the function receives
+		// aren't interleaved with those of any other caller. Key iteration
+		// isn't excluded, and may observe the function's operations while
+		// they're in progress
+		Atomic(func(Storage) error) error
+	}
+
+	// SetOptions control how SetWith stores a value
+	SetOptions struct {
+		// Deadline is when the value expires. The zero Time means never
+		Deadline time.Time
+
+		// KeepDeadline retains the deadline of an existing value
+		KeepDeadline bool
+
+		// Condition determines whether the value is stored at all
+		Condition SetCondition
+
+		// ValuesOnly refuses to replace a Mutable, returning a WrongType
+		// error instead, so that the previous value can always be returned
+		ValuesOnly bool
 	}
 
+	// Updater is applied to the value stored at a Key, or to nil if there
+	// is none, and returns the value that should be stored in its place. If
+	// it returns nil, the Key is removed
+	Updater func(resp.Value) (resp.Value, error)
+
+	// SetCondition restricts SetWith based on the existence of a Key
+	SetCondition uint8
+
 	Accept[T any] func(T) error
 
 	Pair struct {
@@ -42,6 +124,13 @@ type (
 	}
 )
 
+// SetCondition values
+const (
+	Always SetCondition = iota
+	IfExists
+	IfNotExists
+)
+
 // Error messages
 const (
 	ErrEmptyKey   = "empty key"
@@ -56,6 +145,10 @@ var (
 	// operation should be stopped. This is not an error condition, and won't
 	// be propagated outside the iteration operation
 	StopIteration = errors.New("stop iteration")
+
+	// KeyNotFound is matched by any error reporting that a Key doesn't exist,
+	// including those that have expired
+	KeyNotFound = errors.New("key not found")
 )
 
 func AsKey(k resp.Value) (Key, error) {
@@ -81,6 +174,30 @@ func AsKey(k resp.Value) (Key, error) {
 	}
 }
 
+func (c SetCondition) accepts(exists bool) bool {
+	switch c {
+	case IfExists:
+		return exists
+	case IfNotExists:
+		return !exists
+	default:
+		return true
+	}
+}
+
+// ToValue converts a Key into the resp.Value from which it would be parsed by
+// AsKey: a BulkString for single component Keys, otherwise an Array
+func (k Key) ToValue() resp.Value {
+	if len(k) == 1 {
+		return k[0]
+	}
+	res := make([]resp.Value, len(k))
+	for i, e := range k {
+		res[i] = e
+	}
+	return resp.MakeArray(res...)
+}
+
 func (k Key) String() string {
 	if len(k) == 1 {
 		return (string)(k[0])
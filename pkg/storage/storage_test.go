@@ -3,7 +3,9 @@ package storage_test
 import (
 	"fmt"
 	"math/rand/v2"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kode4food/respect/pkg/resp"
 	"github.com/kode4food/respect/pkg/storage"
@@ -223,3 +225,385 @@ func (t *storageTest) iterable(s storage.Storage) {
 	t.Nil(err)
 	t.Equal(len(seen), len(t.live))
 }
+
+func TestMemoryExpiry(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"expiring", "key"}
+	val := resp.BulkString("value")
+
+	_, err := s.Set(key, val)
+	as.Nil(err)
+	_, ok, err := s.Deadline(key)
+	as.Nil(err)
+	as.False(ok)
+
+	deadline := time.Now().Add(50 * time.Millisecond)
+	as.Nil(s.Expire(key, deadline))
+	d, ok, err := s.Deadline(key)
+	as.Nil(err)
+	as.True(ok)
+	as.True(deadline.Equal(d))
+
+	v, err := s.Get(key)
+	as.Nil(err)
+	as.True(val.Equal(v))
+
+	time.Sleep(75 * time.Millisecond)
+	v, err = s.Get(key)
+	as.Nil(v)
+	as.ErrorIs(err, storage.KeyNotFound)
+	ok, err = s.Exists(key)
+	as.False(ok)
+	as.ErrorIs(err, storage.KeyNotFound)
+
+	err = s.IterateKeys(storage.EmptyKey, func(k storage.Key) error {
+		as.Fail("expired key iterated", k.String())
+		return nil
+	})
+	as.Nil(err)
+
+	as.ErrorIs(s.Expire(key, deadline), storage.KeyNotFound)
+	_, err = s.Persist(key)
+	as.ErrorIs(err, storage.KeyNotFound)
+}
+
+func TestMemoryExpiryDistinctKeys(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	nested := storage.Key{"a", "b"}
+	joined := storage.Key{"a\x00b"}
+
+	_, err := s.Set(nested, resp.BulkString("nested"))
+	as.Nil(err)
+	_, err = s.Set(joined, resp.BulkString("joined"))
+	as.Nil(err)
+	as.Nil(s.Expire(nested, time.Now().Add(10*time.Millisecond)))
+	as.Nil(s.Expire(joined, time.Now().Add(time.Hour)))
+
+	// The nested Key is removed without disturbing the other's deadline
+	as.Eventually(func() bool {
+		err := s.IterateKeys(storage.Key{"a"}, func(storage.Key) error {
+			return nil
+		})
+		return err != nil
+	}, time.Second, time.Millisecond)
+	_, ok, err := s.Deadline(joined)
+	as.Nil(err)
+	as.True(ok)
+}
+
+func TestMemoryPersist(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"persistent"}
+
+	_, err := s.Set(key, resp.BulkString("value"))
+	as.Nil(err)
+	ok, err := s.Persist(key)
+	as.Nil(err)
+	as.False(ok)
+
+	as.Nil(s.Expire(key, time.Now().Add(20*time.Millisecond)))
+	ok, err = s.Persist(key)
+	as.Nil(err)
+	as.True(ok)
+
+	time.Sleep(40 * time.Millisecond)
+	ok, err = s.Exists(key)
+	as.Nil(err)
+	as.True(ok)
+
+	as.Nil(s.Expire(key, time.Now().Add(time.Hour)))
+	_, err = s.Set(key, resp.BulkString("replaced"))
+	as.Nil(err)
+	_, ok, err = s.Deadline(key)
+	as.Nil(err)
+	as.False(ok)
+}
+
+func TestMemorySetWith(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"conditional", "key"}
+
+	old, ok, err := s.SetWith(key, resp.Integer(1), storage.SetOptions{
+		Condition: storage.IfExists,
+	})
+	as.Nil(err)
+	as.Nil(old)
+	as.False(ok)
+	err = s.IterateKeys(storage.EmptyKey, func(k storage.Key) error {
+		as.Fail("failed set left a key", k.String())
+		return nil
+	})
+	as.Nil(err)
+
+	old, ok, err = s.SetWith(key, resp.Integer(1), storage.SetOptions{
+		Condition: storage.IfNotExists,
+		Deadline:  time.Now().Add(time.Hour),
+	})
+	as.Nil(err)
+	as.Nil(old)
+	as.True(ok)
+
+	old, ok, err = s.SetWith(key, resp.Integer(2), storage.SetOptions{
+		Condition: storage.IfNotExists,
+	})
+	as.Nil(err)
+	as.Equal(resp.Integer(1), old)
+	as.False(ok)
+
+	old, ok, err = s.SetWith(key, resp.Integer(3), storage.SetOptions{
+		Condition:    storage.IfExists,
+		KeepDeadline: true,
+	})
+	as.Nil(err)
+	as.Equal(resp.Integer(1), old)
+	as.True(ok)
+	_, ok, err = s.Deadline(key)
+	as.Nil(err)
+	as.True(ok)
+
+	_, ok, err = s.SetWith(key, resp.Integer(4), storage.SetOptions{
+		Deadline: time.Now().Add(-time.Second),
+	})
+	as.Nil(err)
+	as.True(ok)
+	ok, err = s.Exists(key)
+	as.False(ok)
+	as.ErrorIs(err, storage.KeyNotFound)
+}
+
+func TestMemoryScanKeys(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+
+	stable := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		k := getRandomKey(i)
+		_, err := s.Set(k, resp.Integer(i))
+		as.Nil(err)
+		stable[k.String()] = 0
+	}
+
+	next := len(stable)
+	var cursor uint64
+	for {
+		var err error
+		cursor, err = s.ScanKeys(storage.EmptyKey, cursor, 10,
+			func(k storage.Key) error {
+				if _, ok := stable[k.String()]; ok {
+					stable[k.String()]++
+				}
+				return nil
+			},
+		)
+		as.Nil(err)
+		if cursor == 0 {
+			break
+		}
+		for i := 0; i < 25; i++ {
+			_, err := s.Set(getRandomKey(next), resp.Integer(next))
+			as.Nil(err)
+			next++
+		}
+	}
+
+	for k, seen := range stable {
+		as.Equal(1, seen, k)
+	}
+}
+
+func TestMemoryScanShrinking(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+
+	var keys []storage.Key
+	for i := 0; i < 1000; i++ {
+		k := storage.Key{resp.BulkString(fmt.Sprintf("key-%d", i))}
+		_, err := s.Set(k, resp.Integer(i))
+		as.Nil(err)
+		keys = append(keys, k)
+	}
+
+	stable := map[string]bool{}
+	for _, k := range keys[:100] {
+		stable[k.String()] = false
+	}
+
+	var cursor uint64
+	deleted := 100
+	for {
+		var err error
+		cursor, err = s.ScanKeys(storage.EmptyKey, cursor, 5,
+			func(k storage.Key) error {
+				if _, ok := stable[k.String()]; ok {
+					stable[k.String()] = true
+				}
+				return nil
+			},
+		)
+		as.Nil(err)
+		if cursor == 0 {
+			break
+		}
+		for i := 0; i < 50 && deleted < len(keys); i++ {
+			_, err := s.Delete(keys[deleted])
+			as.Nil(err)
+			deleted++
+		}
+	}
+
+	for k, seen := range stable {
+		as.True(seen, k)
+	}
+}
+
+func TestMemoryUpdate(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"counter"}
+
+	increment := func(v resp.Value) (resp.Value, error) {
+		if v == nil {
+			return resp.Integer(1), nil
+		}
+		return v.(resp.Integer) + 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := s.Update(key, increment)
+				as.Nil(err)
+			}
+		}()
+	}
+	wg.Wait()
+	v, err := s.Get(key)
+	as.Nil(err)
+	as.Equal(resp.Integer(1000), v)
+
+	deadline := time.Now().Add(time.Hour)
+	as.Nil(s.Expire(key, deadline))
+	v, err = s.Update(key, increment)
+	as.Nil(err)
+	as.Equal(resp.Integer(1001), v)
+	d, ok, err := s.Deadline(key)
+	as.Nil(err)
+	as.True(ok)
+	as.True(deadline.Equal(d))
+
+	_, err = s.Update(key, func(resp.Value) (resp.Value, error) {
+		return nil, storage.StopIteration
+	})
+	as.ErrorIs(err, storage.StopIteration)
+	v, err = s.Get(key)
+	as.Nil(err)
+	as.Equal(resp.Integer(1001), v)
+
+	v, err = s.Update(key, func(resp.Value) (resp.Value, error) {
+		return nil, nil
+	})
+	as.Nil(err)
+	as.Nil(v)
+	_, err = s.Exists(key)
+	as.ErrorIs(err, storage.KeyNotFound)
+
+	list := storage.Key{"list"}
+	as.Nil(storage.MutateList(s, list, true, func(l *storage.List) error {
+		l.PushBack(resp.Integer(1))
+		return nil
+	}))
+	_, err = s.Update(list, increment)
+	as.ErrorIs(err, storage.WrongType)
+}
+
+func TestMemoryRevision(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	key := storage.Key{"parent"}
+
+	missing, ok, err := s.Revision(key)
+	as.Nil(err)
+	as.False(ok)
+
+	_, err = s.Set(key, resp.BulkString("value"))
+	as.Nil(err)
+	first, ok, err := s.Revision(key)
+	as.Nil(err)
+	as.True(ok)
+	as.NotEqual(missing, first)
+
+	_, err = s.Set(storage.Key{"parent", "child"}, resp.BulkString("child"))
+	as.Nil(err)
+	rev, ok, err := s.Revision(key)
+	as.Nil(err)
+	as.True(ok)
+	as.Equal(first, rev)
+
+	as.Nil(s.Expire(key, time.Now().Add(time.Hour)))
+	second, _, err := s.Revision(key)
+	as.Nil(err)
+	as.Greater(second, first)
+
+	_, err = s.Delete(key)
+	as.Nil(err)
+	_, err = s.Set(key, resp.BulkString("value"))
+	as.Nil(err)
+	rev, _, err = s.Revision(key)
+	as.Nil(err)
+	as.Greater(rev, second)
+
+	list := storage.Key{"list"}
+	as.Nil(storage.MutateList(s, list, true, func(l *storage.List) error {
+		l.PushBack(resp.Integer(1))
+		return nil
+	}))
+	first, _, err = s.Revision(list)
+	as.Nil(err)
+	as.Nil(storage.MutateList(s, list, false, func(l *storage.List) error {
+		l.PushBack(resp.Integer(2))
+		return nil
+	}))
+	rev, _, err = s.Revision(list)
+	as.Nil(err)
+	as.Greater(rev, first)
+
+	_, _, err = s.Revision(storage.Key{})
+	as.NotNil(err)
+}
+
+func TestMemoryRevisionOfMissingKey(t *testing.T) {
+	as := assert.New(t)
+	s := storage.NewMemory()
+	revision := func(key storage.Key) uint64 {
+		rev, ok, err := s.Revision(key)
+		as.Nil(err)
+		as.False(ok)
+		return rev
+	}
+
+	for _, key := range []storage.Key{{"key"}, {"parent", "child"}} {
+		before := revision(key)
+		as.Equal(before, revision(key))
+		_, err := s.Set(key, resp.BulkString("value"))
+		as.Nil(err)
+		_, err = s.Delete(key)
+		as.Nil(err)
+		as.NotEqual(before, revision(key))
+	}
+
+	_, err := s.Set(storage.Key{"parent", "other"}, resp.BulkString("value"))
+	as.Nil(err)
+	before := revision(storage.Key{"parent", "child"})
+	_, err = s.Set(storage.Key{"parent", "child"}, resp.BulkString("value"))
+	as.Nil(err)
+	_, err = s.Delete(storage.Key{"parent", "child"})
+	as.Nil(err)
+	as.NotEqual(before, revision(storage.Key{"parent", "child"}))
+}
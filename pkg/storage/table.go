@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"math/bits"
+
+	"github.com/kode4food/respect/pkg/resp"
+)
+
+type (
+	// table is a chained hash table keyed by BulkString. Unlike a Go map,
+	// its buckets are addressable, which allows a scan to resume from a
+	// cursor the way Redis does: by visiting buckets in reverse-binary order,
+	// every entry that remains in the table is visited even if the table is
+	// resized between calls
+	table[T any] struct {
+		buckets []*tableEntry[T]
+		count   int
+	}
+
+	tableEntry[T any] struct {
+		name  resp.BulkString
+		value T
+		hash  uint64
+		next  *tableEntry[T]
+	}
+)
+
+const minTableBuckets = 4
+
+func (t *table[T]) get(name resp.BulkString) (T, bool) {
+	if e := t.find(name); e != nil {
+		return e.value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// put stores a value, returning whether the name was newly added
+func (t *table[T]) put(name resp.BulkString, value T) bool {
+	if e := t.find(name); e != nil {
+		e.value = value
+		return false
+	}
+	if l := len(t.buckets); l == 0 {
+		t.resize(minTableBuckets)
+	} else if t.count >= l {
+		t.resize(l * 2)
+	}
+	h := resp.Hash(name)
+	b := t.bucketOf(h)
+	t.buckets[b] = &tableEntry[T]{
+		name:  name,
+		value: value,
+		hash:  h,
+		next:  t.buckets[b],
+	}
+	t.count++
+	return true
+}
+
+// remove deletes a value, returning whether the name was present
+func (t *table[T]) remove(name resp.BulkString) bool {
+	if t.count == 0 {
+		return false
+	}
+	h := resp.Hash(name)
+	for e := &t.buckets[t.bucketOf(h)]; *e != nil; e = &(*e).next {
+		if (*e).hash == h && (*e).name == name {
+			*e = (*e).next
+			t.count--
+			t.shrink()
+			return true
+		}
+	}
+	return false
+}
+
+func (t *table[T]) len() int {
+	return t.count
+}
+
+func (t *table[T]) names() []resp.BulkString {
+	res := make([]resp.BulkString, 0, t.count)
+	for _, e := range t.buckets {
+		for ; e != nil; e = e.next {
+			res = append(res, e.name)
+		}
+	}
+	return res
+}
+
+func (t *table[T]) forEach(fn func(resp.BulkString, T) error) error {
+	for _, e := range t.buckets {
+		for ; e != nil; e = e.next {
+			if err := fn(e.name, e.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bucket returns the entries of the bucket addressed by a cursor, along with
+// the cursor of the next bucket to visit. A returned cursor of zero means
+// that all buckets have been visited
+func (t *table[T]) bucket(cursor uint64) ([]*tableEntry[T], uint64) {
+	if t.count == 0 {
+		return nil, 0
+	}
+	mask := uint64(len(t.buckets) - 1)
+	var res []*tableEntry[T]
+	for e := t.buckets[cursor&mask]; e != nil; e = e.next {
+		res = append(res, e)
+	}
+	cursor |= ^mask
+	cursor = bits.Reverse64(bits.Reverse64(cursor) + 1)
+	return res, cursor
+}
+
+// scan visits the entries of whole buckets, starting with the one addressed
+// by a cursor, until at least count entries have been visited. It returns
+// the cursor of the next bucket to visit
+func (t *table[T]) scan(
+	cursor uint64, count int, fn func(resp.BulkString, T),
+) uint64 {
+	for seen := 0; ; {
+		entries, next := t.bucket(cursor)
+		for _, e := range entries {
+			fn(e.name, e.value)
+		}
+		seen += len(entries)
+		if cursor = next; cursor == 0 || seen >= count {
+			return cursor
+		}
+	}
+}
+
+func (t *table[T]) find(name resp.BulkString) *tableEntry[T] {
+	if t.count == 0 {
+		return nil
+	}
+	h := resp.Hash(name)
+	for e := t.buckets[t.bucketOf(h)]; e != nil; e = e.next {
+		if e.hash == h && e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+func (t *table[T]) bucketOf(hash uint64) uint64 {
+	return hash & uint64(len(t.buckets)-1)
+}
+
+func (t *table[T]) shrink() {
+	if l := len(t.buckets); l > minTableBuckets && t.count < l/4 {
+		t.resize(l / 2)
+	}
+}
+
+func (t *table[T]) resize(size int) {
+	old := t.buckets
+	t.buckets = make([]*tableEntry[T], size)
+	for _, e := range old {
+		for e != nil {
+			next := e.next
+			b := t.bucketOf(e.hash)
+			e.next = t.buckets[b]
+			t.buckets[b] = e
+			e = next
+		}
+	}
+}